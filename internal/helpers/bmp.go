@@ -0,0 +1,181 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+const (
+	// bmpFileHeaderSize 是 BITMAPFILEHEADER 的字节数
+	bmpFileHeaderSize = 14
+	// bmpInfoHeaderSize 是 BITMAPINFOHEADER 的字节数
+	bmpInfoHeaderSize = 40
+	// bmpHeaderSize 是 filter.c 要求的像素数据偏移 (bfOffBits)
+	bmpHeaderSize = bmpFileHeaderSize + bmpInfoHeaderSize
+)
+
+// RGBTriple 对应 bmp.h 中的 RGBTRIPLE，字段顺序与文件中的字节顺序一致
+type RGBTriple struct {
+	Blue  uint8
+	Green uint8
+	Red   uint8
+}
+
+// String 以 "R G B" 的形式输出像素，与 testing.c 的输出格式一致
+func (p RGBTriple) String() string {
+	return fmt.Sprintf("%d %d %d", p.Red, p.Green, p.Blue)
+}
+
+// BMPImage 表示一张 24 位未压缩 BMP 图片
+// Pixels[y][x]，y = 0 为第一行 (自上而下)
+type BMPImage struct {
+	Width  int
+	Height int
+	Pixels [][]RGBTriple
+}
+
+// NewBMPImage 创建一张全黑的图片
+func NewBMPImage(width, height int) *BMPImage {
+	pixels := make([][]RGBTriple, height)
+	for y := range pixels {
+		pixels[y] = make([]RGBTriple, width)
+	}
+	return &BMPImage{Width: width, Height: height, Pixels: pixels}
+}
+
+// RandomBMPImage 生成一张像素随机的图片
+func RandomBMPImage(width, height int) *BMPImage {
+	img := NewBMPImage(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Pixels[y][x] = RGBTriple{
+				Blue:  uint8(random.RandomInt(0, 256)),
+				Green: uint8(random.RandomInt(0, 256)),
+				Red:   uint8(random.RandomInt(0, 256)),
+			}
+		}
+	}
+	return img
+}
+
+// Clone 返回图片的深拷贝
+func (img *BMPImage) Clone() *BMPImage {
+	clone := NewBMPImage(img.Width, img.Height)
+	for y := range img.Pixels {
+		copy(clone.Pixels[y], img.Pixels[y])
+	}
+	return clone
+}
+
+// bmpRowPadding 返回每行像素后需要补齐到 4 字节边界的字节数
+func bmpRowPadding(width int) int {
+	return (4 - (width*3)%4) % 4
+}
+
+// EncodeBMP 将图片编码为 filter.c 可以读取的 24 位 BMP
+// 使用负的 biHeight (自上而下存储)，与 CS50 提供的图片一致
+func EncodeBMP(img *BMPImage) []byte {
+	padding := bmpRowPadding(img.Width)
+	imageSize := (img.Width*3 + padding) * img.Height
+
+	buf := make([]byte, bmpHeaderSize, bmpHeaderSize+imageSize)
+	le := binary.LittleEndian
+
+	// BITMAPFILEHEADER
+	le.PutUint16(buf[0:], 0x4d42)
+	le.PutUint32(buf[2:], uint32(bmpHeaderSize+imageSize))
+	le.PutUint32(buf[10:], bmpHeaderSize)
+
+	// BITMAPINFOHEADER
+	le.PutUint32(buf[14:], bmpInfoHeaderSize)
+	le.PutUint32(buf[18:], uint32(int32(img.Width)))
+	le.PutUint32(buf[22:], uint32(-int32(img.Height)))
+	le.PutUint16(buf[26:], 1)
+	le.PutUint16(buf[28:], 24)
+	le.PutUint32(buf[34:], uint32(imageSize))
+	le.PutUint32(buf[38:], 2834)
+	le.PutUint32(buf[42:], 2834)
+
+	for y := 0; y < img.Height; y++ {
+		for _, p := range img.Pixels[y] {
+			buf = append(buf, p.Blue, p.Green, p.Red)
+		}
+		for i := 0; i < padding; i++ {
+			buf = append(buf, 0)
+		}
+	}
+	return buf
+}
+
+// DecodeBMP 解析 24 位未压缩 BMP，校验规则与 filter.c 相同
+func DecodeBMP(data []byte) (*BMPImage, error) {
+	if len(data) < bmpHeaderSize {
+		return nil, fmt.Errorf("file too short for BMP headers (%d bytes)", len(data))
+	}
+	le := binary.LittleEndian
+
+	if le.Uint16(data[0:]) != 0x4d42 {
+		return nil, fmt.Errorf("not a BMP file (bfType is 0x%04x)", le.Uint16(data[0:]))
+	}
+	offset := int(le.Uint32(data[10:]))
+	if offset != bmpHeaderSize {
+		return nil, fmt.Errorf("unsupported bfOffBits %d (expected %d)", offset, bmpHeaderSize)
+	}
+	if size := le.Uint32(data[14:]); size != bmpInfoHeaderSize {
+		return nil, fmt.Errorf("unsupported biSize %d (expected %d)", size, bmpInfoHeaderSize)
+	}
+	if bits := le.Uint16(data[28:]); bits != 24 {
+		return nil, fmt.Errorf("unsupported biBitCount %d (expected 24)", bits)
+	}
+	if compression := le.Uint32(data[30:]); compression != 0 {
+		return nil, fmt.Errorf("unsupported biCompression %d (expected 0)", compression)
+	}
+
+	width := int(int32(le.Uint32(data[18:])))
+	height := int(int32(le.Uint32(data[22:])))
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width <= 0 || height == 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+
+	padding := bmpRowPadding(width)
+	rowSize := width*3 + padding
+	if len(data) < offset+rowSize*height {
+		return nil, fmt.Errorf("pixel data truncated: expected %d bytes, got %d",
+			rowSize*height, len(data)-offset)
+	}
+
+	img := NewBMPImage(width, height)
+	for row := 0; row < height; row++ {
+		y := row
+		if !topDown {
+			y = height - 1 - row
+		}
+		start := offset + row*rowSize
+		for x := 0; x < width; x++ {
+			i := start + x*3
+			img.Pixels[y][x] = RGBTriple{Blue: data[i], Green: data[i+1], Red: data[i+2]}
+		}
+	}
+	return img, nil
+}
+
+// ReadBMP 从文件读取 BMP 图片
+func ReadBMP(path string) (*BMPImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBMP(data)
+}
+
+// WriteBMP 将图片写入文件
+func WriteBMP(path string, img *BMPImage) error {
+	return os.WriteFile(path, EncodeBMP(img), 0644)
+}
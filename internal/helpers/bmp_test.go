@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBMPRoundTrip(t *testing.T) {
+	random.Init()
+
+	for _, size := range [][2]int{{1, 1}, {3, 3}, {5, 2}, {1, 7}, {9, 1}, {4, 4}} {
+		img := RandomBMPImage(size[0], size[1])
+		data := EncodeBMP(img)
+
+		padding := (4 - (size[0]*3)%4) % 4
+		assert.Len(t, data, 54+(size[0]*3+padding)*size[1], "size=%v", size)
+
+		decoded, err := DecodeBMP(data)
+		require.NoError(t, err, "size=%v", size)
+		assert.Equal(t, img, decoded, "size=%v", size)
+	}
+}
+
+func TestDecodeBMPBottomUp(t *testing.T) {
+	img := NewBMPImage(2, 2)
+	img.Pixels[0][0] = RGBTriple{Red: 255}
+	img.Pixels[1][1] = RGBTriple{Blue: 255}
+	data := EncodeBMP(img)
+
+	// 将 biHeight 改为正数，并把两行按自下而上的顺序存储
+	data[22], data[23], data[24], data[25] = 2, 0, 0, 0
+	rowSize := 8
+	row0 := append([]byte{}, data[54:54+rowSize]...)
+	copy(data[54:], data[54+rowSize:54+2*rowSize])
+	copy(data[54+rowSize:], row0)
+
+	decoded, err := DecodeBMP(data)
+	require.NoError(t, err)
+	assert.Equal(t, img, decoded)
+}
+
+func TestDecodeBMPRejectsInvalidHeaders(t *testing.T) {
+	data := EncodeBMP(NewBMPImage(2, 2))
+
+	bad := append([]byte{}, data...)
+	bad[0] = 'X'
+	_, err := DecodeBMP(bad)
+	assert.Error(t, err)
+
+	bad = append([]byte{}, data...)
+	bad[28] = 32
+	_, err = DecodeBMP(bad)
+	assert.Error(t, err)
+
+	_, err = DecodeBMP(data[:60])
+	assert.Error(t, err)
+}
+
+func TestReferenceFilters(t *testing.T) {
+	img := NewBMPImage(3, 1)
+	img.Pixels[0] = []RGBTriple{
+		{Red: 27, Green: 28, Blue: 28},
+		{Red: 255, Green: 255, Blue: 255},
+		{Red: 10, Green: 20, Blue: 30},
+	}
+
+	gray := FilterGrayscale(img)
+	assert.Equal(t, RGBTriple{Red: 28, Green: 28, Blue: 28}, gray.Pixels[0][0])
+
+	sepia := FilterSepia(img)
+	assert.Equal(t, RGBTriple{Red: 255, Green: 255, Blue: 239}, sepia.Pixels[0][1])
+
+	reflected := FilterReflect(img)
+	assert.Equal(t, img.Pixels[0][2], reflected.Pixels[0][0])
+	assert.Equal(t, img.Pixels[0][0], reflected.Pixels[0][2])
+
+	blurred := FilterBlur(img)
+	assert.Equal(t, RGBTriple{Red: 141, Green: 142, Blue: 142}, blurred.Pixels[0][0])
+
+	// 纯色图片的中心像素没有边缘
+	solid := NewBMPImage(3, 3)
+	for y := range solid.Pixels {
+		for x := range solid.Pixels[y] {
+			solid.Pixels[y][x] = RGBTriple{Red: 100, Green: 100, Blue: 100}
+		}
+	}
+	assert.Equal(t, RGBTriple{}, FilterEdges(solid).Pixels[1][1])
+	assert.Equal(t, RGBTriple{Red: 255, Green: 255, Blue: 255}, FilterEdges(solid).Pixels[0][0])
+}
+
+func TestFirstPixelMismatch(t *testing.T) {
+	expected := NewBMPImage(3, 2)
+	actual := expected.Clone()
+	mismatch, count := FirstPixelMismatch(expected, actual)
+	assert.Nil(t, mismatch)
+	assert.Equal(t, 0, count)
+
+	actual.Pixels[1][0] = RGBTriple{Green: 1}
+	actual.Pixels[1][2] = RGBTriple{Green: 2}
+	mismatch, count = FirstPixelMismatch(expected, actual)
+	require.NotNil(t, mismatch)
+	assert.Equal(t, 0, mismatch.X)
+	assert.Equal(t, 1, mismatch.Y)
+	assert.Equal(t, 2, count)
+
+	diff := DiffImage(expected, actual)
+	assert.Equal(t, RGBTriple{Red: 255}, diff.Pixels[1][2])
+}
@@ -0,0 +1,322 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CValueKind 是断言检查的值的类型，决定驱动程序如何打印它
+type CValueKind int
+
+const (
+	CBool CValueKind = iota
+	CInt
+	CString
+	CIntArray
+	CBoolArray
+	// COutput 检查执行语句期间打印到标准输出的内容
+	COutput
+	// COutputLines 与 COutput 相同，但按行比较且不计顺序
+	COutputLines
+)
+
+// CStep 是函数级检查中的一步：执行一条 C 语句，或检查一个值
+type CStep struct {
+	Code     string
+	Kind     CValueKind
+	Len      int
+	Expected string
+	assert   bool
+}
+
+// CStmt 执行 C 语句 (例如设置全局变量或声明局部数组)
+func CStmt(code string) CStep {
+	return CStep{Code: code}
+}
+
+// ExpectBool 检查 C 表达式的值为 true/false
+func ExpectBool(expr string, expected bool) CStep {
+	return CStep{Code: expr, Kind: CBool, Expected: strconv.FormatBool(expected), assert: true}
+}
+
+// ExpectInt 检查 C 整数表达式的值
+func ExpectInt(expr string, expected int) CStep {
+	return CStep{Code: expr, Kind: CInt, Expected: strconv.Itoa(expected), assert: true}
+}
+
+// ExpectString 检查 C 字符串表达式的值 (NULL 打印为 (null))
+func ExpectString(expr, expected string) CStep {
+	return CStep{Code: expr, Kind: CString, Expected: expected, assert: true}
+}
+
+// ExpectIntArray 检查 C 整数数组前 len(expected) 个元素
+func ExpectIntArray(expr string, expected []int) CStep {
+	items := make([]string, len(expected))
+	for i, v := range expected {
+		items[i] = strconv.Itoa(v)
+	}
+	return CStep{Code: expr, Kind: CIntArray, Len: len(expected), Expected: "{" + strings.Join(items, ", ") + "}", assert: true}
+}
+
+// ExpectBoolArray 检查 C bool 数组前 len(expected) 个元素
+func ExpectBoolArray(expr string, expected []bool) CStep {
+	items := make([]string, len(expected))
+	for i, v := range expected {
+		items[i] = strconv.FormatBool(v)
+	}
+	return CStep{Code: expr, Kind: CBoolArray, Len: len(expected), Expected: "{" + strings.Join(items, ", ") + "}", assert: true}
+}
+
+// ExpectOutput 执行语句并检查其标准输出 (忽略首尾空白)
+func ExpectOutput(stmt, expected string) CStep {
+	return CStep{Code: stmt, Kind: COutput, Expected: strings.TrimSpace(expected), assert: true}
+}
+
+// ExpectOutputLines 执行语句并检查其输出的非空行 (不计顺序)
+func ExpectOutputLines(stmt string, expected []string) CStep {
+	return CStep{Code: stmt, Kind: COutputLines, Expected: strings.Join(sortedLines(strings.Join(expected, "\n")), "\n"), assert: true}
+}
+
+// CFunctionCheck 是一个函数级检查，各步骤在同一个进程中依次执行
+type CFunctionCheck struct {
+	Name  string
+	Steps []CStep
+}
+
+// CAssertionResult 是单个断言的结果
+type CAssertionResult struct {
+	Code     string
+	Expected string
+	Actual   string
+	Passed   bool
+	// Err 非空表示断言没有执行到 (例如程序崩溃或超时)
+	Err string
+}
+
+// CFunctionCheckResult 是一个函数级检查的结果
+type CFunctionCheckResult struct {
+	Name       string
+	Assertions []CAssertionResult
+}
+
+// Error 返回第一个失败的断言，全部通过时返回 nil
+func (r *CFunctionCheckResult) Error() error {
+	for _, a := range r.Assertions {
+		if a.Err != "" {
+			return fmt.Errorf("%s: %s", a.Code, a.Err)
+		}
+		if !a.Passed {
+			return fmt.Errorf("%s: expected %s, got %s", a.Code, formatCValue(a.Expected), formatCValue(a.Actual))
+		}
+	}
+	return nil
+}
+
+func formatCValue(v string) string {
+	if strings.Contains(v, "\n") {
+		return "\n" + v + "\n"
+	}
+	return strconv.Quote(v)
+}
+
+// CFunctionTester 把学生代码 (main 已重命名) 与生成的驱动程序编译在一起，
+// 每个检查在单独的进程中运行
+type CFunctionTester struct {
+	WorkDir string
+	Source  string
+	Flags   []string
+	Timeout time.Duration
+
+	checks []CFunctionCheck
+	binary string
+	driver string
+}
+
+// NewCFunctionTester 创建测试器；source 为学生的源文件名 (如 "plurality.c")
+func NewCFunctionTester(workDir, source string, flags ...string) *CFunctionTester {
+	return &CFunctionTester{WorkDir: workDir, Source: source, Flags: flags, Timeout: 5 * time.Second}
+}
+
+// Build 为所有检查生成驱动程序并编译
+func (t *CFunctionTester) Build(checks []CFunctionCheck) error {
+	code, err := os.ReadFile(filepath.Join(t.WorkDir, t.Source))
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", t.Source, err)
+	}
+
+	base := strings.TrimSuffix(t.Source, filepath.Ext(t.Source))
+	t.checks = checks
+	t.driver = base + "_checks.c"
+	t.binary = base + "_checks"
+
+	source := RenameMain(t.Source, code) + GenerateCDriver(checks)
+	if err := os.WriteFile(filepath.Join(t.WorkDir, t.driver), []byte(source), 0644); err != nil {
+		return fmt.Errorf("could not write test driver: %v", err)
+	}
+
+	args := append([]string{"-o", t.binary, t.driver}, t.Flags...)
+	cmd := exec.Command("clang", args...)
+	cmd.Dir = t.WorkDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("test driver does not compile: %s\n%s", err, string(out))
+	}
+	return nil
+}
+
+// Run 运行第 i 个检查并返回每个断言的结果
+func (t *CFunctionTester) Run(i int) *CFunctionCheckResult {
+	check := t.checks[i]
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "./"+t.binary, strconv.Itoa(i))
+	cmd.Dir = t.WorkDir
+	out, err := cmd.Output()
+
+	failure := ""
+	switch {
+	case ctx.Err() != nil:
+		failure = fmt.Sprintf("timed out after %v", t.Timeout)
+	case err != nil:
+		failure = fmt.Sprintf("program crashed (%v)", err)
+	}
+	return ParseCDriverOutput(check, string(out), failure)
+}
+
+// Cleanup 删除生成的驱动程序和可执行文件
+func (t *CFunctionTester) Cleanup() {
+	os.Remove(filepath.Join(t.WorkDir, t.driver))
+	os.Remove(filepath.Join(t.WorkDir, t.binary))
+}
+
+// GenerateCDriver 生成驱动程序的 main：argv[1] 选择要运行的检查，
+// 每个断言的值打印在 @@BEGIN n@@ 与 @@END n@@ 之间
+func GenerateCDriver(checks []CFunctionCheck) string {
+	var b strings.Builder
+	b.WriteString("\n#include <stdbool.h>\n#include <stdio.h>\n#include <stdlib.h>\n#include <string.h>\n\n")
+	b.WriteString("int main(int argc, char *argv[])\n{\n")
+	b.WriteString("    setvbuf(stdout, NULL, _IONBF, 0);\n")
+	b.WriteString("    if (argc != 2)\n    {\n        return 2;\n    }\n")
+	b.WriteString("    switch (atoi(argv[1]))\n    {\n")
+	for i, check := range checks {
+		fmt.Fprintf(&b, "        case %d:\n        {\n", i)
+		n := 0
+		for _, step := range check.Steps {
+			if !step.assert {
+				fmt.Fprintf(&b, "            %s\n", step.Code)
+				continue
+			}
+			b.WriteString(cAssertionCode(step, n))
+			n++
+		}
+		b.WriteString("            break;\n        }\n")
+	}
+	b.WriteString("        default:\n            return 2;\n    }\n    return 0;\n}\n")
+	return b.String()
+}
+
+// cAssertionCode 生成单个断言的 C 代码：先求值 (学生代码的输出不会混入结果)，再打印
+func cAssertionCode(step CStep, n int) string {
+	begin := fmt.Sprintf("printf(\"\\n@@BEGIN %d@@\\n\");", n)
+	end := fmt.Sprintf("printf(\"\\n@@END %d@@\\n\");", n)
+
+	var eval, print string
+	switch step.Kind {
+	case CBool:
+		eval = fmt.Sprintf("bool v%d = (%s);", n, step.Code)
+		print = fmt.Sprintf("printf(\"%%s\", v%d ? \"true\" : \"false\");", n)
+	case CInt:
+		eval = fmt.Sprintf("long long v%d = (long long) (%s);", n, step.Code)
+		print = fmt.Sprintf("printf(\"%%lld\", v%d);", n)
+	case CString:
+		eval = fmt.Sprintf("const char *v%d = (%s);", n, step.Code)
+		print = fmt.Sprintf("printf(\"%%s\", v%d == NULL ? \"(null)\" : v%d);", n, n)
+	case CIntArray, CBoolArray:
+		format := "printf(\"%%s%%lld\", i ? \", \" : \"\", (long long) (%s)[i]);"
+		if step.Kind == CBoolArray {
+			format = "printf(\"%%s%%s\", i ? \", \" : \"\", (%s)[i] ? \"true\" : \"false\");"
+		}
+		print = fmt.Sprintf("printf(\"{\"); for (int i = 0; i < %d; i++) { "+format+" } printf(\"}\");", step.Len, step.Code)
+	case COutput, COutputLines:
+		// 输出类断言直接在标记之间执行语句
+		return fmt.Sprintf("            %s\n            %s\n            %s\n", begin, step.Code, end)
+	}
+
+	var b strings.Builder
+	if eval != "" {
+		fmt.Fprintf(&b, "            %s\n", eval)
+	}
+	fmt.Fprintf(&b, "            %s\n            %s\n            %s\n", begin, print, end)
+	return b.String()
+}
+
+var cDriverValueRegex = regexp.MustCompile(`(?s)\n@@BEGIN (\d+)@@\n(.*?)\n@@END (\d+)@@\n`)
+
+// ParseCDriverOutput 从驱动程序输出中提取每个断言的实际值并与期望值比较
+// failure 非空时，没有输出结果的断言标记为未执行
+func ParseCDriverOutput(check CFunctionCheck, output, failure string) *CFunctionCheckResult {
+	actual := make(map[int]string)
+	for _, m := range cDriverValueRegex.FindAllStringSubmatch(output, -1) {
+		if m[1] != m[3] {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		actual[n] = m[2]
+	}
+
+	result := &CFunctionCheckResult{Name: check.Name}
+	n := 0
+	reported := false
+	for _, step := range check.Steps {
+		if !step.assert {
+			continue
+		}
+		a := CAssertionResult{Code: step.Code, Expected: step.Expected}
+		value, ok := actual[n]
+		switch {
+		case !ok && !reported:
+			a.Err = failure
+			if a.Err == "" {
+				a.Err = "no result was printed"
+			}
+			reported = true
+		case !ok:
+			a.Err = "not reached"
+		default:
+			a.Actual = normalizeCValue(step.Kind, value)
+			a.Passed = a.Actual == step.Expected
+		}
+		result.Assertions = append(result.Assertions, a)
+		n++
+	}
+	return result
+}
+
+func normalizeCValue(kind CValueKind, value string) string {
+	switch kind {
+	case COutput:
+		return strings.TrimSpace(value)
+	case COutputLines:
+		return strings.Join(sortedLines(value), "\n")
+	}
+	return value
+}
+
+func sortedLines(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
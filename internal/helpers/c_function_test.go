@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCDriver(t *testing.T) {
+	driver := GenerateCDriver([]CFunctionCheck{
+		{Name: "first", Steps: []CStep{CStmt("int ranks[3];"), ExpectBool(`vote(0, "Alice", ranks)`, true)}},
+		{Name: "second", Steps: []CStep{ExpectIntArray("ranks", []int{1, 2, 0})}},
+	})
+
+	assert.Contains(t, driver, "case 0:")
+	assert.Contains(t, driver, "case 1:")
+	assert.Contains(t, driver, "int ranks[3];")
+	assert.Contains(t, driver, `bool v0 = (vote(0, "Alice", ranks));`)
+	assert.Contains(t, driver, `printf("\n@@BEGIN 0@@\n");`)
+}
+
+func TestParseCDriverOutput(t *testing.T) {
+	check := CFunctionCheck{Name: "vote", Steps: []CStep{
+		CStmt("int ranks[3];"),
+		ExpectBool(`vote(0, "Alice", ranks)`, true),
+		ExpectIntArray("ranks", []int{1, 2, 0}),
+		ExpectOutputLines("print_winner();", []string{"Bob", "Alice"}),
+	}}
+
+	output := "debug\n@@BEGIN 0@@\ntrue\n@@END 0@@\n" +
+		"\n@@BEGIN 1@@\n{1, 2, 0}\n@@END 1@@\n" +
+		"\n@@BEGIN 2@@\nAlice\nBob\n\n@@END 2@@\n"
+	result := ParseCDriverOutput(check, output, "")
+	assert.Len(t, result.Assertions, 3)
+	assert.NoError(t, result.Error())
+
+	output = "\n@@BEGIN 0@@\nfalse\n@@END 0@@\n"
+	result = ParseCDriverOutput(check, output, "program crashed (signal: segmentation fault)")
+	assert.False(t, result.Assertions[0].Passed)
+	assert.Equal(t, "program crashed (signal: segmentation fault)", result.Assertions[1].Err)
+	assert.Equal(t, "not reached", result.Assertions[2].Err)
+	assert.EqualError(t, result.Error(), `vote(0, "Alice", ranks): expected "true", got "false"`)
+}
+
+func TestCFunctionTester(t *testing.T) {
+	if _, err := exec.LookPath("clang"); err != nil {
+		t.Skip("clang not installed")
+	}
+	dir := t.TempDir()
+	source := `#include <stdio.h>
+
+int counter = 0;
+
+int add(int a, int b)
+{
+    return a + b;
+}
+
+// 故意写错：应该返回 a - b
+int subtract(int a, int b)
+{
+    return b - a;
+}
+
+void greet(const char *name)
+{
+    counter++;
+    printf("hello, %s\n", name);
+}
+
+int main(void)
+{
+    printf("main should not run\n");
+    return 1;
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "math.c"), []byte(source), 0644))
+
+	tester := NewCFunctionTester(dir, "math.c")
+	checks := []CFunctionCheck{
+		{Name: "add", Steps: []CStep{ExpectInt("add(2, 3)", 5), ExpectBool("add(-1, 1) == 0", true)}},
+		{Name: "subtract", Steps: []CStep{ExpectInt("subtract(5, 3)", 2), ExpectInt("subtract(3, 3)", 0)}},
+		{Name: "greet", Steps: []CStep{
+			ExpectOutput(`greet("Alice");`, "hello, Alice"), ExpectInt("counter", 1),
+			CStmt("int values[] = {add(1, 1), add(2, 2)};"), ExpectIntArray("values", []int{2, 4}),
+		}},
+		{Name: "crash", Steps: []CStep{ExpectInt("add(1, 1)", 2), CStmt("int *null = NULL; *null = 1;"), ExpectInt("counter", 0)}},
+	}
+	require.NoError(t, tester.Build(checks))
+	defer tester.Cleanup()
+
+	result := tester.Run(0)
+	assert.NoError(t, result.Error())
+	assert.Len(t, result.Assertions, 2)
+
+	// 每个断言单独报告结果
+	result = tester.Run(1)
+	assert.False(t, result.Assertions[0].Passed)
+	assert.Equal(t, "-2", result.Assertions[0].Actual)
+	assert.True(t, result.Assertions[1].Passed)
+	assert.EqualError(t, result.Error(), `subtract(5, 3): expected "2", got "-2"`)
+
+	// 学生代码的 main 不会运行，输出只在标记之间被捕获
+	result = tester.Run(2)
+	assert.NoError(t, result.Error())
+	assert.Equal(t, "hello, Alice", result.Assertions[0].Actual)
+
+	result = tester.Run(3)
+	assert.True(t, result.Assertions[0].Passed)
+	assert.Contains(t, result.Assertions[1].Err, "program crashed")
+
+	tester.Cleanup()
+	_, err := os.Stat(filepath.Join(dir, "math_checks.c"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,18 @@
+package helpers
+
+import "fmt"
+
+// RenamedMain 是学生代码中 main 被重命名后的函数名，harness 通过它调用学生的 main
+const RenamedMain = "distro_main"
+
+// RenameMain 返回 main 被重命名为 distro_main 的学生代码，可与 harness 拼接为同一个翻译单元
+// 重命名由预处理器完成，只替换 main 这个标识符：注释和字符串中的 main 不受影响，
+// int\nmain(、signed int main( 等写法也能正确处理；#line 使编译错误指向原文件的行号
+func RenameMain(filename string, code []byte) string {
+	return fmt.Sprintf("#define main %s\n#line 1 %q\n%s\n#undef main\n", RenamedMain, filename, code)
+}
+
+// CombineWithHarness 将学生代码 (main 已重命名) 与 harness 拼接，harness 中的 main 保持不变
+func CombineWithHarness(filename string, code []byte, harnessName string, harness []byte) string {
+	return RenameMain(filename, code) + fmt.Sprintf("#line 1 %q\n%s", harnessName, harness)
+}
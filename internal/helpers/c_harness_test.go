@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameMain(t *testing.T) {
+	code := "// int main(void)\nint\nmain(void)\n{\n    return 0;\n}"
+	renamed := RenameMain("hello.c", []byte(code))
+
+	assert.True(t, strings.HasPrefix(renamed, "#define main distro_main\n#line 1 \"hello.c\"\n"))
+	assert.Contains(t, renamed, code, "student code should be left untouched")
+	assert.True(t, strings.HasSuffix(renamed, "\n#undef main\n"))
+}
+
+func TestCombineWithHarness(t *testing.T) {
+	combined := CombineWithHarness("a.c", []byte("int main(void) { return 1; }"), "a_test.c", []byte("int main(void) { return distro_main(); }\n"))
+
+	undef := strings.Index(combined, "#undef main")
+	harness := strings.Index(combined, "#line 1 \"a_test.c\"\nint main(void) { return distro_main(); }")
+	assert.Greater(t, undef, 0)
+	assert.Greater(t, harness, undef, "harness main must come after #undef")
+}
+
+// compileTestC 用 clang 编译 source，返回可执行文件路径和编译输出
+func compileTestC(t *testing.T, source string) (string, string, error) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "combined.c"), []byte(source), 0644))
+	binary := filepath.Join(dir, "combined")
+	out, err := exec.Command("clang", "-o", binary, filepath.Join(dir, "combined.c")).CombinedOutput()
+	return binary, string(out), err
+}
+
+func TestCombineWithHarnessCompiles(t *testing.T) {
+	if _, err := exec.LookPath("clang"); err != nil {
+		t.Skip("clang not installed")
+	}
+
+	// 注释和字符串中的 main 不受影响，跨行的 int\nmain( 也被重命名
+	student := "#include <stdio.h>\n\n// main prints a greeting\nint\nmain(int argc, char *argv[])\n{\n    printf(\"main: %d\\n\", argc);\n    return 3;\n}\n"
+	harness := "int main(void)\n{\n    char *argv[] = {\"hello\", \"world\", NULL};\n    return distro_main(2, argv) + 1;\n}\n"
+	binary, out, err := compileTestC(t, CombineWithHarness("hello.c", []byte(student), "hello_test.c", []byte(harness)))
+	require.NoError(t, err, out)
+
+	stdout, err := exec.Command(binary).Output()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 4, exitErr.ExitCode())
+	assert.Equal(t, "main: 2\n", string(stdout))
+
+	// #line 使编译错误指向学生文件和 harness 各自的行号
+	_, out, err = compileTestC(t, CombineWithHarness("broken.c", []byte("int main(void)\n{\n    return x;\n}\n"), "broken_test.c", []byte(harness)))
+	require.Error(t, err)
+	assert.Contains(t, out, "broken.c:3")
+	_, out, err = compileTestC(t, CombineWithHarness("hello.c", []byte(student), "broken_test.c", []byte("int main(void)\n{\n    return y;\n}\n")))
+	require.Error(t, err)
+	assert.Contains(t, out, "broken_test.c:3")
+}
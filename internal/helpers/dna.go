@@ -0,0 +1,302 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// DNANoMatch 是 dna.py 在没有匹配者时的输出
+const DNANoMatch = "No match"
+
+// dnaBases 是 DNA 的四种碱基
+const dnaBases = "ACGT"
+
+// dnaSTRPool 是生成数据库时可选的 STR (与发行版数据库中的 STR 相同)
+var dnaSTRPool = []string{"AGATC", "TTTTTTCT", "AATG", "TCTAG", "GATA", "TATC", "GAAA", "TCTG"}
+
+// dnaNames 是生成数据库时使用的名字
+var dnaNames = []string{
+	"Albus", "Cedric", "Draco", "Fred", "Ginny", "Hagrid", "Harry", "Hermione",
+	"James", "Kingsley", "Lavender", "Lily", "Lucius", "Luna", "Minerva", "Neville",
+	"Petunia", "Remus", "Ron", "Severus", "Sirius", "Vernon", "Alice", "Bob", "Charlie",
+}
+
+// DNAPerson 是数据库中的一个人及其每个 STR 的最长连续重复次数
+type DNAPerson struct {
+	Name   string
+	Counts []int
+}
+
+// DNADatabase 是 STR 数据库 (对应 databases/*.csv)
+type DNADatabase struct {
+	STRs   []string
+	People []DNAPerson
+}
+
+// DNACaseKind 是生成的测试序列类型
+type DNACaseKind int
+
+const (
+	// DNAPlanted 是植入了某人各 STR 重复的序列
+	DNAPlanted DNACaseKind = iota
+	// DNANearMiss 在植入的重复旁边加入被一个碱基打断的重复
+	DNANearMiss
+	// DNAOverlap 的 STR 中包含另一个 STR 的轮换，二者的重复互相重叠
+	DNAOverlap
+	// DNAUnmatched 是与数据库中某人只差一个 STR 的序列，不匹配任何人
+	DNAUnmatched
+)
+
+// DNACase 是生成的 dna 测试用例
+type DNACase struct {
+	Database DNADatabase
+	Sequence string
+	Expected string
+}
+
+// LongestMatch 返回 str 在 sequence 中最长的连续重复次数 (与发行版 longest_match 一致)
+func LongestMatch(sequence, str string) int {
+	longest := 0
+	for i := range sequence {
+		count := 0
+		for start := i; strings.HasPrefix(sequence[start:], str); start += len(str) {
+			count++
+		}
+		if count > longest {
+			longest = count
+		}
+	}
+	return longest
+}
+
+// Profile 返回序列中每个 STR 的最长连续重复次数
+func (db *DNADatabase) Profile(sequence string) []int {
+	counts := make([]int, len(db.STRs))
+	for i, str := range db.STRs {
+		counts[i] = LongestMatch(sequence, str)
+	}
+	return counts
+}
+
+// Match 返回与序列匹配的人名 (所有 STR 次数都相同)，没有时返回 "No match"
+func (db *DNADatabase) Match(sequence string) string {
+	profile := db.Profile(sequence)
+	for _, person := range db.People {
+		if equalCounts(person.Counts, profile) {
+			return person.Name
+		}
+	}
+	return DNANoMatch
+}
+
+// CSV 返回 databases/*.csv 格式的数据库
+func (db *DNADatabase) CSV() string {
+	var b strings.Builder
+	b.WriteString("name," + strings.Join(db.STRs, ",") + "\n")
+	for _, person := range db.People {
+		b.WriteString(person.Name)
+		for _, c := range person.Counts {
+			b.WriteString("," + strconv.Itoa(c))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// String 以可读形式输出数据库 (用于失败时展示)
+func (db *DNADatabase) String() string {
+	return strings.TrimRight(db.CSV(), "\n")
+}
+
+// GenerateDNADatabase 生成含 strCount 个 STR、people 个人的随机数据库 (每个人的次数组合互不相同)
+func GenerateDNADatabase(strCount, people int) DNADatabase {
+	db := DNADatabase{STRs: random.RandomElementsFromArray(dnaSTRPool, strCount)}
+	names := random.ShuffleArray(dnaNames)
+	for len(db.People) < people {
+		name := names[len(db.People)%len(names)]
+		if len(db.People) >= len(names) {
+			name += strconv.Itoa(len(db.People) / len(names))
+		}
+		counts := make([]int, strCount)
+		for i := range counts {
+			counts[i] = random.RandomInt(2, 20)
+		}
+		if db.indexOf(counts) < 0 {
+			db.People = append(db.People, DNAPerson{Name: name, Counts: counts})
+		}
+	}
+	return db
+}
+
+// indexOf 返回次数与 counts 相同的人的下标，没有时返回 -1
+func (db *DNADatabase) indexOf(counts []int) int {
+	for i, person := range db.People {
+		if equalCounts(person.Counts, counts) {
+			return i
+		}
+	}
+	return -1
+}
+
+// dnaCaseAttempts 是生成一个测试用例时最多重新生成的次数；
+// 正常情况下几次之内就能成功，超过说明生成逻辑有问题
+const dnaCaseAttempts = 100
+
+// GenerateDNACase 生成指定类型的测试用例；期望结果由参考实现根据实际生成的序列决定
+func GenerateDNACase(kind DNACaseKind) (DNACase, error) {
+	for attempt := 0; attempt < dnaCaseAttempts; attempt++ {
+		db := GenerateDNADatabase(random.RandomInt(3, 7), random.RandomInt(5, 30))
+		if kind == DNAOverlap {
+			if err := addRotatedSTR(&db); err != nil {
+				return DNACase{}, err
+			}
+		}
+
+		target := random.RandomInt(0, len(db.People))
+		counts := append([]int(nil), db.People[target].Counts...)
+		if kind == DNAUnmatched {
+			i := random.RandomInt(0, len(counts))
+			if random.RandomInt(0, 2) == 0 {
+				counts[i]++
+			} else {
+				counts[i]--
+			}
+		}
+
+		sequence := plantDNASequence(db.STRs, counts, kind == DNANearMiss)
+		profile := db.Profile(sequence)
+
+		if kind == DNAUnmatched {
+			if db.indexOf(profile) < 0 {
+				return DNACase{Database: db, Sequence: sequence, Expected: DNANoMatch}, nil
+			}
+			continue
+		}
+
+		// 以序列的实际结果为准 (填充碱基可能恰好延长了某个重复)
+		if other := db.indexOf(profile); other >= 0 && other != target {
+			continue
+		}
+		db.People[target].Counts = profile
+		return DNACase{Database: db, Sequence: sequence, Expected: db.People[target].Name}, nil
+	}
+	return DNACase{}, fmt.Errorf("could not generate a DNA case of kind %d after %d attempts", kind, dnaCaseAttempts)
+}
+
+// addRotatedSTR 加入某个 STR 的轮换 (例如 AATG -> ATGA)，并为每个人补上该列
+// 没有可加入的轮换 (STR 都是 AAAA 这样的周期串，或轮换都已在数据库中) 时返回错误
+func addRotatedSTR(db *DNADatabase) error {
+	var rotations []string
+	for _, str := range db.STRs {
+		for shift := 1; shift < len(str); shift++ {
+			rotated := str[shift:] + str[:shift]
+			if !containsString(db.STRs, rotated) && !containsString(rotations, rotated) {
+				rotations = append(rotations, rotated)
+			}
+		}
+	}
+	if len(rotations) == 0 {
+		return fmt.Errorf("none of the STRs %s has a rotation that is not already in the database", strings.Join(db.STRs, ", "))
+	}
+	db.STRs = append(db.STRs, rotations[random.RandomInt(0, len(rotations))])
+
+	for i := range db.People {
+		db.People[i].Counts = append(db.People[i].Counts, random.RandomInt(2, 20))
+	}
+	// 新列可能使两个人相同，重新调整直到唯一
+	for i := range db.People {
+		for attempt := 0; db.indexOf(db.People[i].Counts) != i; attempt++ {
+			if attempt == dnaCaseAttempts {
+				return fmt.Errorf("could not give %s a unique profile after %d attempts", db.People[i].Name, dnaCaseAttempts)
+			}
+			db.People[i].Counts[len(db.STRs)-1] = random.RandomInt(2, 20)
+		}
+	}
+	return nil
+}
+
+// plantDNASequence 生成序列：每个 STR 植入一段 counts[i] 次的重复，外加更短的干扰重复，
+// 各段之间用随机碱基填充；nearMiss 时再加入被一个碱基打断的长重复
+func plantDNASequence(strs []string, counts []int, nearMiss bool) string {
+	var segments []string
+	for i, str := range strs {
+		if counts[i] > 0 {
+			segments = append(segments, strings.Repeat(str, counts[i]))
+		}
+		if counts[i] > 1 {
+			segments = append(segments, strings.Repeat(str, random.RandomInt(1, counts[i])))
+		}
+		if nearMiss && counts[i] > 1 {
+			before := random.RandomInt(1, counts[i])
+			after := counts[i] - before + random.RandomInt(0, counts[i])
+			if after >= counts[i] {
+				after = counts[i] - 1
+			}
+			segments = append(segments, strings.Repeat(str, before)+mutateBase(str)+strings.Repeat(str, after))
+		}
+	}
+	segments = random.ShuffleArray(segments)
+
+	var b strings.Builder
+	b.WriteString(randomBases(random.RandomInt(5, 30)))
+	for _, segment := range segments {
+		b.WriteString(segment)
+		b.WriteString(randomBases(random.RandomInt(1, 12)))
+	}
+	return b.String()
+}
+
+// mutateBase 随机替换 str 中的一个碱基
+func mutateBase(str string) string {
+	b := []byte(str)
+	i := random.RandomInt(0, len(b))
+	for {
+		c := dnaBases[random.RandomInt(0, len(dnaBases))]
+		if c != b[i] {
+			b[i] = c
+			return string(b)
+		}
+	}
+}
+
+func randomBases(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = dnaBases[random.RandomInt(0, len(dnaBases))]
+	}
+	return string(b)
+}
+
+func equalCounts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe 返回用例的可读描述 (数据库和序列的实际 STR 次数)
+func (c DNACase) Describe() string {
+	profile := c.Database.Profile(c.Sequence)
+	parts := make([]string, len(profile))
+	for i, n := range profile {
+		parts[i] = fmt.Sprintf("%s=%d", c.Database.STRs[i], n)
+	}
+	return fmt.Sprintf("database:\n%s\nsequence (%d bases): %s", c.Database.String(), len(c.Sequence), strings.Join(parts, " "))
+}
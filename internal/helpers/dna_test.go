@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongestMatch(t *testing.T) {
+	assert.Equal(t, 3, LongestMatch("AGATCAGATCAGATC", "AGATC"))
+	assert.Equal(t, 2, LongestMatch("TTAGATCTTAGATCAGATCTT", "AGATC"))
+	assert.Equal(t, 0, LongestMatch("TTTTTTCT", "AGATC"))
+	assert.Equal(t, 3, LongestMatch("AAAAAAA", "AA"))
+}
+
+func TestDNADatabase(t *testing.T) {
+	db := DNADatabase{
+		STRs: []string{"AGATC", "AATG"},
+		People: []DNAPerson{
+			{Name: "Alice", Counts: []int{2, 1}},
+			{Name: "Bob", Counts: []int{1, 2}},
+		},
+	}
+	assert.Equal(t, "name,AGATC,AATG\nAlice,2,1\nBob,1,2\n", db.CSV())
+	assert.Equal(t, "Alice", db.Match("AGATCAGATCTTAATGTT"))
+	assert.Equal(t, "Bob", db.Match("AGATCTTAATGAATG"))
+	assert.Equal(t, DNANoMatch, db.Match("AGATCAGATCAATGAATG"))
+}
+
+func TestGenerateDNACase(t *testing.T) {
+	random.Init()
+
+	for _, kind := range []DNACaseKind{DNAPlanted, DNANearMiss, DNAOverlap, DNAUnmatched} {
+		for i := 0; i < 20; i++ {
+			c, err := GenerateDNACase(kind)
+			require.NoError(t, err, "kind=%d", kind)
+			assert.Equal(t, c.Expected, c.Database.Match(c.Sequence), "kind=%d", kind)
+			assert.Empty(t, strings.Trim(c.Sequence, dnaBases), "kind=%d", kind)
+
+			seen := make(map[string]bool)
+			for _, person := range c.Database.People {
+				key := fmt.Sprint(person.Counts)
+				assert.False(t, seen[key], "duplicate profile %s", key)
+				seen[key] = true
+			}
+			if kind == DNAUnmatched {
+				assert.Equal(t, DNANoMatch, c.Expected)
+			} else {
+				assert.NotEqual(t, DNANoMatch, c.Expected)
+			}
+		}
+	}
+}
+
+func TestAddRotatedSTR(t *testing.T) {
+	random.Init()
+
+	db := DNADatabase{STRs: []string{"AATG"}, People: []DNAPerson{{"Alice", []int{2}}, {"Bob", []int{3}}}}
+	require.NoError(t, addRotatedSTR(&db))
+	require.Len(t, db.STRs, 2)
+	assert.Contains(t, []string{"ATGA", "TGAA", "GAAT"}, db.STRs[1])
+	assert.Len(t, db.People[0].Counts, 2)
+	assert.Len(t, db.People[1].Counts, 2)
+
+	db = DNADatabase{STRs: []string{"AAAA"}, People: []DNAPerson{{"Alice", []int{2}}}}
+	assert.EqualError(t, addRotatedSTR(&db), "none of the STRs AAAA has a rotation that is not already in the database")
+
+	db = DNADatabase{STRs: []string{"GATA", "ATAG", "TAGA", "AGAT"}, People: []DNAPerson{{"Alice", []int{2, 3, 4, 5}}}}
+	assert.Error(t, addRotatedSTR(&db))
+}
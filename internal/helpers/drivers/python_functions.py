@@ -0,0 +1,89 @@
+"""Call functions in a student's Python module and report the results as JSON.
+
+Usage: python3 python_functions.py <module.py> <request.json>
+
+The module is imported under a name other than __main__, so code guarded by
+`if __name__ == "__main__"` does not run. Standard input is empty and
+sys.argv only contains the module path, so a module that calls main()
+unconditionally usually stops early (e.g. with a usage message); functions
+defined before that point are still available.
+
+Each call reads its own standard input (the call's "stdin", empty by default),
+so input()-driven functions such as main() can be called directly.
+"""
+
+import contextlib
+import importlib.util
+import inspect
+import io
+import json
+import os
+import sys
+import traceback
+
+RESULT_MARKER = "@@RESULT@@"
+
+
+def describe(error):
+    return {
+        "type": type(error).__name__,
+        "message": str(error),
+        "traceback": "".join(traceback.format_exception(type(error), error, error.__traceback__)),
+    }
+
+
+def encode(value):
+    try:
+        json.dumps(value)
+        return {"value": value}
+    except (TypeError, ValueError):
+        return {"repr": repr(value)}
+
+
+def main():
+    path = sys.argv[1]
+    with open(sys.argv[2]) as f:
+        request = json.load(f)
+
+    real_stdout = sys.stdout
+    sys.argv = [path]
+    sys.stdin = io.StringIO("")
+    sys.path.insert(0, os.path.dirname(os.path.abspath(path)))
+
+    response = {"import_error": None, "calls": []}
+    captured = io.StringIO()
+    spec = importlib.util.spec_from_file_location("student_module", path)
+    module = importlib.util.module_from_spec(spec)
+    sys.modules["student_module"] = module
+    with contextlib.redirect_stdout(captured):
+        try:
+            spec.loader.exec_module(module)
+        except BaseException as error:
+            response["import_error"] = describe(error)
+    response["import_stdout"] = captured.getvalue()
+    response["functions"] = [
+        name for name, value in vars(module).items()
+        if inspect.isfunction(value) and value.__module__ == module.__name__
+    ]
+
+    for call in request.get("calls") or []:
+        result = {}
+        captured = io.StringIO()
+        sys.stdin = io.StringIO(call.get("stdin") or "")
+        with contextlib.redirect_stdout(captured):
+            try:
+                function = getattr(module, call["function"], None)
+                if not callable(function):
+                    raise AttributeError("module has no function named " + call["function"])
+                result.update(encode(function(*(call.get("args") or []))))
+            except BaseException as error:
+                result["exception"] = describe(error)
+        result["stdout"] = captured.getvalue()
+        response["calls"].append(result)
+
+    real_stdout.write(RESULT_MARKER + json.dumps(response) + "\n")
+    real_stdout.flush()
+
+
+if __name__ == "__main__":
+    main()
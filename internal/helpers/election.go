@@ -0,0 +1,440 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// ElectionMaxCandidates 是 plurality/runoff/tideman 中 MAX (候选人上限)
+const ElectionMaxCandidates = 9
+
+// RunoffMaxVoters 是 runoff.c 中 MAX_VOTERS 的值
+const RunoffMaxVoters = 100
+
+// electionNames 是生成候选人时使用的名字
+var electionNames = []string{
+	"Alice", "Bob", "Charlie", "David", "Erin", "Frank",
+	"Grace", "Heidi", "Ivan", "Judy", "Mallory", "Oscar",
+}
+
+// Election 是一次生成的选举
+// plurality 的选票只有一个名字；runoff/tideman 的选票是所有候选人的完整排名
+type Election struct {
+	Candidates []string
+	Ballots    [][]string
+}
+
+// RandomCandidates 随机选取 n 个不同的候选人名字
+func RandomCandidates(n int) []string {
+	return random.RandomElementsFromArray(electionNames, n)
+}
+
+// RandomPluralityElection 生成随机的 plurality 选举
+// invalidPercent 为投给不存在候选人的选票百分比
+func RandomPluralityElection(candidates, voters, invalidPercent int) Election {
+	e := Election{Candidates: RandomCandidates(candidates)}
+	weights := randomWeights(candidates)
+	for i := 0; i < voters; i++ {
+		if random.RandomInt(0, 100) < invalidPercent {
+			e.Ballots = append(e.Ballots, []string{"Nobody"})
+			continue
+		}
+		e.Ballots = append(e.Ballots, []string{e.Candidates[weightedIndex(weights)]})
+	}
+	return e
+}
+
+// TiedPluralityElection 生成前 tied 名候选人票数相同且领先的 plurality 选举
+func TiedPluralityElection(candidates, tied, votesEach int) Election {
+	e := Election{Candidates: RandomCandidates(candidates)}
+	for i, name := range e.Candidates {
+		votes := votesEach
+		if i >= tied {
+			votes = random.RandomInt(0, votesEach)
+		}
+		for j := 0; j < votes; j++ {
+			e.Ballots = append(e.Ballots, []string{name})
+		}
+	}
+	e.Ballots = random.ShuffleArray(e.Ballots)
+	return e
+}
+
+// RandomRankedElection 生成随机的排名选举 (候选人人气不同，使结果不总是平局)
+func RandomRankedElection(candidates, voters int) Election {
+	e := Election{Candidates: RandomCandidates(candidates)}
+	weights := randomWeights(candidates)
+	for i := 0; i < voters; i++ {
+		e.Ballots = append(e.Ballots, weightedRanking(e.Candidates, weights))
+	}
+	return e
+}
+
+// TiedRankedElection 生成所有候选人始终平局的排名选举 (选票为候选人列表的轮换)
+func TiedRankedElection(candidates, cycles int) Election {
+	e := Election{Candidates: RandomCandidates(candidates)}
+	for c := 0; c < cycles; c++ {
+		for shift := 0; shift < candidates; shift++ {
+			ballot := make([]string, candidates)
+			for i := range ballot {
+				ballot[i] = e.Candidates[(i+shift)%candidates]
+			}
+			e.Ballots = append(e.Ballots, ballot)
+		}
+	}
+	e.Ballots = random.ShuffleArray(e.Ballots)
+	return e
+}
+
+// CyclicRankedElection 生成前三名候选人构成 Condorcet 循环 (A>B, B>C, C>A) 的排名选举
+// 三种轮换选票的数量互不相同且满足三角不等式，因此循环中三条边强度不同；
+// 其余候选人在每张选票上都排在最后，顺序固定
+func CyclicRankedElection(candidates int) Election {
+	e := Election{Candidates: RandomCandidates(candidates)}
+	var a, b, c int
+	for {
+		a, b, c = random.RandomInt(2, 12), random.RandomInt(2, 12), random.RandomInt(2, 12)
+		if a != b && b != c && a != c && a < b+c && b < a+c && c < a+b {
+			break
+		}
+	}
+
+	rest := e.Candidates[3:]
+	for i, count := range []int{a, b, c} {
+		ballot := []string{e.Candidates[i%3], e.Candidates[(i+1)%3], e.Candidates[(i+2)%3]}
+		ballot = append(ballot, rest...)
+		for j := 0; j < count; j++ {
+			e.Ballots = append(e.Ballots, ballot)
+		}
+	}
+	e.Ballots = random.ShuffleArray(e.Ballots)
+	return e
+}
+
+func randomWeights(n int) []int {
+	weights := make([]int, n)
+	for i := range weights {
+		weights[i] = random.RandomInt(1, 10)
+	}
+	return weights
+}
+
+func weightedIndex(weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	r := random.RandomInt(0, total)
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(weights) - 1
+}
+
+// weightedRanking 按权重依次抽取候选人，得到一个完整排名
+func weightedRanking(candidates []string, weights []int) []string {
+	remaining := append([]string(nil), candidates...)
+	w := append([]int(nil), weights...)
+	ranking := make([]string, 0, len(candidates))
+	for len(remaining) > 0 {
+		i := weightedIndex(w)
+		ranking = append(ranking, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+		w = append(w[:i], w[i+1:]...)
+	}
+	return ranking
+}
+
+// Input 返回按程序提示顺序输入的标准输入 (先是选民数，再逐行输入选票)
+func (e Election) Input() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(e.Ballots)) + "\n")
+	for _, ballot := range e.Ballots {
+		for _, name := range ballot {
+			b.WriteString(name + "\n")
+		}
+	}
+	return b.String()
+}
+
+// String 以可读形式输出选举 (相同的选票合并计数)，用于失败时展示
+func (e Election) String() string {
+	var order []string
+	counts := make(map[string]int)
+	for _, ballot := range e.Ballots {
+		key := strings.Join(ballot, " > ")
+		if counts[key] == 0 {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "candidates: %s\n", strings.Join(e.Candidates, " "))
+	fmt.Fprintf(&b, "voters: %d\n", len(e.Ballots))
+	for _, key := range order {
+		fmt.Fprintf(&b, "  %3d × %s\n", counts[key], key)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Args 返回运行程序时的命令行参数 (候选人名字)
+func (e Election) Args() []string {
+	return append([]string(nil), e.Candidates...)
+}
+
+// PluralityWinners 返回 plurality 的获胜者 (得票最多的所有候选人，按候选人顺序)
+// 投给不存在候选人的选票无效
+func PluralityWinners(e Election) []string {
+	votes := make(map[string]int)
+	for _, ballot := range e.Ballots {
+		votes[ballot[0]]++
+	}
+	max := 0
+	for _, name := range e.Candidates {
+		if votes[name] > max {
+			max = votes[name]
+		}
+	}
+	var winners []string
+	for _, name := range e.Candidates {
+		if votes[name] == max {
+			winners = append(winners, name)
+		}
+	}
+	return winners
+}
+
+// RunoffWinners 返回即时决选 (instant-runoff) 的获胜者和进行的轮数
+// 每轮统计每位选民排名最高且未被淘汰的候选人；得票超过半数者获胜；
+// 若剩余候选人全部平局则全部获胜；否则淘汰所有得票最少的候选人
+func RunoffWinners(e Election) ([]string, int) {
+	eliminated := make(map[string]bool)
+	for round := 1; ; round++ {
+		votes := make(map[string]int)
+		for _, ballot := range e.Ballots {
+			for _, name := range ballot {
+				if !eliminated[name] {
+					votes[name]++
+					break
+				}
+			}
+		}
+
+		for _, name := range e.Candidates {
+			if !eliminated[name] && votes[name] > len(e.Ballots)/2 {
+				return []string{name}, round
+			}
+		}
+
+		min := -1
+		for _, name := range e.Candidates {
+			if !eliminated[name] && (min == -1 || votes[name] < min) {
+				min = votes[name]
+			}
+		}
+
+		var remaining []string
+		tie := true
+		for _, name := range e.Candidates {
+			if !eliminated[name] {
+				remaining = append(remaining, name)
+				if votes[name] != min {
+					tie = false
+				}
+			}
+		}
+		if tie {
+			return remaining, round
+		}
+
+		for _, name := range remaining {
+			if votes[name] == min {
+				eliminated[name] = true
+			}
+		}
+	}
+}
+
+// tidemanPair 是 tideman 中的一对候选人 (下标)
+type tidemanPair struct {
+	winner, loser, strength int
+}
+
+// tidemanMaxOrderings 是判断结果是否依赖同强度 pair 顺序时最多尝试的排列数
+const tidemanMaxOrderings = 5040
+
+// TidemanWinner 返回 ranked pairs 的获胜者和因成环而被跳过的 pair 数
+// 当结果取决于同强度 pair 的排序，或锁定后的图有多个源点时返回错误
+func TidemanWinner(e Election) (string, int, error) {
+	index := make(map[string]int)
+	for i, name := range e.Candidates {
+		index[name] = i
+	}
+	n := len(e.Candidates)
+	preferences := make([][]int, n)
+	for i := range preferences {
+		preferences[i] = make([]int, n)
+	}
+	for _, ballot := range e.Ballots {
+		for i := 0; i < len(ballot); i++ {
+			for j := i + 1; j < len(ballot); j++ {
+				preferences[index[ballot[i]]][index[ballot[j]]]++
+			}
+		}
+	}
+
+	var pairs []tidemanPair
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if preferences[i][j] > preferences[j][i] {
+				pairs = append(pairs, tidemanPair{i, j, preferences[i][j]})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].strength > pairs[b].strength })
+
+	// 按强度分组，同组内的顺序可能影响结果
+	var groups [][]tidemanPair
+	orderings := 1
+	for i := 0; i < len(pairs); {
+		j := i
+		for j < len(pairs) && pairs[j].strength == pairs[i].strength {
+			j++
+		}
+		groups = append(groups, pairs[i:j])
+		for k := 2; k <= j-i; k++ {
+			orderings *= k
+			if orderings > tidemanMaxOrderings {
+				return "", 0, fmt.Errorf("too many equally strong pairs to determine a unique winner")
+			}
+		}
+		i = j
+	}
+
+	winners := make(map[int]bool)
+	skipped := -1
+	var ambiguous error
+	var try func(g int, order []tidemanPair)
+	try = func(g int, order []tidemanPair) {
+		if ambiguous != nil {
+			return
+		}
+		if g == len(groups) {
+			winner, s, err := lockTidemanPairs(n, order)
+			if err != nil {
+				ambiguous = err
+				return
+			}
+			winners[winner] = true
+			if skipped == -1 {
+				skipped = s
+			}
+			return
+		}
+		permute(groups[g], func(p []tidemanPair) {
+			try(g+1, append(append([]tidemanPair(nil), order...), p...))
+		})
+	}
+	try(0, nil)
+
+	if ambiguous != nil {
+		return "", 0, ambiguous
+	}
+	if len(winners) != 1 {
+		return "", 0, fmt.Errorf("winner depends on the order of equally strong pairs")
+	}
+	for w := range winners {
+		return e.Candidates[w], skipped, nil
+	}
+	return "", 0, fmt.Errorf("no winner")
+}
+
+// lockTidemanPairs 按顺序锁定不会成环的 pair，返回唯一的源点
+func lockTidemanPairs(n int, pairs []tidemanPair) (int, int, error) {
+	locked := make([][]bool, n)
+	for i := range locked {
+		locked[i] = make([]bool, n)
+	}
+	var reaches func(from, to int) bool
+	reaches = func(from, to int) bool {
+		if from == to {
+			return true
+		}
+		for next := 0; next < n; next++ {
+			if locked[from][next] && reaches(next, to) {
+				return true
+			}
+		}
+		return false
+	}
+
+	skipped := 0
+	for _, p := range pairs {
+		if reaches(p.loser, p.winner) {
+			skipped++
+			continue
+		}
+		locked[p.winner][p.loser] = true
+	}
+
+	var sources []int
+	for j := 0; j < n; j++ {
+		source := true
+		for i := 0; i < n; i++ {
+			if locked[i][j] {
+				source = false
+				break
+			}
+		}
+		if source {
+			sources = append(sources, j)
+		}
+	}
+	if len(sources) != 1 {
+		return 0, 0, fmt.Errorf("locked graph has %d sources", len(sources))
+	}
+	return sources[0], skipped, nil
+}
+
+// permute 对 items 的每一种排列调用 f
+func permute(items []tidemanPair, f func([]tidemanPair)) {
+	p := append([]tidemanPair(nil), items...)
+	var rec func(k int)
+	rec = func(k int) {
+		if k == len(p) {
+			f(p)
+			return
+		}
+		for i := k; i < len(p); i++ {
+			p[k], p[i] = p[i], p[k]
+			rec(k + 1)
+			p[k], p[i] = p[i], p[k]
+		}
+	}
+	rec(0)
+}
+
+// electionPromptRegex 匹配程序的输入提示 (标准输入不会回显，提示与输出混在一起)
+var electionPromptRegex = regexp.MustCompile(`(Number of voters|Vote|Rank \d+): ?`)
+
+// ParseElectionWinners 从程序输出中去掉输入提示和 "Invalid vote." 行，返回获胜者名单
+func ParseElectionWinners(output string) []string {
+	output = electionPromptRegex.ReplaceAllString(output, "\n")
+	winners := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "Invalid vote." {
+			winners = append(winners, line)
+		}
+	}
+	return winners
+}
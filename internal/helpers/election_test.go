@@ -0,0 +1,101 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+)
+
+// rankedElection 根据 "A>B>C" 形式的选票构造选举
+func rankedElection(candidates string, ballots ...string) Election {
+	e := Election{Candidates: strings.Fields(candidates)}
+	for _, b := range ballots {
+		e.Ballots = append(e.Ballots, strings.Split(b, ">"))
+	}
+	return e
+}
+
+func TestPluralityWinners(t *testing.T) {
+	e := Election{
+		Candidates: []string{"Alice", "Bob", "Charlie"},
+		Ballots:    [][]string{{"Alice"}, {"Bob"}, {"Nobody"}, {"Bob"}, {"Alice"}, {"Charlie"}},
+	}
+	assert.Equal(t, []string{"Alice", "Bob"}, PluralityWinners(e))
+
+	e.Ballots = nil
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie"}, PluralityWinners(e))
+}
+
+func TestRunoffWinners(t *testing.T) {
+	// 首轮过半
+	winners, rounds := RunoffWinners(rankedElection("A B C", "A>B>C", "A>C>B", "B>A>C"))
+	assert.Equal(t, []string{"A"}, winners)
+	assert.Equal(t, 1, rounds)
+
+	// C 被淘汰后其选票转给 B
+	winners, rounds = RunoffWinners(rankedElection("A B C",
+		"A>B>C", "A>B>C", "B>A>C", "B>A>C", "C>B>A"))
+	assert.Equal(t, []string{"B"}, winners)
+	assert.Equal(t, 2, rounds)
+
+	// C、D 同时被淘汰后 A、B 平局
+	winners, rounds = RunoffWinners(rankedElection("A B C D",
+		"A>B>C>D", "A>B>C>D", "A>B>C>D", "B>A>C>D", "B>A>C>D", "B>A>C>D", "C>A>B>D", "D>B>A>C"))
+	assert.Equal(t, []string{"A", "B"}, winners)
+	assert.Equal(t, 2, rounds)
+}
+
+func TestTidemanWinner(t *testing.T) {
+	// CS50 示例：A>B (7) 与 C>A (6) 锁定，B>C (5) 成环被跳过
+	var ballots []string
+	for i := 0; i < 3; i++ {
+		ballots = append(ballots, "A>B>C")
+	}
+	for i := 0; i < 2; i++ {
+		ballots = append(ballots, "B>C>A")
+	}
+	for i := 0; i < 4; i++ {
+		ballots = append(ballots, "C>A>B")
+	}
+	winner, skipped, err := TidemanWinner(rankedElection("A B C", ballots...))
+	assert.NoError(t, err)
+	assert.Equal(t, "C", winner)
+	assert.Equal(t, 1, skipped)
+
+	// 完全对称的循环：结果取决于同强度 pair 的顺序
+	_, _, err = TidemanWinner(rankedElection("A B C", "A>B>C", "B>C>A", "C>A>B"))
+	assert.Error(t, err)
+}
+
+func TestGeneratedElections(t *testing.T) {
+	random.Init()
+
+	e := TiedRankedElection(4, 2)
+	winners, _ := RunoffWinners(e)
+	assert.Len(t, e.Ballots, 8)
+	assert.Equal(t, e.Candidates, winners)
+
+	e = CyclicRankedElection(4)
+	_, skipped, err := TidemanWinner(e)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, skipped)
+
+	e = TiedPluralityElection(4, 2, 5)
+	assert.Equal(t, e.Candidates[:2], PluralityWinners(e))
+}
+
+func TestElectionInput(t *testing.T) {
+	e := rankedElection("A B", "A>B", "B>A")
+	assert.Equal(t, "2\nA\nB\nB\nA\n", e.Input())
+	assert.Equal(t, []string{"A", "B"}, e.Args())
+}
+
+func TestParseElectionWinners(t *testing.T) {
+	output := "Number of voters: Rank 1: Rank 2: Rank 3: \nRank 1: Rank 2: Rank 3: \nAlice\n"
+	assert.Equal(t, []string{"Alice"}, ParseElectionWinners(output))
+
+	output = "Number of voters: Vote: Invalid vote.\nVote: Vote: Alice\nBob\n"
+	assert.Equal(t, []string{"Alice", "Bob"}, ParseElectionWinners(output))
+}
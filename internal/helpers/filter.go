@@ -0,0 +1,170 @@
+package helpers
+
+import "math"
+
+// 以下为 filter 各滤镜的参考实现 (与 CS50 官方 helpers.c 的行为一致)
+// 所有函数都返回新图片，不修改输入
+
+// FilterGrayscale 灰度：三个通道取平均值并四舍五入
+func FilterGrayscale(img *BMPImage) *BMPImage {
+	out := img.Clone()
+	for y := range out.Pixels {
+		for x, p := range out.Pixels[y] {
+			avg := roundByte((float64(p.Red) + float64(p.Green) + float64(p.Blue)) / 3.0)
+			out.Pixels[y][x] = RGBTriple{Blue: avg, Green: avg, Red: avg}
+		}
+	}
+	return out
+}
+
+// FilterSepia 怀旧：按 sepia 公式计算，超过 255 时截断
+func FilterSepia(img *BMPImage) *BMPImage {
+	out := img.Clone()
+	for y := range out.Pixels {
+		for x, p := range out.Pixels[y] {
+			r, g, b := float64(p.Red), float64(p.Green), float64(p.Blue)
+			out.Pixels[y][x] = RGBTriple{
+				Red:   roundByte(.393*r + .769*g + .189*b),
+				Green: roundByte(.349*r + .686*g + .168*b),
+				Blue:  roundByte(.272*r + .534*g + .131*b),
+			}
+		}
+	}
+	return out
+}
+
+// FilterReflect 水平翻转
+func FilterReflect(img *BMPImage) *BMPImage {
+	out := img.Clone()
+	for y := range out.Pixels {
+		row := out.Pixels[y]
+		for i, j := 0, len(row)-1; i < j; i, j = i+1, j-1 {
+			row[i], row[j] = row[j], row[i]
+		}
+	}
+	return out
+}
+
+// FilterBlur 模糊：取 3x3 范围内 (图片内的) 像素的平均值
+func FilterBlur(img *BMPImage) *BMPImage {
+	out := NewBMPImage(img.Width, img.Height)
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			var r, g, b, count float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					ny, nx := y+dy, x+dx
+					if ny < 0 || ny >= img.Height || nx < 0 || nx >= img.Width {
+						continue
+					}
+					p := img.Pixels[ny][nx]
+					r += float64(p.Red)
+					g += float64(p.Green)
+					b += float64(p.Blue)
+					count++
+				}
+			}
+			out.Pixels[y][x] = RGBTriple{
+				Red:   roundByte(r / count),
+				Green: roundByte(g / count),
+				Blue:  roundByte(b / count),
+			}
+		}
+	}
+	return out
+}
+
+// sobelGx 和 sobelGy 是 edges 使用的 Sobel 算子
+var (
+	sobelGx = [3][3]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}}
+	sobelGy = [3][3]float64{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}}
+)
+
+// FilterEdges 边缘检测：Sobel 算子，图片外的像素视为黑色
+func FilterEdges(img *BMPImage) *BMPImage {
+	out := NewBMPImage(img.Width, img.Height)
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			var gxR, gxG, gxB, gyR, gyG, gyB float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					ny, nx := y+dy, x+dx
+					if ny < 0 || ny >= img.Height || nx < 0 || nx >= img.Width {
+						continue
+					}
+					p := img.Pixels[ny][nx]
+					kx, ky := sobelGx[dy+1][dx+1], sobelGy[dy+1][dx+1]
+					gxR += kx * float64(p.Red)
+					gxG += kx * float64(p.Green)
+					gxB += kx * float64(p.Blue)
+					gyR += ky * float64(p.Red)
+					gyG += ky * float64(p.Green)
+					gyB += ky * float64(p.Blue)
+				}
+			}
+			out.Pixels[y][x] = RGBTriple{
+				Red:   roundByte(math.Sqrt(gxR*gxR + gyR*gyR)),
+				Green: roundByte(math.Sqrt(gxG*gxG + gyG*gyG)),
+				Blue:  roundByte(math.Sqrt(gxB*gxB + gyB*gyB)),
+			}
+		}
+	}
+	return out
+}
+
+// roundByte 四舍五入并截断到 [0, 255]
+func roundByte(v float64) uint8 {
+	v = math.Round(v)
+	if v > 255 {
+		return 255
+	}
+	if v < 0 {
+		return 0
+	}
+	return uint8(v)
+}
+
+// PixelMismatch 描述第一个不一致的像素
+type PixelMismatch struct {
+	X        int
+	Y        int
+	Expected RGBTriple
+	Actual   RGBTriple
+}
+
+// FirstPixelMismatch 逐像素比较两张同尺寸的图片
+// 返回第一个不一致的像素 (按行优先顺序) 以及不一致像素的总数；完全一致时返回 nil, 0
+func FirstPixelMismatch(expected, actual *BMPImage) (*PixelMismatch, int) {
+	var first *PixelMismatch
+	count := 0
+	for y := 0; y < expected.Height; y++ {
+		for x := 0; x < expected.Width; x++ {
+			e, a := expected.Pixels[y][x], actual.Pixels[y][x]
+			if e == a {
+				continue
+			}
+			if first == nil {
+				first = &PixelMismatch{X: x, Y: y, Expected: e, Actual: a}
+			}
+			count++
+		}
+	}
+	return first, count
+}
+
+// DiffImage 生成差异图：一致的像素显示为暗化的灰度，不一致的像素显示为红色
+func DiffImage(expected, actual *BMPImage) *BMPImage {
+	out := NewBMPImage(expected.Width, expected.Height)
+	for y := 0; y < expected.Height; y++ {
+		for x := 0; x < expected.Width; x++ {
+			e, a := expected.Pixels[y][x], actual.Pixels[y][x]
+			if e != a {
+				out.Pixels[y][x] = RGBTriple{Red: 255}
+				continue
+			}
+			gray := uint8((int(e.Red) + int(e.Green) + int(e.Blue)) / 3 / 3)
+			out.Pixels[y][x] = RGBTriple{Blue: gray, Green: gray, Red: gray}
+		}
+	}
+	return out
+}
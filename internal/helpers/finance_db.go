@@ -0,0 +1,238 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FinanceTransactionsTable 是学生记录交易的表；表名和列名由 FindFinanceTransactionsTables 按列名推断
+type FinanceTransactionsTable struct {
+	Name   string
+	Symbol string
+	Shares string
+	Price  string
+	// Kind 是可选的交易类型列 (如 type = 'sell')，没有时为空
+	Kind string
+}
+
+// FinanceTransaction 是交易表中的一行
+type FinanceTransaction struct {
+	RowID  int64
+	Symbol string
+	Shares int64
+	Cents  int64
+	Kind   string
+}
+
+// SignedShares 返回带符号的股数：卖出为负
+// 股数都存为正数、另用类型列区分买卖的表，类型含 "sell" 的行按卖出计算
+func (t FinanceTransaction) SignedShares() int64 {
+	if t.Shares > 0 && strings.Contains(strings.ToLower(t.Kind), "sell") {
+		return -t.Shares
+	}
+	return t.Shares
+}
+
+// financeTransactionsNames 是像交易记录的表名片段；同时有持仓表 (如 portfolio) 和交易表时，这些表排在前面
+var financeTransactionsNames = []string{"transaction", "history", "trade", "purchase", "order", "log"}
+
+// FindFinanceTransactionsTables 返回 users 以外所有同时有股票代码列和股数列的表，名称像交易记录的表排在前面
+func FindFinanceTransactionsTables(db *sql.DB) ([]*FinanceTransactionsTable, error) {
+	tables, err := sqliteTables(db)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*FinanceTransactionsTable
+	for _, table := range tables {
+		if strings.EqualFold(table, "users") {
+			continue
+		}
+		columns, err := sqliteColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		t := &FinanceTransactionsTable{
+			Name:   table,
+			Symbol: findColumn(columns, "symbol", "stock"),
+			Shares: findColumn(columns, "shares", "share", "quantity", "amount"),
+			Price:  findColumn(columns, "price"),
+			Kind:   findColumn(columns, "type", "kind", "action", "transaction_type", "operation"),
+		}
+		if t.Symbol != "" && t.Shares != "" {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("finance.db has no table recording transactions (a table with symbol and shares columns)")
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return isFinanceTransactionsName(candidates[i].Name) && !isFinanceTransactionsName(candidates[j].Name)
+	})
+	return candidates, nil
+}
+
+// isFinanceTransactionsName 判断表名是否像交易记录
+func isFinanceTransactionsName(table string) bool {
+	table = strings.ToLower(table)
+	for _, name := range financeTransactionsNames {
+		if strings.Contains(table, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FinanceTransactionsMark 是请求之前一个候选交易表的最大 rowid
+type FinanceTransactionsMark struct {
+	Table    *FinanceTransactionsTable
+	MaxRowID int64
+}
+
+// MarkFinanceTransactions 记录每个候选交易表当前的最大 rowid
+func MarkFinanceTransactions(db *sql.DB) ([]FinanceTransactionsMark, error) {
+	tables, err := FindFinanceTransactionsTables(db)
+	if err != nil {
+		return nil, err
+	}
+	marks := make([]FinanceTransactionsMark, len(tables))
+	for i, table := range tables {
+		max, err := table.MaxRowID(db)
+		if err != nil {
+			return nil, err
+		}
+		marks[i] = FinanceTransactionsMark{Table: table, MaxRowID: max}
+	}
+	return marks, nil
+}
+
+// CheckNewTransactions 检查 marks 之后新写入的交易：任一候选表的新行符合 expected 即通过；
+// 都不符合时返回最像交易表的表 (这次请求新增了行的表优先) 的名称和问题
+func CheckNewTransactions(db *sql.DB, marks []FinanceTransactionsMark, expected []ScenarioTransaction, vars ScenarioVars) (string, error) {
+	best := -1
+	problems := make([]error, len(marks))
+	for i, mark := range marks {
+		transactions, err := mark.Table.Since(db, mark.MaxRowID)
+		if err != nil {
+			return mark.Table.Name, err
+		}
+		if problems[i] = CheckTransactions(expected, transactions, vars); problems[i] == nil {
+			return mark.Table.Name, nil
+		}
+		if best < 0 && len(transactions) > 0 {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	return marks[best].Table.Name, problems[best]
+}
+
+// findColumn 返回第一个名称等于 names 之一的列，其次是名称包含 names[0] 的列
+func findColumn(columns []string, names ...string) string {
+	for _, name := range names {
+		for _, column := range columns {
+			if strings.EqualFold(column, name) {
+				return column
+			}
+		}
+	}
+	for _, column := range columns {
+		if strings.Contains(strings.ToLower(column), names[0]) {
+			return column
+		}
+	}
+	return ""
+}
+
+// MaxRowID 返回表中最大的 rowid (空表为 0)，用来找出之后新写入的行
+func (t *FinanceTransactionsTable) MaxRowID(db *sql.DB) (int64, error) {
+	var max sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(rowid) FROM " + quoteSQLIdentifier(t.Name)).Scan(&max); err != nil {
+		return 0, fmt.Errorf("could not read %s: %v", t.Name, err)
+	}
+	return max.Int64, nil
+}
+
+// Since 返回 rowid 大于 rowID 的交易
+func (t *FinanceTransactionsTable) Since(db *sql.DB, rowID int64) ([]FinanceTransaction, error) {
+	price, kind := "NULL", "NULL"
+	if t.Price != "" {
+		price = quoteSQLIdentifier(t.Price)
+	}
+	if t.Kind != "" {
+		kind = quoteSQLIdentifier(t.Kind)
+	}
+	query := fmt.Sprintf("SELECT rowid, %s, %s, %s, %s FROM %s WHERE rowid > ? ORDER BY rowid",
+		quoteSQLIdentifier(t.Symbol), quoteSQLIdentifier(t.Shares), price, kind, quoteSQLIdentifier(t.Name))
+	rows, err := db.Query(query, rowID)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", t.Name, err)
+	}
+	defer rows.Close()
+
+	var transactions []FinanceTransaction
+	for rows.Next() {
+		var tx FinanceTransaction
+		var symbol, shares, price, kind sql.NullString
+		if err := rows.Scan(&tx.RowID, &symbol, &shares, &price, &kind); err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", t.Name, err)
+		}
+		tx.Symbol, tx.Kind = symbol.String, kind.String
+		if cents, ok := ParseMoney(shares.String); ok && cents%100 == 0 {
+			tx.Shares = cents / 100
+		}
+		tx.Cents, _ = ParseMoney(price.String)
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+// SnapshotDatabase 返回每个表内容的摘要，用来检查一个请求是否写入了数据库
+func SnapshotDatabase(db *sql.DB) (map[string]string, error) {
+	tables, err := sqliteTables(db)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]string)
+	for _, table := range tables {
+		result, err := ExecuteQuery(db, "SELECT * FROM "+quoteSQLIdentifier(table)+" ORDER BY rowid")
+		if err != nil {
+			// WITHOUT ROWID 表没有 rowid
+			result, err = ExecuteQuery(db, "SELECT * FROM "+quoteSQLIdentifier(table))
+		}
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		for _, row := range result.Rows {
+			for _, value := range row {
+				fmt.Fprintf(hash, "%s:%q,", value.Type, value.Text)
+			}
+			hash.Write([]byte("\n"))
+		}
+		snapshot[table] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return snapshot, nil
+}
+
+// ChangedTables 返回两次快照之间内容变化 (或新建、删除) 的表
+func ChangedTables(before, after map[string]string) []string {
+	var changed []string
+	for table, hash := range after {
+		if before[table] != hash {
+			changed = append(changed, table)
+		}
+	}
+	for table := range before {
+		if _, ok := after[table]; !ok {
+			changed = append(changed, table)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestFinanceDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "finance.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT, hash TEXT, cash NUMERIC DEFAULT 10000.00);
+		CREATE TABLE notes (id INTEGER PRIMARY KEY, text TEXT);
+		CREATE TABLE purchases (id INTEGER PRIMARY KEY, user_id INTEGER, stock TEXT, quantity INTEGER, unit_price REAL, type TEXT);
+		INSERT INTO users (username, hash) VALUES ('testuser', 'pbkdf2:sha256:600000$abc$0123');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestFindFinanceTransactionsTable(t *testing.T) {
+	db := openTestFinanceDB(t)
+
+	tables, err := FindFinanceTransactionsTables(db)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	table := tables[0]
+	assert.Equal(t, &FinanceTransactionsTable{Name: "purchases", Symbol: "stock", Shares: "quantity", Price: "unit_price", Kind: "type"}, table)
+
+	_, err = db.Exec("INSERT INTO purchases (user_id, stock, quantity, unit_price, type) VALUES (1, 'AAAA', 4, 28.5, 'buy')")
+	require.NoError(t, err)
+	max, err := table.MaxRowID(db)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO purchases (user_id, stock, quantity, unit_price, type) VALUES (1, 'AAAA', 2, 30.1, 'SELL')")
+	require.NoError(t, err)
+
+	transactions, err := table.Since(db, max)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, int64(-2), transactions[0].SignedShares())
+	assert.Equal(t, int64(3010), transactions[0].Cents)
+
+	vars := ScenarioVars{"price.AAAA": 3010}
+	assert.NoError(t, CheckTransactions([]ScenarioTransaction{{Symbol: "AAAA", Shares: -2, Price: "price.AAAA"}}, transactions, vars))
+	assert.EqualError(t, CheckTransactions([]ScenarioTransaction{{Symbol: "AAAA", Shares: 2}}, transactions, vars),
+		"transaction for AAAA should record 2 shares, found -2 (sales should be recorded with negative shares, or with a type column such as 'sell')")
+	vars["price.AAAA"] = 3000
+	assert.EqualError(t, CheckTransactions([]ScenarioTransaction{{Symbol: "AAAA", Shares: -2, Price: "price.AAAA"}}, transactions, vars),
+		"transaction for AAAA should record price 30.00, found 30.10")
+	assert.EqualError(t, CheckTransactions(nil, transactions, vars), "expected 0 new transaction row(s), found 1")
+
+	_, err = db.Exec("DROP TABLE purchases")
+	require.NoError(t, err)
+	_, err = FindFinanceTransactionsTables(db)
+	assert.ErrorContains(t, err, "no table recording transactions")
+}
+
+func TestSnapshotDatabase(t *testing.T) {
+	db := openTestFinanceDB(t)
+
+	before, err := SnapshotDatabase(db)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE users SET cash = 10000 WHERE id = 1")
+	require.NoError(t, err)
+	after, err := SnapshotDatabase(db)
+	require.NoError(t, err)
+	assert.Empty(t, ChangedTables(before, after))
+
+	_, err = db.Exec("UPDATE users SET cash = cash - 1; INSERT INTO notes (text) VALUES ('x'); CREATE TABLE log (x)")
+	require.NoError(t, err)
+	after, err = SnapshotDatabase(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log", "notes", "users"}, ChangedTables(before, after))
+}
+
+func TestScenarioDBCheckPattern(t *testing.T) {
+	db := openTestFinanceDB(t)
+	check := ScenarioDBCheck{
+		Query:   "SELECT hash FROM users WHERE username = 'testuser'",
+		Pattern: `^pbkdf2:sha256(:\d+)?\$[^$]+\$[0-9a-f]+$`,
+		Message: "passwords should be hashed",
+	}
+	assert.NoError(t, check.Check(db, nil))
+
+	_, err := db.Exec("UPDATE users SET hash = 'password123'")
+	require.NoError(t, err)
+	assert.ErrorContains(t, check.Check(db, nil), `passwords should be hashed (expected a value matching ^pbkdf2`)
+}
+
+func TestCheckNewTransactionsWithPortfolio(t *testing.T) {
+	db := openTestFinanceDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE portfolio (user_id INTEGER, symbol TEXT, shares INTEGER, PRIMARY KEY (user_id, symbol));
+		CREATE TABLE transactions (id INTEGER PRIMARY KEY, user_id INTEGER, symbol TEXT, shares INTEGER, price REAL);
+		INSERT INTO portfolio VALUES (1, 'AAAA', 4);
+	`)
+	require.NoError(t, err)
+
+	tables, err := FindFinanceTransactionsTables(db)
+	require.NoError(t, err)
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	assert.Equal(t, []string{"purchases", "transactions", "portfolio"}, names)
+
+	// 买入：持仓表原地更新，交易表新增一行
+	marks, err := MarkFinanceTransactions(db)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		UPDATE portfolio SET shares = shares + 2 WHERE symbol = 'AAAA';
+		INSERT INTO transactions (user_id, symbol, shares, price) VALUES (1, 'AAAA', 2, 28.5);
+	`)
+	require.NoError(t, err)
+	vars := ScenarioVars{"price.AAAA": 2850}
+	table, err := CheckNewTransactions(db, marks, []ScenarioTransaction{{Symbol: "AAAA", Shares: 2, Price: "price.AAAA"}}, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "transactions", table)
+
+	// 新股票同时插入两个表时，符合期望的是交易表
+	marks, err = MarkFinanceTransactions(db)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO portfolio VALUES (1, 'BBBB', 3);
+		INSERT INTO transactions (user_id, symbol, shares, price) VALUES (1, 'BBBB', -3, 10);
+	`)
+	require.NoError(t, err)
+	vars["price.BBBB"] = 1000
+	table, err = CheckNewTransactions(db, marks, []ScenarioTransaction{{Symbol: "BBBB", Shares: -3, Price: "price.BBBB"}}, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "transactions", table)
+
+	// 没有记录交易时报告最像交易表的表
+	marks, err = MarkFinanceTransactions(db)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE portfolio SET shares = shares - 1 WHERE symbol = 'AAAA'")
+	require.NoError(t, err)
+	table, err = CheckNewTransactions(db, marks, []ScenarioTransaction{{Symbol: "AAAA", Shares: -1}}, vars)
+	assert.EqualError(t, err, "expected 1 new transaction row(s), found 0")
+	assert.Equal(t, "purchases", table)
+}
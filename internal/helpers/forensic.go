@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"bytes"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// ForensicBlockSize 是 FAT 文件系统的块大小，recover 按块读取
+const ForensicBlockSize = 512
+
+// ForensicImageOptions 控制生成的存储卡镜像
+type ForensicImageOptions struct {
+	// JPEGCount 是镜像中 JPEG 的数量，可以为 0
+	JPEGCount int
+	// LeadingBlocks 是第一张 JPEG 之前的非 JPEG 块数 (模拟 FAT 区域)
+	LeadingBlocks int
+	// TrailingBlocks 是最后一张 JPEG 之后的空闲块数 (会被写入最后一个文件)
+	TrailingBlocks int
+	// MaxBlocksPerJPEG 是每张 JPEG 最多占用的块数
+	MaxBlocksPerJPEG int
+	// AlignedEnds 为 true 时每张 JPEG 都恰好在块边界结束；否则随机决定
+	AlignedEnds bool
+}
+
+// ForensicImage 是生成的镜像及 recover 应该恢复出的文件
+type ForensicImage struct {
+	Raw []byte
+	// Files[i] 是 i.jpg (如 000.jpg) 的期望内容：从签名块开始，直到下一个签名块或镜像结尾
+	Files [][]byte
+}
+
+// GenerateForensicImage 生成一张 FAT 风格的存储卡镜像
+// 每张 JPEG 以 0xff 0xd8 0xff 0xe0-0xef 开头并与块对齐，第四个字节依次覆盖全部 16 种取值；
+// 未填满最后一个块的 JPEG 以 0 填充 (slack space)
+func GenerateForensicImage(opts ForensicImageOptions) ForensicImage {
+	if opts.MaxBlocksPerJPEG < 1 {
+		opts.MaxBlocksPerJPEG = 1
+	}
+
+	var raw bytes.Buffer
+	for i := 0; i < opts.LeadingBlocks; i++ {
+		raw.Write(randomNonSignatureBlock())
+	}
+
+	starts := make([]int, 0, opts.JPEGCount)
+	for i := 0; i < opts.JPEGCount; i++ {
+		starts = append(starts, raw.Len())
+		raw.Write(randomJPEG(byte(0xe0+i%16), opts))
+	}
+
+	for i := 0; i < opts.TrailingBlocks; i++ {
+		raw.Write(make([]byte, ForensicBlockSize))
+	}
+
+	data := raw.Bytes()
+	files := make([][]byte, len(starts))
+	for i, start := range starts {
+		end := len(data)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		files[i] = data[start:end]
+	}
+	return ForensicImage{Raw: data, Files: files}
+}
+
+// randomJPEG 生成一张占用整数个块的 JPEG 载荷
+func randomJPEG(marker byte, opts ForensicImageOptions) []byte {
+	blocks := random.RandomInt(1, opts.MaxBlocksPerJPEG+1)
+	length := blocks * ForensicBlockSize
+	if !opts.AlignedEnds && random.RandomInt(0, 2) == 0 {
+		// 在最后一个块中间结束，剩余部分为 slack space
+		length -= random.RandomInt(1, ForensicBlockSize-8)
+	}
+
+	jpeg := make([]byte, blocks*ForensicBlockSize)
+	copy(jpeg, []byte{0xff, 0xd8, 0xff, marker})
+	for i := 4; i < length-2; i++ {
+		jpeg[i] = byte(random.RandomInt(0, 256))
+	}
+	// JPEG 以 EOI (0xff 0xd9) 结束
+	jpeg[length-2], jpeg[length-1] = 0xff, 0xd9
+
+	// 后续块不能恰好以签名开头，否则会被当成新的 JPEG
+	for offset := ForensicBlockSize; offset < len(jpeg); offset += ForensicBlockSize {
+		if IsJPEGSignature(jpeg[offset:]) {
+			jpeg[offset] = 0x00
+		}
+	}
+	return jpeg
+}
+
+// randomNonSignatureBlock 生成一个不以 JPEG 签名开头的随机块
+func randomNonSignatureBlock() []byte {
+	block := make([]byte, ForensicBlockSize)
+	for i := range block {
+		block[i] = byte(random.RandomInt(0, 256))
+	}
+	if IsJPEGSignature(block) {
+		block[0] = 0x00
+	}
+	return block
+}
+
+// IsJPEGSignature 判断数据是否以 JPEG 签名 (0xff 0xd8 0xff 0xe0-0xef) 开头
+func IsJPEGSignature(data []byte) bool {
+	return len(data) >= 4 &&
+		data[0] == 0xff && data[1] == 0xd8 && data[2] == 0xff && data[3]&0xf0 == 0xe0
+}
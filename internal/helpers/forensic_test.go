@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+)
+
+// recoverReference 按 recover.c 的方式逐块扫描镜像
+func recoverReference(raw []byte) [][]byte {
+	var files [][]byte
+	for offset := 0; offset+ForensicBlockSize <= len(raw); offset += ForensicBlockSize {
+		block := raw[offset : offset+ForensicBlockSize]
+		if IsJPEGSignature(block) {
+			files = append(files, nil)
+		}
+		if len(files) > 0 {
+			files[len(files)-1] = append(files[len(files)-1], block...)
+		}
+	}
+	return files
+}
+
+func TestGenerateForensicImage(t *testing.T) {
+	random.Init()
+
+	tests := []ForensicImageOptions{
+		{JPEGCount: 0, LeadingBlocks: 8},
+		{JPEGCount: 1, LeadingBlocks: 2, MaxBlocksPerJPEG: 4, AlignedEnds: true},
+		{JPEGCount: 16, LeadingBlocks: 3, MaxBlocksPerJPEG: 3},
+		{JPEGCount: 23, LeadingBlocks: 5, TrailingBlocks: 4, MaxBlocksPerJPEG: 6},
+	}
+
+	for _, opts := range tests {
+		image := GenerateForensicImage(opts)
+		assert.Equal(t, 0, len(image.Raw)%ForensicBlockSize, "opts=%+v", opts)
+		assert.Len(t, image.Files, opts.JPEGCount, "opts=%+v", opts)
+		assert.Equal(t, len(image.Files), len(recoverReference(image.Raw)), "opts=%+v", opts)
+
+		for i, file := range image.Files {
+			assert.True(t, IsJPEGSignature(file), "file %d", i)
+			assert.Equal(t, byte(0xe0+i%16), file[3], "file %d", i)
+			assert.Equal(t, 0, len(file)%ForensicBlockSize, "file %d", i)
+		}
+		if opts.JPEGCount > 0 {
+			assert.Equal(t, image.Files, recoverReference(image.Raw), "opts=%+v", opts)
+		}
+	}
+}
+
+func TestGenerateForensicImageAlignedEnds(t *testing.T) {
+	random.Init()
+
+	image := GenerateForensicImage(ForensicImageOptions{JPEGCount: 5, MaxBlocksPerJPEG: 3, AlignedEnds: true})
+	for i, file := range image.Files {
+		// 恰好在块边界结束的 JPEG，EOI 标记位于最后两个字节
+		assert.Equal(t, []byte{0xff, 0xd9}, file[len(file)-2:], "file %d", i)
+	}
+}
@@ -0,0 +1,303 @@
+package helpers
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// moneyRegex 匹配页面文字中的金额，例如 "$1,234.56"、"-12.5" 或 "112"
+var moneyRegex = regexp.MustCompile(`-?\$?-?\d[\d,]*(?:\.\d+)?`)
+
+// HTMLDocument 是解析后的 HTML 页面
+type HTMLDocument struct {
+	root *html.Node
+}
+
+// HTMLInput 是表单中的一个字段 (input、select 或 textarea)
+type HTMLInput struct {
+	Tag     string
+	Name    string
+	Type    string
+	Value   string
+	Options []string
+}
+
+// HTMLForm 是页面中的一个表单；Action 已按页面地址解析为路径，Method 为大写
+type HTMLForm struct {
+	Action string
+	Method string
+	Inputs []HTMLInput
+}
+
+// HTMLTable 是页面中的一个表格，单元格为去掉标签后的文字
+type HTMLTable struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// ParseHTML 解析 HTML 页面
+func ParseHTML(body string) (*HTMLDocument, error) {
+	root, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return &HTMLDocument{root: root}, nil
+}
+
+// Text 返回页面上可见的文字 (不含 script、style 和 template)，空白已合并
+func (d *HTMLDocument) Text() string {
+	return nodeText(d.root)
+}
+
+// ContainsMoney 检查页面可见文字中是否有等于 cents 的金额
+func (d *HTMLDocument) ContainsMoney(cents int64) bool {
+	return textContainsMoney(d.Text(), cents)
+}
+
+// Forms 返回页面中的所有表单，action 相对于 pagePath 解析
+func (d *HTMLDocument) Forms(pagePath string) []HTMLForm {
+	var forms []HTMLForm
+	for _, n := range findAll(d.root, atom.Form) {
+		form := HTMLForm{
+			Action: resolveFormAction(pagePath, attr(n, "action")),
+			Method: strings.ToUpper(attr(n, "method")),
+		}
+		if form.Method == "" {
+			form.Method = "GET"
+		}
+		for _, field := range findAll(n, atom.Input, atom.Select, atom.Textarea) {
+			input := HTMLInput{
+				Tag:   field.Data,
+				Name:  attr(field, "name"),
+				Type:  strings.ToLower(attr(field, "type")),
+				Value: attr(field, "value"),
+			}
+			if field.DataAtom == atom.Input && input.Type == "" {
+				input.Type = "text"
+			}
+			for _, option := range findAll(field, atom.Option) {
+				value, ok := attrOK(option, "value")
+				if !ok {
+					value = nodeText(option)
+				}
+				input.Options = append(input.Options, value)
+			}
+			form.Inputs = append(form.Inputs, input)
+		}
+		forms = append(forms, form)
+	}
+	return forms
+}
+
+// FindForm 返回提交到 action 且方法为 method 的表单
+func (d *HTMLDocument) FindForm(pagePath, action, method string) (*HTMLForm, bool) {
+	for _, form := range d.Forms(pagePath) {
+		if form.Action == action && strings.EqualFold(form.Method, method) {
+			return &form, true
+		}
+	}
+	return nil, false
+}
+
+// Input 返回名为 name 的字段
+func (f *HTMLForm) Input(name string) (*HTMLInput, bool) {
+	for i := range f.Inputs {
+		if f.Inputs[i].Name == name {
+			return &f.Inputs[i], true
+		}
+	}
+	return nil, false
+}
+
+// Tables 返回页面中的所有表格
+// 表头取自 thead，没有 thead 时取第一行全是 th 的行；其余行都是数据行 (含 tfoot)
+func (d *HTMLDocument) Tables() []HTMLTable {
+	var tables []HTMLTable
+	for _, n := range findAll(d.root, atom.Table) {
+		var table HTMLTable
+		for _, tr := range findAll(n, atom.Tr) {
+			var cells []string
+			allHeaders := true
+			for c := tr.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					cells = append(cells, nodeText(c))
+					allHeaders = allHeaders && c.DataAtom == atom.Th
+				}
+			}
+			inHead := tr.Parent != nil && tr.Parent.DataAtom == atom.Thead
+			if table.Headers == nil && len(table.Rows) == 0 && (inHead || allHeaders) && len(cells) > 0 {
+				table.Headers = cells
+				continue
+			}
+			table.Rows = append(table.Rows, cells)
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// FindTable 返回表头包含所有 headers 的第一个表格 (不区分大小写)
+func (d *HTMLDocument) FindTable(headers ...string) (*HTMLTable, bool) {
+	for _, table := range d.Tables() {
+		found := true
+		for _, header := range headers {
+			if table.Column(header) < 0 {
+				found = false
+				break
+			}
+		}
+		if found {
+			return &table, true
+		}
+	}
+	return nil, false
+}
+
+// Column 返回表头为 header 的列号 (不区分大小写，先精确匹配再包含匹配)，找不到时返回 -1
+func (t *HTMLTable) Column(header string) int {
+	for i, h := range t.Headers {
+		if strings.EqualFold(h, header) {
+			return i
+		}
+	}
+	for i, h := range t.Headers {
+		if strings.Contains(strings.ToLower(h), strings.ToLower(header)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Cell 返回第 row 行中表头为 header 的单元格
+func (t *HTMLTable) Cell(row int, header string) (string, bool) {
+	column := t.Column(header)
+	if row < 0 || row >= len(t.Rows) || column < 0 || column >= len(t.Rows[row]) {
+		return "", false
+	}
+	return t.Rows[row][column], true
+}
+
+// FindRows 返回表头为 header 的列等于 value 的所有行号 (不区分大小写)
+func (t *HTMLTable) FindRows(header, value string) []int {
+	var rows []int
+	for i := range t.Rows {
+		if cell, ok := t.Cell(i, header); ok && strings.EqualFold(cell, value) {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// RowContainsMoney 检查第 row 行是否有等于 cents 的金额
+func (t *HTMLTable) RowContainsMoney(row int, cents int64) bool {
+	if row < 0 || row >= len(t.Rows) {
+		return false
+	}
+	for _, cell := range t.Rows[row] {
+		if textContainsMoney(cell, cents) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMoney 把 "$1,234.56"、"1234.5" 或 "-$2.00" 这样的金额解析为美分
+func ParseMoney(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-") || strings.HasPrefix(s, "$-")
+	s = strings.NewReplacer("$", "", ",", "", "-", "", " ", "").Replace(s)
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || s == "" {
+		return 0, false
+	}
+	cents := int64(math.Round(value * 100))
+	if negative {
+		cents = -cents
+	}
+	return cents, true
+}
+
+// textContainsMoney 检查文字中是否有等于 cents 的金额
+func textContainsMoney(text string, cents int64) bool {
+	for _, match := range moneyRegex.FindAllString(text, -1) {
+		if value, ok := ParseMoney(match); ok && value == cents {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFormAction 把表单 action 解析为路径；空 action 提交到页面自身
+func resolveFormAction(pagePath, action string) string {
+	base, err := url.Parse(pagePath)
+	if err != nil {
+		return action
+	}
+	ref, err := url.Parse(strings.TrimSpace(action))
+	if err != nil {
+		return action
+	}
+	return base.ResolveReference(ref).Path
+}
+
+// findAll 按文档顺序返回 n 之下所有标签为 atoms 之一的元素
+func findAll(n *html.Node, atoms ...atom.Atom) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				for _, a := range atoms {
+					if c.DataAtom == a {
+						found = append(found, c)
+						break
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return found
+}
+
+// nodeText 返回 n 中可见的文字，空白已合并
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style || n.DataAtom == atom.Template) {
+			return
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// attr 返回元素的属性值
+func attr(n *html.Node, key string) string {
+	value, _ := attrOK(n, key)
+	return value
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
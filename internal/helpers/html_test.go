@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPortfolioHTML = `<!DOCTYPE html>
+<html>
+<head><script>var total = "112.00";</script></head>
+<body>
+  <form action="/sell" method="post">
+    <select name="symbol"><option disabled selected>Symbol</option><option value="AAAA">AAAA</option></select>
+    <input name="shares" type="number">
+    <button type="submit">Sell</button>
+  </form>
+  <form action="search"><input name="q"></form>
+  <table>
+    <thead><tr><th>Symbol</th><th>Shares</th><th>Price</th><th>TOTAL</th></tr></thead>
+    <tbody>
+      <tr><td>AAAA</td><td>4</td><td>$<span>28.00</span></td><td>$112.00</td></tr>
+      <tr><td>bbbb</td><td>1</td><td>$1,000.50</td><td>$1,000.50</td></tr>
+    </tbody>
+    <tfoot><tr><td colspan="3">Cash</td><td>$9,888.00</td></tr></tfoot>
+  </table>
+</body>
+</html>`
+
+func TestHTMLForms(t *testing.T) {
+	page, err := ParseHTML(testPortfolioHTML)
+	require.NoError(t, err)
+
+	forms := page.Forms("/portfolio/")
+	require.Len(t, forms, 2)
+	assert.Equal(t, "/portfolio/search", forms[1].Action)
+	assert.Equal(t, "GET", forms[1].Method)
+
+	form, ok := page.FindForm("/", "/sell", "post")
+	require.True(t, ok)
+	symbol, ok := form.Input("symbol")
+	require.True(t, ok)
+	assert.Equal(t, "select", symbol.Tag)
+	assert.Equal(t, []string{"Symbol", "AAAA"}, symbol.Options)
+	shares, _ := form.Input("shares")
+	assert.Equal(t, "number", shares.Type)
+	_, ok = form.Input("confirmation")
+	assert.False(t, ok)
+
+	_, ok = page.FindForm("/", "/buy", "POST")
+	assert.False(t, ok)
+}
+
+func TestHTMLTables(t *testing.T) {
+	page, err := ParseHTML(testPortfolioHTML)
+	require.NoError(t, err)
+
+	table, ok := page.FindTable("symbol", "shares")
+	require.True(t, ok)
+	assert.Equal(t, []string{"Symbol", "Shares", "Price", "TOTAL"}, table.Headers)
+	require.Len(t, table.Rows, 3)
+
+	rows := table.FindRows("symbol", "BBBB")
+	assert.Equal(t, []int{1}, rows)
+	price, ok := table.Cell(0, "price")
+	assert.True(t, ok)
+	assert.Equal(t, "$ 28.00", price)
+	assert.True(t, table.RowContainsMoney(0, 11200))
+	assert.False(t, table.RowContainsMoney(1, 11200))
+
+	_, ok = page.FindTable("transacted")
+	assert.False(t, ok)
+}
+
+func TestHTMLMoney(t *testing.T) {
+	page, err := ParseHTML(testPortfolioHTML)
+	require.NoError(t, err)
+	assert.True(t, page.ContainsMoney(988800))
+	assert.True(t, page.ContainsMoney(100050))
+	assert.NotContains(t, page.Text(), "var total")
+
+	scriptOnly, err := ParseHTML(`<p>Total</p><script>var total = "112.00";</script>`)
+	require.NoError(t, err)
+	assert.False(t, scriptOnly.ContainsMoney(11200))
+
+	for input, expected := range map[string]int64{"$1,234.56": 123456, "112": 11200, "-$2.50": -250, "0.005": 1} {
+		cents, ok := ParseMoney(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, cents, input)
+	}
+	_, ok := ParseMoney("AAAA")
+	assert.False(t, ok)
+}
@@ -0,0 +1,589 @@
+package helpers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTPScenario 是一个 Web 应用的测试场景 (YAML)，由按顺序执行的步骤组成，
+// 例如 stages/scenarios/finance.yaml
+type HTTPScenario struct {
+	Name string `yaml:"name"`
+	// Quotes 是模拟报价服务提供的股票，初始价格随机
+	Quotes []ScenarioQuote `yaml:"quotes"`
+	// Vars 是场景开始时计算的变量，形如 "cash = 10000.00"
+	Vars  []string       `yaml:"vars"`
+	Steps []ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioQuote 是场景中的一支股票
+type ScenarioQuote struct {
+	Symbol string `yaml:"symbol"`
+	Name   string `yaml:"name"`
+}
+
+// ScenarioStep 是场景中的一步：发送一个请求并检查响应
+type ScenarioStep struct {
+	// Test 和 Pass 是这一步开始和通过时输出的日志
+	Test string `yaml:"test"`
+	Pass string `yaml:"pass"`
+	// Session 选择发送请求的会话 (各自独立的 cookie)，默认为 "default"
+	Session string `yaml:"session"`
+	// Clone 不为空时，Session 重新创建为该会话 cookie 的副本 (模拟被复制的 cookie)
+	Clone string `yaml:"clone"`
+	// TamperCookies 在请求前篡改 Session 的 cookie 值
+	TamperCookies bool `yaml:"tamper_cookies"`
+	// Hint 在这一步失败时附加在错误信息后
+	Hint string `yaml:"hint"`
+	// Quotes 中的股票在请求前换成新的随机价格
+	Quotes []string `yaml:"quotes"`
+	// Let 在请求前计算变量，形如 "cash = cash - 4 * price.AAAA"
+	Let     []string        `yaml:"let"`
+	Request ScenarioRequest `yaml:"request"`
+	// Cases 不为空时，请求对每个 case 各发送一次，case 中的字段覆盖 Request.Form
+	Cases  []map[string]string `yaml:"cases"`
+	Expect ScenarioExpect      `yaml:"expect"`
+}
+
+// ScenarioRequest 是一步中发送的请求；没有 Method 时为 GET
+// 设置 Paths 时，同样的请求依次发送到每个路径
+type ScenarioRequest struct {
+	Method string            `yaml:"method"`
+	Path   string            `yaml:"path"`
+	Paths  []string          `yaml:"paths"`
+	Form   map[string]string `yaml:"form"`
+}
+
+// ScenarioCall 是一步中实际发送的一个请求
+type ScenarioCall struct {
+	Path string
+	Form url.Values
+}
+
+// ScenarioExpect 是对响应的期望，未设置的项不检查
+type ScenarioExpect struct {
+	// Status 可以是一个状态码或状态码列表
+	Status ScenarioStatus `yaml:"status"`
+	// Redirect 要求 3xx 状态码并重定向到该路径
+	Redirect string        `yaml:"redirect"`
+	Form     *ScenarioForm `yaml:"form"`
+	// Text 中的每段文字都应出现在页面可见文字中
+	Text []string `yaml:"text"`
+	// Money 中每个表达式的金额都应出现在页面上
+	Money []string       `yaml:"money"`
+	Table *ScenarioTable `yaml:"table"`
+	// Lookups 中的股票在这一步中应向报价服务查询过
+	Lookups []string `yaml:"lookups"`
+	// DB 是请求之后对应用数据库的检查
+	DB []ScenarioDBCheck `yaml:"db"`
+	// NoWrites 要求请求没有修改数据库中的任何表 (用于应被拒绝的请求)
+	NoWrites bool `yaml:"no_writes"`
+	// Transactions 是请求在交易表中新写入的行 (见 CheckNewTransactions)
+	Transactions []ScenarioTransaction `yaml:"transactions"`
+}
+
+// ScenarioTransaction 是期望写入的一笔交易；卖出时 Shares 为负
+type ScenarioTransaction struct {
+	Symbol string `yaml:"symbol"`
+	Shares int64  `yaml:"shares"`
+	// Price 是成交价的表达式
+	Price string `yaml:"price"`
+}
+
+// ScenarioStatus 是允许的状态码列表
+type ScenarioStatus []int
+
+// UnmarshalYAML 同时接受 "status: 200" 和 "status: [200, 302]"
+func (s *ScenarioStatus) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var status int
+		if err := node.Decode(&status); err != nil {
+			return err
+		}
+		*s = ScenarioStatus{status}
+		return nil
+	}
+	var statuses []int
+	if err := node.Decode(&statuses); err != nil {
+		return err
+	}
+	*s = statuses
+	return nil
+}
+
+// ScenarioForm 期望页面上有提交到 Action 的表单，并包含 Fields 中的字段
+type ScenarioForm struct {
+	Action string   `yaml:"action"`
+	Method string   `yaml:"method"`
+	Fields []string `yaml:"fields"`
+}
+
+// ScenarioTable 期望页面上有包含 Headers 各列的表格，并满足 Rows 中的期望
+type ScenarioTable struct {
+	Headers []string      `yaml:"headers"`
+	Rows    []ScenarioRow `yaml:"rows"`
+}
+
+// ScenarioRow 是对表格中匹配 Match 的行的期望
+type ScenarioRow struct {
+	// Match 是列名到单元格值的映射 (不区分大小写)
+	Match map[string]string `yaml:"match"`
+	// Count 是匹配的行数，默认为 1；Cells 和 Money 只在 Count 为 1 时检查
+	Count *int              `yaml:"count"`
+	Cells map[string]string `yaml:"cells"`
+	Money []string          `yaml:"money"`
+}
+
+// ScenarioDBCheck 在应用数据库上执行 Query，检查返回的单个值
+type ScenarioDBCheck struct {
+	Query string `yaml:"query"`
+	// Value 是期望的值 (按文字比较)
+	Value *string `yaml:"value"`
+	// Money 是期望金额的表达式
+	Money string `yaml:"money"`
+	// Pattern 是值应匹配的正则表达式
+	Pattern string `yaml:"pattern"`
+	// Message 是检查失败时的错误信息
+	Message string `yaml:"message"`
+}
+
+// ScenarioResponse 是一步中收到的响应
+type ScenarioResponse struct {
+	Method   string
+	Path     string
+	Status   int
+	Location string
+	Body     string
+}
+
+// ParseHTTPScenario 解析 YAML 场景
+func ParseHTTPScenario(data []byte) (*HTTPScenario, error) {
+	var scenario HTTPScenario
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %v", err)
+	}
+	for i, step := range scenario.Steps {
+		if (step.Request.Path == "") == (len(step.Request.Paths) == 0) {
+			return nil, fmt.Errorf("invalid scenario: step %d (%s) needs either a request path or paths", i+1, step.Test)
+		}
+		if len(scenario.Quotes) == 0 && (len(step.Quotes) > 0 || len(step.Expect.Lookups) > 0) {
+			return nil, fmt.Errorf("invalid scenario: step %d (%s) uses quotes, but the scenario has none", i+1, step.Test)
+		}
+	}
+	return &scenario, nil
+}
+
+// Requests 返回这一步要发送的请求 (每个路径的每个 case 一个)
+func (s *ScenarioStep) Requests() []ScenarioCall {
+	paths := s.Request.Paths
+	if s.Request.Path != "" {
+		paths = []string{s.Request.Path}
+	}
+	cases := s.Cases
+	if len(cases) == 0 {
+		cases = []map[string]string{nil}
+	}
+	var calls []ScenarioCall
+	for _, path := range paths {
+		for _, c := range cases {
+			calls = append(calls, ScenarioCall{Path: path, Form: scenarioForm(s.Request.Form, c)})
+		}
+	}
+	return calls
+}
+
+// Method 返回请求方法 (大写)
+func (s *ScenarioStep) Method() string {
+	if s.Request.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(s.Request.Method)
+}
+
+// Description 返回请求的方法和路径，用于错误信息
+func (s *ScenarioStep) Description() string {
+	if s.Request.Path != "" {
+		return s.Method() + " " + s.Request.Path
+	}
+	return s.Method() + " " + strings.Join(s.Request.Paths, ", ")
+}
+
+func scenarioForm(base, override map[string]string) url.Values {
+	form := url.Values{}
+	for k, v := range base {
+		form.Set(k, v)
+	}
+	for k, v := range override {
+		form.Set(k, v)
+	}
+	return form
+}
+
+// Check 检查响应是否满足页面相关的期望 (Lookups 和 DB 由执行场景的一方检查)
+func (e *ScenarioExpect) Check(resp *ScenarioResponse, vars ScenarioVars) error {
+	request := resp.Method + " " + resp.Path
+	if len(e.Status) > 0 && !containsInt(e.Status, resp.Status) {
+		return fmt.Errorf("%s should return %s, got %d", request, formatStatuses(e.Status), resp.Status)
+	}
+	if e.Redirect != "" {
+		if resp.Status < 300 || resp.Status >= 400 {
+			return fmt.Errorf("%s should redirect to %s, got %d", request, e.Redirect, resp.Status)
+		}
+		if location := resolveFormAction(resp.Path, resp.Location); location != e.Redirect {
+			return fmt.Errorf("%s should redirect to %s, redirected to %s", request, e.Redirect, location)
+		}
+	}
+	if e.Form == nil && len(e.Text) == 0 && len(e.Money) == 0 && e.Table == nil {
+		return nil
+	}
+
+	page, err := ParseHTML(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", resp.Path, err)
+	}
+	if e.Form != nil {
+		if err := e.Form.check(page, resp.Path); err != nil {
+			return err
+		}
+	}
+	text := page.Text()
+	for _, t := range e.Text {
+		if !strings.Contains(strings.ToLower(text), strings.ToLower(t)) {
+			return fmt.Errorf("%s should show %q", resp.Path, t)
+		}
+	}
+	for _, expr := range e.Money {
+		cents, err := vars.Eval(expr)
+		if err != nil {
+			return err
+		}
+		if !page.ContainsMoney(cents) {
+			return fmt.Errorf("%s should show %s (%s)", resp.Path, FormatCents(cents), expr)
+		}
+	}
+	if e.Table != nil {
+		return e.Table.check(page, resp.Path, vars)
+	}
+	return nil
+}
+
+func (f *ScenarioForm) check(page *HTMLDocument, path string) error {
+	method := f.Method
+	if method == "" {
+		method = "POST"
+	}
+	form, ok := page.FindForm(path, f.Action, method)
+	if !ok {
+		return fmt.Errorf("%s has no form that submits to %s with %s", path, f.Action, strings.ToUpper(method))
+	}
+	for _, field := range f.Fields {
+		if _, ok := form.Input(field); !ok {
+			return fmt.Errorf("%s is missing %s field", path, field)
+		}
+	}
+	return nil
+}
+
+func (t *ScenarioTable) check(page *HTMLDocument, path string, vars ScenarioVars) error {
+	table, ok := page.FindTable(t.Headers...)
+	if !ok {
+		return fmt.Errorf("%s should have a table with columns %s", path, strings.Join(t.Headers, ", "))
+	}
+	for _, row := range t.Rows {
+		matched := table.matchRows(row.Match)
+		count := 1
+		if row.Count != nil {
+			count = *row.Count
+		}
+		if len(matched) != count {
+			return fmt.Errorf("%s should show %d row(s) with %s, found %d", path, count, formatMatch(row.Match), len(matched))
+		}
+		if count != 1 {
+			continue
+		}
+		for _, header := range sortedKeys(row.Cells) {
+			if cell, _ := table.Cell(matched[0], header); !strings.EqualFold(cell, row.Cells[header]) {
+				return fmt.Errorf("%s should show %s %s for %s, found %q", path, header, row.Cells[header], formatMatch(row.Match), cell)
+			}
+		}
+		for _, expr := range row.Money {
+			cents, err := vars.Eval(expr)
+			if err != nil {
+				return err
+			}
+			if !table.RowContainsMoney(matched[0], cents) {
+				return fmt.Errorf("%s should show %s (%s) for %s", path, FormatCents(cents), expr, formatMatch(row.Match))
+			}
+		}
+	}
+	return nil
+}
+
+// matchRows 返回所有列都与 match 相等的行号
+func (t *HTMLTable) matchRows(match map[string]string) []int {
+	var rows []int
+	for i := range t.Rows {
+		ok := true
+		for header, value := range match {
+			if cell, found := t.Cell(i, header); !found || !strings.EqualFold(cell, value) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// Check 在 db 上执行检查
+func (c *ScenarioDBCheck) Check(db *sql.DB, vars ScenarioVars) error {
+	result, err := ExecuteQuery(db, c.Query)
+	if err != nil {
+		return fmt.Errorf("database check %q failed: %v", c.Query, err)
+	}
+	actual := "no rows"
+	var value SQLValue
+	if len(result.Rows) > 0 && len(result.Rows[0]) > 0 {
+		value = result.Rows[0][0]
+		actual = value.String()
+	}
+	fail := func(expected string) error {
+		if c.Message != "" {
+			return fmt.Errorf("%s (expected %s, found %s)", c.Message, expected, actual)
+		}
+		return fmt.Errorf("database check %q expected %s, found %s", c.Query, expected, actual)
+	}
+
+	if c.Value != nil && (len(result.Rows) == 0 || value.Text != *c.Value) {
+		return fail(strconv.Quote(*c.Value))
+	}
+	if c.Pattern != "" {
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", c.Pattern, err)
+		}
+		if len(result.Rows) == 0 || !pattern.MatchString(value.Text) {
+			return fail("a value matching " + c.Pattern)
+		}
+	}
+	if c.Money != "" {
+		cents, err := vars.Eval(c.Money)
+		if err != nil {
+			return err
+		}
+		if actualCents, ok := ParseMoney(value.Text); len(result.Rows) == 0 || !ok || actualCents != cents {
+			return fail(FormatCents(cents))
+		}
+	}
+	return nil
+}
+
+// CheckTransactions 检查新写入的交易与期望一致 (数量、股票代码、带符号的股数和价格)
+func CheckTransactions(expected []ScenarioTransaction, actual []FinanceTransaction, vars ScenarioVars) error {
+	if len(actual) != len(expected) {
+		return fmt.Errorf("expected %d new transaction row(s), found %d", len(expected), len(actual))
+	}
+	for i, e := range expected {
+		tx := actual[i]
+		if !strings.EqualFold(tx.Symbol, e.Symbol) {
+			return fmt.Errorf("transaction should record symbol %s, found %q", e.Symbol, tx.Symbol)
+		}
+		if tx.SignedShares() != e.Shares {
+			hint := ""
+			if tx.SignedShares() == -e.Shares {
+				hint = " (sales should be recorded with negative shares, or with a type column such as 'sell')"
+			}
+			return fmt.Errorf("transaction for %s should record %d shares, found %d%s", e.Symbol, e.Shares, tx.SignedShares(), hint)
+		}
+		if e.Price != "" {
+			cents, err := vars.Eval(e.Price)
+			if err != nil {
+				return err
+			}
+			if tx.Cents != cents {
+				return fmt.Errorf("transaction for %s should record price %s, found %s", e.Symbol, FormatCents(cents), FormatCents(tx.Cents))
+			}
+		}
+	}
+	return nil
+}
+
+// ScenarioVars 是场景中的变量，金额以美分保存
+// 表达式支持 + - * / 和括号；带小数点的数是美元 ("10000.00" 即 1000000 美分)，
+// 整数是倍数 ("4 * price.AAAA")
+type ScenarioVars map[string]int64
+
+// Assign 执行 "name = expr"
+func (v ScenarioVars) Assign(statement string) error {
+	name, expr, ok := strings.Cut(statement, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("invalid assignment %q", statement)
+	}
+	value, err := v.Eval(expr)
+	if err != nil {
+		return err
+	}
+	v[name] = value
+	return nil
+}
+
+// Eval 计算表达式
+func (v ScenarioVars) Eval(expr string) (int64, error) {
+	p := &scenarioExprParser{src: expr, vars: v}
+	value, err := p.sum()
+	if err == nil && p.peek() != 0 {
+		err = fmt.Errorf("unexpected %q", string(p.peek()))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid expression %q: %v", expr, err)
+	}
+	return value, nil
+}
+
+type scenarioExprParser struct {
+	src  string
+	pos  int
+	vars ScenarioVars
+}
+
+func (p *scenarioExprParser) peek() rune {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return rune(p.src[p.pos])
+}
+
+func (p *scenarioExprParser) sum() (int64, error) {
+	value, err := p.product()
+	for err == nil && (p.peek() == '+' || p.peek() == '-') {
+		op := p.peek()
+		p.pos++
+		var rhs int64
+		if rhs, err = p.product(); op == '+' {
+			value += rhs
+		} else {
+			value -= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *scenarioExprParser) product() (int64, error) {
+	value, err := p.operand()
+	for err == nil && (p.peek() == '*' || p.peek() == '/') {
+		op := p.peek()
+		p.pos++
+		var rhs int64
+		if rhs, err = p.operand(); err != nil {
+			break
+		}
+		if op == '*' {
+			value *= rhs
+		} else if rhs == 0 {
+			err = fmt.Errorf("division by zero")
+		} else {
+			value /= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *scenarioExprParser) operand() (int64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		value, err := p.sum()
+		if err == nil && p.peek() != ')' {
+			err = fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, err
+	case c == '-':
+		p.pos++
+		value, err := p.operand()
+		return -value, err
+	case unicode.IsDigit(c):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		literal := p.src[start:p.pos]
+		if strings.Contains(literal, ".") {
+			cents, ok := ParseMoney(literal)
+			if !ok {
+				return 0, fmt.Errorf("invalid number %q", literal)
+			}
+			return cents, nil
+		}
+		return strconv.ParseInt(literal, 10, 64)
+	case unicode.IsLetter(c) || c == '_':
+		start := p.pos
+		for p.pos < len(p.src) {
+			r := rune(p.src[p.pos])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+				break
+			}
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		value, ok := p.vars[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown variable %s", name)
+		}
+		return value, nil
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end")
+	}
+	return 0, fmt.Errorf("unexpected %q", string(c))
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatStatuses(statuses []int) string {
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = strconv.Itoa(status)
+	}
+	return strings.Join(parts, " or ")
+}
+
+func formatMatch(match map[string]string) string {
+	var parts []string
+	for _, header := range sortedKeys(match) {
+		parts = append(parts, fmt.Sprintf("%s %s", header, match[header]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScenarioYAML = `
+name: test
+quotes:
+  - {symbol: AAAA, name: Test A}
+vars:
+  - cash = 10000.00
+steps:
+  - test: buy
+    pass: buy succeeds
+    quotes: [AAAA]
+    request:
+      method: post
+      path: /buy
+      form: {symbol: AAAA}
+    cases:
+      - {shares: "-1"}
+      - {shares: foo}
+    expect:
+      status: 400
+  - test: protected routes
+    session: anonymous
+    request: {paths: [/, /history]}
+    expect: {redirect: /login}
+  - test: portfolio
+    request: {path: /}
+    expect:
+      status: [200, 302]
+      lookups: [AAAA]
+`
+
+func TestParseHTTPScenario(t *testing.T) {
+	scenario, err := ParseHTTPScenario([]byte(testScenarioYAML))
+	require.NoError(t, err)
+	require.Len(t, scenario.Steps, 3)
+
+	buy := scenario.Steps[0]
+	assert.Equal(t, "POST", buy.Method())
+	assert.Equal(t, ScenarioStatus{400}, buy.Expect.Status)
+	requests := buy.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/buy", requests[0].Path)
+	assert.Equal(t, "shares=-1&symbol=AAAA", requests[0].Form.Encode())
+	assert.Equal(t, "shares=foo&symbol=AAAA", requests[1].Form.Encode())
+	assert.Equal(t, "POST /buy", buy.Description())
+
+	protected := scenario.Steps[1]
+	calls := protected.Requests()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "/history", calls[1].Path)
+	assert.Equal(t, "GET /, /history", protected.Description())
+
+	portfolio := scenario.Steps[2]
+	assert.Equal(t, "GET", portfolio.Method())
+	assert.Equal(t, ScenarioStatus{200, 302}, portfolio.Expect.Status)
+	assert.Len(t, portfolio.Requests(), 1)
+
+	_, err = ParseHTTPScenario([]byte("steps:\n  - test: x\n    request: {path: /}\n    expect: {stauts: 200}\n"))
+	assert.ErrorContains(t, err, "field stauts not found")
+	_, err = ParseHTTPScenario([]byte("steps:\n  - test: x\n    request: {method: GET}\n"))
+	assert.ErrorContains(t, err, "needs either a request path or paths")
+	_, err = ParseHTTPScenario([]byte("steps:\n  - test: x\n    quotes: [AAAA]\n    request: {path: /}\n"))
+	assert.ErrorContains(t, err, "uses quotes")
+}
+
+func TestScenarioVars(t *testing.T) {
+	vars := ScenarioVars{"price.AAAA": 2850}
+	require.NoError(t, vars.Assign("cash = 10000.00"))
+	require.NoError(t, vars.Assign("cash = cash - 4 * price.AAAA"))
+	assert.Equal(t, int64(988600), vars["cash"])
+
+	value, err := vars.Eval("(cash + 2 * price.AAAA) / 2 - -1")
+	require.NoError(t, err)
+	assert.Equal(t, int64((988600+5700)/2+1), value)
+
+	_, err = vars.Eval("price.BBBB")
+	assert.ErrorContains(t, err, "unknown variable price.BBBB")
+	_, err = vars.Eval("4 *")
+	assert.ErrorContains(t, err, "unexpected end")
+	_, err = vars.Eval("(4")
+	assert.ErrorContains(t, err, "missing )")
+	assert.Error(t, vars.Assign("= 4"))
+}
+
+func TestScenarioExpectCheck(t *testing.T) {
+	vars := ScenarioVars{"price.AAAA": 2800, "cash": 988800}
+	one := 1
+	expect := ScenarioExpect{
+		Status: ScenarioStatus{200},
+		Form:   &ScenarioForm{Action: "/sell", Fields: []string{"symbol", "shares"}},
+		Text:   []string{"cash"},
+		Money:  []string{"cash"},
+		Table: &ScenarioTable{
+			Headers: []string{"symbol", "shares"},
+			Rows: []ScenarioRow{{
+				Match: map[string]string{"symbol": "AAAA"},
+				Count: &one,
+				Cells: map[string]string{"shares": "4"},
+				Money: []string{"4 * price.AAAA"},
+			}},
+		},
+	}
+	resp := &ScenarioResponse{Method: "GET", Path: "/", Status: 200, Body: testPortfolioHTML}
+	assert.NoError(t, expect.Check(resp, vars))
+
+	vars["price.AAAA"] = 2900
+	assert.EqualError(t, expect.Check(resp, vars), "/ should show 116.00 (4 * price.AAAA) for symbol AAAA")
+
+	expect.Table.Rows[0].Cells["shares"] = "5"
+	assert.EqualError(t, expect.Check(resp, vars), "/ should show shares 5 for symbol AAAA, found \"4\"")
+
+	resp.Status = 500
+	assert.EqualError(t, expect.Check(resp, vars), "GET / should return 200, got 500")
+
+	redirect := ScenarioExpect{Redirect: "/login"}
+	assert.NoError(t, redirect.Check(&ScenarioResponse{Method: "GET", Path: "/", Status: 302, Location: "http://127.0.0.1:5000/login"}, vars))
+	assert.EqualError(t, redirect.Check(&ScenarioResponse{Method: "GET", Path: "/", Status: 200}, vars), "GET / should redirect to /login, got 200")
+}
+
+func TestScenarioDBCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finance.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE users (username TEXT, cash NUMERIC); INSERT INTO users VALUES ('testuser', 9887.999999999)")
+	require.NoError(t, err)
+
+	vars := ScenarioVars{"cash": 988800}
+	check := ScenarioDBCheck{Query: "SELECT cash FROM users WHERE username = 'testuser'", Money: "cash"}
+	assert.NoError(t, check.Check(db, vars))
+
+	vars["cash"] = 988700
+	check.Message = "buying should deduct cash"
+	assert.EqualError(t, check.Check(db, vars), "buying should deduct cash (expected 9,887.00, found 9887.999999999)")
+
+	value := "testuser"
+	assert.NoError(t, (&ScenarioDBCheck{Query: "SELECT username FROM users", Value: &value}).Check(db, vars))
+	assert.ErrorContains(t, (&ScenarioDBCheck{Query: "SELECT username FROM users WHERE 0", Value: &value}).Check(db, vars), "found no rows")
+}
@@ -0,0 +1,101 @@
+package helpers
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogBuffer 保存进程输出的最后 maxLines 行，可以在进程写入的同时读取
+// 行从 0 开始编号，Mark 记下当前位置，之后用 Since 取出这之后的输出
+type LogBuffer struct {
+	mu       sync.Mutex
+	maxLines int
+	lines    []string
+	// dropped 是已经被挤出缓冲区的行数
+	dropped int
+	partial string
+}
+
+// NewLogBuffer 创建最多保存 maxLines 行的 LogBuffer
+func NewLogBuffer(maxLines int) *LogBuffer {
+	return &LogBuffer{maxLines: maxLines}
+}
+
+// Write 实现 io.Writer，按行保存输出
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	text := b.partial + string(p)
+	parts := strings.Split(text, "\n")
+	b.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		b.lines = append(b.lines, strings.TrimRight(line, "\r"))
+	}
+	if extra := len(b.lines) - b.maxLines; extra > 0 {
+		b.lines = append([]string(nil), b.lines[extra:]...)
+		b.dropped += extra
+	}
+	return len(p), nil
+}
+
+// Mark 返回下一行的编号
+func (b *LogBuffer) Mark() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped + len(b.lines)
+}
+
+// Since 返回编号不小于 mark 且仍在缓冲区中的行，包括还没有换行的最后一行
+func (b *LogBuffer) Since(mark int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := mark - b.dropped
+	if start < 0 {
+		start = 0
+	}
+	var lines []string
+	if start < len(b.lines) {
+		lines = append(lines, b.lines[start:]...)
+	}
+	if b.partial != "" {
+		lines = append(lines, b.partial)
+	}
+	return lines
+}
+
+// Lines 返回缓冲区中的所有行
+func (b *LogBuffer) Lines() []string {
+	return b.Since(0)
+}
+
+// LastPythonTraceback 返回 lines 中最后一个 Python traceback (从 "Traceback" 行到异常行)，没有时返回空字符串
+func LastPythonTraceback(lines []string) string {
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "Traceback (most recent call last):") {
+			start = i
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	end := start + 1
+	for end < len(lines) {
+		line := lines[end]
+		end++
+		// 栈帧和源码行都有缩进，第一个没有缩进的行是异常本身
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			break
+		}
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// TracebackException 返回 traceback 的最后一行，例如 "sqlite3.OperationalError: near "1": syntax error"
+func TracebackException(traceback string) string {
+	lines := strings.Split(strings.TrimSpace(traceback), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
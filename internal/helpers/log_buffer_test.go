@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFlaskLog = ` * Running on http://127.0.0.1:5000
+127.0.0.1 - - [19/Oct/2026 10:00:00] "GET / HTTP/1.1" 302 -
+[2026-10-19 10:00:01,000] ERROR in app: Exception on /login [POST]
+Traceback (most recent call last):
+  File "/usr/lib/python3/site-packages/flask/app.py", line 1473, in wsgi_app
+    response = self.full_dispatch_request()
+  File "/tmp/finance/app.py", line 120, in login
+    rows = db.execute(f"SELECT * FROM users WHERE username = '{username}'")
+sqlite3.OperationalError: near "1": syntax error
+127.0.0.1 - - [19/Oct/2026 10:00:01] "POST /login HTTP/1.1" 500 -
+`
+
+func TestLogBuffer(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	fmt.Fprint(buffer, "one\r\ntwo\nthr")
+	assert.Equal(t, []string{"one", "two", "thr"}, buffer.Lines())
+	mark := buffer.Mark()
+	assert.Equal(t, 2, mark)
+
+	fmt.Fprint(buffer, "ee\nfour\nfive\n")
+	assert.Equal(t, []string{"three", "four", "five"}, buffer.Lines())
+	assert.Equal(t, []string{"three", "four", "five"}, buffer.Since(mark))
+	assert.Equal(t, []string{"five"}, buffer.Since(4))
+	assert.Empty(t, buffer.Since(5))
+
+	// 被挤出缓冲区的行不再返回
+	fmt.Fprint(buffer, "six\n")
+	assert.Equal(t, []string{"four", "five", "six"}, buffer.Since(mark))
+}
+
+func TestLastPythonTraceback(t *testing.T) {
+	buffer := NewLogBuffer(100)
+	fmt.Fprint(buffer, testFlaskLog)
+
+	traceback := LastPythonTraceback(buffer.Lines())
+	assert.Contains(t, traceback, `File "/tmp/finance/app.py", line 120, in login`)
+	assert.NotContains(t, traceback, "ERROR in app")
+	assert.NotContains(t, traceback, "POST /login HTTP/1.1")
+	assert.Equal(t, `sqlite3.OperationalError: near "1": syntax error`, TracebackException(traceback))
+
+	assert.Empty(t, LastPythonTraceback(buffer.Since(9)))
+	assert.Empty(t, LastPythonTraceback([]string{"127.0.0.1 - - \"GET / HTTP/1.1\" 200 -"}))
+}
@@ -0,0 +1,233 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PythonEnvVar 指定使用的 Python 解释器 (提交中没有 .venv 时)
+const PythonEnvVar = "BCS_PYTHON"
+
+// pythonProbeTimeout 是查询解释器版本和模块的超时时间
+const pythonProbeTimeout = 30 * time.Second
+
+// MinPythonVersion 是支持的最低 Python 版本
+var MinPythonVersion = PythonVersion{Major: 3, Minor: 8}
+
+// PythonVersion 是 Python 的版本号
+type PythonVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v PythonVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast 检查版本是否不低于 min
+func (v PythonVersion) AtLeast(min PythonVersion) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+// ParsePythonVersion 解析 "3.12.1" 或 python --version 的输出 "Python 3.12.1"
+func ParsePythonVersion(s string) (PythonVersion, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "Python"))
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return PythonVersion{}, fmt.Errorf("invalid Python version %q", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return PythonVersion{}, fmt.Errorf("invalid Python version %q", s)
+		}
+		numbers[i] = n
+	}
+	return PythonVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// PythonRequirement 是需要能导入的模块；Modules 中任意一个能导入即可 (例如 cs50 或 bootcs)
+type PythonRequirement struct {
+	Modules []string
+	// Package 是安装第一个模块的 pip 包名
+	Package string
+}
+
+func (r PythonRequirement) String() string {
+	return strings.Join(r.Modules, " or ")
+}
+
+// PythonEnv 是运行学生代码的 Python 解释器
+type PythonEnv struct {
+	// Path 是解释器的绝对路径
+	Path string
+	// Source 说明解释器的来源：".venv"、PythonEnvVar 或 "system"
+	Source  string
+	Version PythonVersion
+}
+
+func (e *PythonEnv) String() string {
+	return fmt.Sprintf("Python %s (%s, %s)", e.Version, e.Path, e.Source)
+}
+
+// pythonEnvs 缓存 ResolvePython 的结果，每个提交目录只查找和检查一次解释器
+var (
+	pythonEnvsMu sync.Mutex
+	pythonEnvs   = make(map[string]*PythonEnv)
+)
+
+// ResolvePython 找到 workDir 使用的 Python 解释器并检查版本：
+// 依次是 workDir/.venv、环境变量 BCS_PYTHON、PATH 中的 python3
+func ResolvePython(workDir string) (*PythonEnv, error) {
+	absDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, err
+	}
+	key := absDir + "\x00" + os.Getenv(PythonEnvVar)
+
+	pythonEnvsMu.Lock()
+	defer pythonEnvsMu.Unlock()
+	if env, ok := pythonEnvs[key]; ok {
+		return env, nil
+	}
+
+	env, err := findPython(absDir)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runPython(env.Path, absDir, "-c", "import sys; print('%d.%d.%d' % sys.version_info[:3])")
+	if err != nil {
+		return nil, fmt.Errorf("could not run %s (%s): %v", env.Path, env.Source, err)
+	}
+	if env.Version, err = ParsePythonVersion(output); err != nil {
+		return nil, err
+	}
+	if !env.Version.AtLeast(MinPythonVersion) {
+		return nil, fmt.Errorf("%s is too old; Python %d.%d or newer is required",
+			env, MinPythonVersion.Major, MinPythonVersion.Minor)
+	}
+
+	pythonEnvs[key] = env
+	return env, nil
+}
+
+// findPython 按优先级找到解释器的绝对路径
+func findPython(absDir string) (*PythonEnv, error) {
+	venvPython := filepath.Join(absDir, ".venv", "bin", "python3")
+	if _, err := os.Stat(venvPython); err == nil {
+		return &PythonEnv{Path: venvPython, Source: ".venv"}, nil
+	}
+
+	if python := os.Getenv(PythonEnvVar); python != "" {
+		// 明确指定的解释器找不到时报错，而不是悄悄换成系统的 python3
+		path, err := exec.LookPath(python)
+		if err != nil {
+			return nil, fmt.Errorf("%s=%s is not an executable Python: %v", PythonEnvVar, python, err)
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+		return &PythonEnv{Path: path, Source: PythonEnvVar}, nil
+	}
+
+	path, err := exec.LookPath("python3")
+	if err != nil {
+		return nil, fmt.Errorf("python3 not found: create .venv in your submission, set %s, or install Python 3", PythonEnvVar)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	return &PythonEnv{Path: path, Source: "system"}, nil
+}
+
+// runPython 在 dir 中运行解释器并返回标准输出
+func runPython(python, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pythonProbeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, python, args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// pythonImportScript 逐个导入参数中的模块，输出导入失败的模块名
+const pythonImportScript = `
+import importlib, sys
+for name in sys.argv[1:]:
+    try:
+        importlib.import_module(name)
+    except Exception:
+        print(name)
+`
+
+// CheckModules 检查在 workDir 中 (学生自己的模块也在搜索路径中) 能否导入 requirements，
+// 列出缺少的模块和安装命令
+func (e *PythonEnv) CheckModules(workDir string, requirements ...PythonRequirement) error {
+	if len(requirements) == 0 {
+		return nil
+	}
+	args := []string{"-c", pythonImportScript}
+	for _, r := range requirements {
+		args = append(args, r.Modules...)
+	}
+	output, err := runPython(e.Path, workDir, args...)
+	if err != nil {
+		return fmt.Errorf("could not check Python modules with %s: %v", e, err)
+	}
+	failed := make(map[string]bool)
+	for _, name := range strings.Fields(output) {
+		failed[name] = true
+	}
+
+	var missing, packages []string
+	for _, r := range requirements {
+		importable := false
+		for _, module := range r.Modules {
+			importable = importable || !failed[module]
+		}
+		if !importable {
+			missing = append(missing, r.String())
+			packages = append(packages, r.Package)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s cannot import %s; install with: %s -m pip install %s",
+			e, strings.Join(missing, ", "), e.Path, strings.Join(packages, " "))
+	}
+	return nil
+}
+
+// Command 返回可以传给 runner.Run(workDir, ...) 的解释器路径：
+// runner 会把含 "/" 的命令 (包括绝对路径) 拼接到 workDir 之后，所以这里返回相对于 workDir 的路径
+func (e *PythonEnv) Command(workDir string) (string, error) {
+	absDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, e.Path)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel, nil
+}
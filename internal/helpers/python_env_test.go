@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePythonVersion(t *testing.T) {
+	v, err := ParsePythonVersion("Python 3.12.1\n")
+	require.NoError(t, err)
+	assert.Equal(t, PythonVersion{3, 12, 1}, v)
+	assert.True(t, v.AtLeast(MinPythonVersion))
+
+	v, err = ParsePythonVersion("3.7")
+	require.NoError(t, err)
+	assert.Equal(t, "3.7.0", v.String())
+	assert.False(t, v.AtLeast(MinPythonVersion))
+	assert.True(t, PythonVersion{3, 8, 0}.AtLeast(MinPythonVersion))
+	assert.False(t, PythonVersion{2, 9, 0}.AtLeast(MinPythonVersion))
+
+	_, err = ParsePythonVersion("Python 3.x")
+	assert.Error(t, err)
+}
+
+func TestResolvePython(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	// 没有 .venv 时使用 BCS_PYTHON 指定的解释器
+	t.Setenv(PythonEnvVar, python)
+	env, err := ResolvePython(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, PythonEnvVar, env.Source)
+	assert.True(t, filepath.IsAbs(env.Path))
+	assert.True(t, env.Version.AtLeast(MinPythonVersion))
+
+	// .venv 优先
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".venv", "bin"), 0755))
+	require.NoError(t, os.Symlink(python, filepath.Join(dir, ".venv", "bin", "python3")))
+	env, err = ResolvePython(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ".venv", env.Source)
+	assert.Equal(t, filepath.Join(dir, ".venv", "bin", "python3"), env.Path)
+
+	command, err := env.Command(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "./.venv/bin/python3", command)
+
+	t.Setenv(PythonEnvVar, filepath.Join(t.TempDir(), "python3"))
+	_, err = ResolvePython(t.TempDir())
+	assert.ErrorContains(t, err, PythonEnvVar)
+}
+
+func TestPythonEnvCommand(t *testing.T) {
+	env := &PythonEnv{Path: "/usr/bin/python3"}
+	command, err := env.Command("/home/student/finance")
+	require.NoError(t, err)
+	assert.Equal(t, "../../../usr/bin/python3", command)
+	// runner.Run 把命令拼接到 workDir 之后
+	assert.Equal(t, "/usr/bin/python3", filepath.Join("/home/student/finance", command))
+}
+
+func TestCheckModules(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bootcs.py"), []byte("get_int = int\n"), 0644))
+	env := &PythonEnv{Path: python, Source: "system"}
+
+	// 提交目录中的模块也能导入，备选模块之一能导入即可
+	assert.NoError(t, env.CheckModules(dir,
+		PythonRequirement{Modules: []string{"json"}, Package: "json"},
+		PythonRequirement{Modules: []string{"bcs_no_such_module", "bootcs"}, Package: "cs50"},
+	))
+
+	err = env.CheckModules(dir,
+		PythonRequirement{Modules: []string{"json"}},
+		PythonRequirement{Modules: []string{"bcs_missing_a"}, Package: "bcs-a"},
+		PythonRequirement{Modules: []string{"bcs_missing_b", "bcs_missing_c"}, Package: "bcs-b"},
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot import bcs_missing_a, bcs_missing_b or bcs_missing_c")
+	assert.Contains(t, err.Error(), "-m pip install bcs-a bcs-b")
+}
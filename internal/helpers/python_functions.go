@@ -0,0 +1,140 @@
+package helpers
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//go:embed drivers/python_functions.py
+var pythonFunctionsDriver string
+
+// pythonResultMarker 是驱动程序输出 JSON 结果前的标记 (与 python_functions.py 一致)
+const pythonResultMarker = "@@RESULT@@"
+
+// PythonCall 是对学生模块中某个函数的一次调用，参数以 JSON 传给 Python
+// Stdin 是调用期间的标准输入 (例如直接调用读取 input() 的 main)
+type PythonCall struct {
+	Function string        `json:"function"`
+	Args     []interface{} `json:"args"`
+	Stdin    string        `json:"stdin,omitempty"`
+}
+
+// PythonException 是 Python 抛出的异常
+type PythonException struct {
+	Type      string `json:"type"`
+	Message   string `json:"message"`
+	Traceback string `json:"traceback"`
+}
+
+func (e *PythonException) Error() string {
+	if e.Message == "" {
+		return e.Type
+	}
+	return e.Type + ": " + e.Message
+}
+
+// PythonCallResult 是一次函数调用的结果
+// 返回值可 JSON 序列化时放在 Value 中，否则 Repr 为其 repr()
+type PythonCallResult struct {
+	Value     json.RawMessage  `json:"value"`
+	Repr      string           `json:"repr"`
+	Stdout    string           `json:"stdout"`
+	Exception *PythonException `json:"exception"`
+}
+
+// Decode 将返回值解码到 v；调用抛出异常或返回值无法序列化时返回错误
+func (r PythonCallResult) Decode(v interface{}) error {
+	if r.Exception != nil {
+		return r.Exception
+	}
+	if r.Value == nil {
+		return fmt.Errorf("returned %s, which is not a plain value", r.Repr)
+	}
+	return json.Unmarshal(r.Value, v)
+}
+
+// PythonModuleResult 是一次驱动程序运行的结果
+type PythonModuleResult struct {
+	// ImportError 是导入模块时抛出的异常 (例如模块无条件调用 main() 后因参数不足而 sys.exit)
+	// 此时在该处之前定义的函数仍可调用
+	ImportError  *PythonException `json:"import_error"`
+	ImportStdout string           `json:"import_stdout"`
+	// Functions 是模块中定义的函数名 (不含导入的函数)
+	Functions []string           `json:"functions"`
+	Calls     []PythonCallResult `json:"calls"`
+}
+
+// Defines 判断模块是否定义了函数 name
+func (r *PythonModuleResult) Defines(name string) bool {
+	for _, function := range r.Functions {
+		if function == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RanOnImport 判断导入模块时是否就运行了程序 (输出了内容或因读不到输入而退出)，
+// 即程序没有放在 if __name__ == "__main__": 之下
+func (r *PythonModuleResult) RanOnImport() bool {
+	return r.ImportStdout != "" || r.ImportError != nil
+}
+
+// CallPythonFunctions 用解释器 python 以非 __main__ 方式导入 workDir 中的 module (标准输入为空)，
+// 依次调用 calls 中的函数 (共享同一个进程) 并返回每次调用的结果
+func CallPythonFunctions(python, workDir, module string, calls []PythonCall, timeout time.Duration) (*PythonModuleResult, error) {
+	tmpDir, err := os.MkdirTemp("", "pyfunc-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	driverPath := filepath.Join(tmpDir, "python_functions.py")
+	if err := os.WriteFile(driverPath, []byte(pythonFunctionsDriver), 0644); err != nil {
+		return nil, fmt.Errorf("could not write python driver: %v", err)
+	}
+	request, err := json.Marshal(map[string]interface{}{"calls": calls})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode calls: %v", err)
+	}
+	requestPath := filepath.Join(tmpDir, "request.json")
+	if err := os.WriteFile(requestPath, request, 0644); err != nil {
+		return nil, fmt.Errorf("could not write request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, python, driverPath, module, requestPath)
+	cmd.Dir = workDir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("calling functions in %s timed out after %v", module, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("python driver failed: %v\n%s", err, stderr.String())
+	}
+
+	return ParsePythonDriverOutput(string(out))
+}
+
+// ParsePythonDriverOutput 从驱动程序输出中提取 JSON 结果 (之前的内容是学生代码直接写入的输出)
+func ParsePythonDriverOutput(output string) (*PythonModuleResult, error) {
+	i := strings.LastIndex(output, pythonResultMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("python driver produced no result")
+	}
+	var result PythonModuleResult
+	if err := json.Unmarshal([]byte(output[i+len(pythonResultMarker):]), &result); err != nil {
+		return nil, fmt.Errorf("could not parse python driver result: %v", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePythonDriverOutput(t *testing.T) {
+	output := "stray output\n" + pythonResultMarker +
+		`{"import_error": {"type": "SystemExit", "message": "1", "traceback": ""}, "import_stdout": "Usage\n", ` +
+		`"calls": [{"value": 3, "stdout": ""}, {"repr": "<object>", "stdout": "hi\n"}, ` +
+		`{"exception": {"type": "ValueError", "message": "bad", "traceback": "..."}, "stdout": ""}]}` + "\n"
+
+	result, err := ParsePythonDriverOutput(output)
+	assert.NoError(t, err)
+	assert.EqualError(t, result.ImportError, "SystemExit: 1")
+	assert.Len(t, result.Calls, 3)
+
+	var n int
+	assert.NoError(t, result.Calls[0].Decode(&n))
+	assert.Equal(t, 3, n)
+	assert.Error(t, result.Calls[1].Decode(&n))
+	assert.Equal(t, "hi\n", result.Calls[1].Stdout)
+	assert.EqualError(t, result.Calls[2].Decode(&n), "ValueError: bad")
+
+	_, err = ParsePythonDriverOutput("Traceback ...")
+	assert.Error(t, err)
+}
+
+func TestCallPythonFunctions(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	dir := t.TempDir()
+	source := `import sys
+
+
+def main():
+    name = input("Name: ")
+    print(f"hello, {name}")
+    sys.exit(0)
+
+
+if __name__ == "__main__":
+    main()
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.py"), []byte(source), 0644))
+
+	result, err := CallPythonFunctions(python, dir, "hello.py", []PythonCall{
+		{Function: "main", Stdin: "David\n"},
+		{Function: "main"},
+		{Function: "missing"},
+	}, 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, result.RanOnImport())
+	assert.Equal(t, []string{"main"}, result.Functions)
+	assert.True(t, result.Defines("main"))
+	assert.False(t, result.Defines("sys"))
+
+	assert.Equal(t, "Name: hello, David\n", result.Calls[0].Stdout)
+	assert.EqualError(t, result.Calls[0].Exception, "SystemExit: 0")
+	assert.EqualError(t, result.Calls[1].Exception, "EOFError: EOF when reading a line")
+	assert.EqualError(t, result.Calls[2].Exception, "AttributeError: module has no function named missing")
+
+	// 没有 if __name__ == "__main__": 的程序在导入时就运行
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unguarded.py"), []byte("print('hello, ' + input())\n"), 0644))
+	result, err = CallPythonFunctions(python, dir, "unguarded.py", nil, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, result.RanOnImport())
+	assert.Empty(t, result.Functions)
+}
@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// QuoteURLEnv 是告诉 finance 应用报价服务地址的环境变量，
+// helpers.py 中的 lookup 应请求 $BOOTCS_QUOTE_URL/quote?symbol=SYMBOL
+const QuoteURLEnv = "BOOTCS_QUOTE_URL"
+
+// Quote 是一支股票的报价，价格以美分保存以避免浮点误差
+type Quote struct {
+	Symbol string
+	Name   string
+	Cents  int64
+}
+
+// quoteResponse 是报价 API 返回的 JSON (与 finance.cs50.io 的字段一致)
+type quoteResponse struct {
+	CompanyName string  `json:"companyName"`
+	LatestPrice float64 `json:"latestPrice"`
+	Symbol      string  `json:"symbol"`
+}
+
+// QuoteServer 是进程内的模拟报价服务，价格可在请求之间修改，
+// 并记录每支股票被查询的次数，用来确认应用确实调用了 lookup
+type QuoteServer struct {
+	URL string
+
+	listener net.Listener
+	server   *http.Server
+
+	mu       sync.Mutex
+	quotes   map[string]Quote
+	requests map[string]int
+}
+
+// NewQuoteServer 在随机端口上启动报价服务；不在 quotes 中的股票返回 404
+func NewQuoteServer(quotes ...Quote) (*QuoteServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start quote server: %v", err)
+	}
+
+	s := &QuoteServer{
+		URL:      "http://" + listener.Addr().String(),
+		listener: listener,
+		quotes:   make(map[string]Quote),
+		requests: make(map[string]int),
+	}
+	for _, quote := range quotes {
+		s.quotes[strings.ToUpper(quote.Symbol)] = quote
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quote", s.handleQuote)
+	s.server = &http.Server{Handler: mux}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// handleQuote 处理 GET /quote?symbol=SYMBOL
+func (s *QuoteServer) handleQuote(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+
+	s.mu.Lock()
+	s.requests[symbol]++
+	quote, ok := s.quotes[symbol]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quoteResponse{
+		CompanyName: quote.Name,
+		LatestPrice: float64(quote.Cents) / 100,
+		Symbol:      symbol,
+	})
+}
+
+// SetPrice 修改股票价格，之后的请求返回新价格
+func (s *QuoteServer) SetPrice(symbol string, cents int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	quote := s.quotes[symbol]
+	quote.Symbol = symbol
+	quote.Cents = cents
+	s.quotes[symbol] = quote
+}
+
+// Price 返回股票当前价格 (美分)
+func (s *QuoteServer) Price(symbol string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.quotes[strings.ToUpper(symbol)].Cents
+}
+
+// Symbols 返回所有股票代码 (已排序)
+func (s *QuoteServer) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbols := make([]string, 0, len(s.quotes))
+	for symbol := range s.quotes {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Requests 返回股票被查询的次数
+func (s *QuoteServer) Requests(symbol string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[strings.ToUpper(symbol)]
+}
+
+// Close 关闭报价服务
+func (s *QuoteServer) Close() {
+	s.server.Close()
+}
+
+// RandomQuoteCents 返回 $10.00 到 $500.00 之间且不同于 exclude 的随机价格
+func RandomQuoteCents(exclude ...int64) int64 {
+	for {
+		cents := int64(random.RandomInt(1000, 50001))
+		excluded := false
+		for _, e := range exclude {
+			if cents == e {
+				excluded = true
+			}
+		}
+		if !excluded {
+			return cents
+		}
+	}
+}
+
+// FormatCents 把美分格式化为带千位分隔符的金额，例如 123456 -> "1,234.56"
+func FormatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	dollars := fmt.Sprintf("%d", cents/100)
+	var b strings.Builder
+	for i, c := range dollars {
+		if i > 0 && (len(dollars)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, b.String(), cents%100)
+}
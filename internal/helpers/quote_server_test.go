@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteServer(t *testing.T) {
+	server, err := NewQuoteServer(Quote{Symbol: "AAAA", Name: "Test A", Cents: 2800})
+	require.NoError(t, err)
+	defer server.Close()
+
+	getQuote := func(symbol string) (int, quoteResponse) {
+		resp, err := http.Get(server.URL + "/quote?symbol=" + symbol)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var quote quoteResponse
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&quote))
+		}
+		return resp.StatusCode, quote
+	}
+
+	status, quote := getQuote("aaaa")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, quoteResponse{CompanyName: "Test A", LatestPrice: 28, Symbol: "AAAA"}, quote)
+
+	server.SetPrice("AAAA", 12345)
+	_, quote = getQuote("AAAA")
+	assert.Equal(t, 123.45, quote.LatestPrice)
+	assert.Equal(t, int64(12345), server.Price("aaaa"))
+	assert.Equal(t, 2, server.Requests("AAAA"))
+
+	status, _ = getQuote("ZZZZ")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, 1, server.Requests("ZZZZ"))
+}
+
+func TestFormatCents(t *testing.T) {
+	assert.Equal(t, "0.05", FormatCents(5))
+	assert.Equal(t, "112.00", FormatCents(11200))
+	assert.Equal(t, "9,888.00", FormatCents(988800))
+	assert.Equal(t, "1,234,567.89", FormatCents(123456789))
+	assert.Equal(t, "-1,000.50", FormatCents(-100050))
+}
+
+func TestRandomQuoteCents(t *testing.T) {
+	random.Init()
+	for i := 0; i < 100; i++ {
+		cents := RandomQuoteCents(1000)
+		assert.GreaterOrEqual(t, cents, int64(1001))
+		assert.LessOrEqual(t, cents, int64(50000))
+	}
+}
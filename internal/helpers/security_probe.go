@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SQLInjectionPayloads 是提交到表单字段的 SQL 注入载荷
+// 用 ? 占位符执行的查询会把它们当作普通字符串；拼接 SQL 的查询会出错或改变查询的含义
+var SQLInjectionPayloads = []string{
+	"' OR '1'='1",
+	"' OR 1=1 --",
+	`" OR ""="`,
+	"') OR ('1'='1",
+	"'; DROP TABLE users; --",
+}
+
+// sqlErrorMarkers 是 SQLite 和 Python 出错时常见的文字，出现在响应中说明错误信息泄露给了用户
+var sqlErrorMarkers = []string{
+	"sqlite3.",
+	"OperationalError",
+	"syntax error",
+	"unrecognized token",
+	"Traceback (most recent call last)",
+}
+
+// XSSPayloads 返回带 marker 的 XSS 载荷：一个 script 元素和一个跳出属性的事件处理器
+func XSSPayloads(marker string) []string {
+	return []string{
+		fmt.Sprintf(`<script>alert("%s")</script>`, marker),
+		fmt.Sprintf(`"><img src=x onerror=alert("%s")>`, marker),
+	}
+}
+
+// SecurityFinding 是安全检查发现的一个问题，记录触发问题的请求
+type SecurityFinding struct {
+	Category string
+	Method   string
+	Path     string
+	Form     url.Values
+	Problem  string
+}
+
+// Request 返回触发问题的请求，例如 POST /login (password="x", username="' OR 1=1 --")
+func (f SecurityFinding) Request() string {
+	if len(f.Form) == 0 {
+		return f.Method + " " + f.Path
+	}
+	keys := make([]string, 0, len(f.Form))
+	for key := range f.Form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%q", key, f.Form.Get(key)))
+	}
+	return fmt.Sprintf("%s %s (%s)", f.Method, f.Path, strings.Join(fields, ", "))
+}
+
+func (f SecurityFinding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Category, f.Request(), f.Problem)
+}
+
+// LeaksSQLError 检查响应中是否有 SQL 错误或 Python traceback
+func LeaksSQLError(body string) bool {
+	for _, marker := range sqlErrorMarkers {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasInjectedMarkup 检查页面是否把带 marker 的载荷当作标记执行：
+// script 元素的内容或 on* 事件属性中含有 marker；转义后的载荷只是文字，不算
+func HasInjectedMarkup(body, marker string) bool {
+	root, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return hasInjectedMarkup(root, marker)
+}
+
+func hasInjectedMarkup(n *html.Node, marker string) bool {
+	if n.Type == html.ElementNode {
+		if n.DataAtom == atom.Script {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.TextNode && strings.Contains(c.Data, marker) {
+					return true
+				}
+			}
+		}
+		for _, a := range n.Attr {
+			if strings.HasPrefix(strings.ToLower(a.Key), "on") && strings.Contains(a.Val, marker) {
+				return true
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasInjectedMarkup(c, marker) {
+			return true
+		}
+	}
+	return false
+}
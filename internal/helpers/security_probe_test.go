@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"html"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityFinding(t *testing.T) {
+	finding := SecurityFinding{
+		Category: "SQL injection",
+		Method:   "POST",
+		Path:     "/login",
+		Form:     url.Values{"username": {"' OR 1=1 --"}, "password": {"x"}},
+		Problem:  "logged in without a valid password",
+	}
+	assert.Equal(t, `[SQL injection] POST /login (password="x", username="' OR 1=1 --"): logged in without a valid password`,
+		finding.String())
+
+	finding.Form = nil
+	assert.Equal(t, "POST /login", finding.Request())
+}
+
+func TestLeaksSQLError(t *testing.T) {
+	assert.True(t, LeaksSQLError(`sqlite3.OperationalError: near "1": syntax error`))
+	assert.True(t, LeaksSQLError("Traceback (most recent call last):\n  File \"app.py\""))
+	assert.False(t, LeaksSQLError("<h1>400</h1><p>invalid username and/or password</p>"))
+}
+
+func TestHasInjectedMarkup(t *testing.T) {
+	marker := "bcs-xss-42"
+	payloads := XSSPayloads(marker)
+
+	for _, payload := range payloads {
+		raw := "<p>Hello, " + payload + "</p>"
+		assert.True(t, HasInjectedMarkup(raw, marker), payload)
+
+		escaped := "<p>Hello, " + html.EscapeString(payload) + "</p>"
+		assert.False(t, HasInjectedMarkup(escaped, marker), payload)
+	}
+
+	// 属性值中转义后的载荷不会跳出属性
+	attribute := `<input name="symbol" value="` + html.EscapeString(payloads[1]) + `">`
+	assert.False(t, HasInjectedMarkup(attribute, marker))
+	attribute = `<input name="symbol" value="` + payloads[1] + `">`
+	assert.True(t, HasInjectedMarkup(attribute, marker))
+
+	assert.False(t, HasInjectedMarkup(`<script>var user = "alice";</script>`, marker))
+}
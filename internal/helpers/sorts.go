@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// SortInputKinds 是 sort 实验使用的三种输入 (与发行版的 sorted/reversed/random*.txt 对应)
+var SortInputKinds = []string{"sorted", "reversed", "random"}
+
+// 排序算法名称 (answers.txt 中的答案)
+const (
+	BubbleSort    = "Bubble"
+	MergeSort     = "Merge"
+	SelectionSort = "Selection"
+)
+
+// sortQuadraticGrowth 是规模扩大 4 倍时判定为 O(n²) 的增长倍数 (O(n²) 约 16 倍，O(n log n) 约 4.6 倍)
+const sortQuadraticGrowth = 8.0
+
+// GenerateSortInput 生成 n 个整数：已排序、逆序或随机
+func GenerateSortInput(kind string, n int) []int {
+	numbers := make([]int, n)
+	for i := range numbers {
+		switch kind {
+		case "sorted":
+			numbers[i] = i + 1
+		case "reversed":
+			numbers[i] = n - i
+		default:
+			numbers[i] = random.RandomInt(1, n+1)
+		}
+	}
+	return numbers
+}
+
+// FormatSortInput 返回每行一个数字的文本 (发行版 sort 程序的输入格式)
+func FormatSortInput(numbers []int) string {
+	var b strings.Builder
+	for _, n := range numbers {
+		fmt.Fprintf(&b, "%d\n", n)
+	}
+	return b.String()
+}
+
+// SortComparisons 用 Go 实现的排序算法排序 numbers 的副本，返回比较次数
+// 冒泡排序在某一轮没有交换时提前结束，因此对已排序输入只需 O(n) 次比较
+func SortComparisons(algorithm string, numbers []int) int {
+	return sortInts(algorithm, append([]int(nil), numbers...))
+}
+
+// sortInts 用 algorithm 原地排序 a，返回比较次数
+func sortInts(algorithm string, a []int) int {
+	comparisons := 0
+	switch algorithm {
+	case BubbleSort:
+		for end := len(a) - 1; end > 0; end-- {
+			swapped := false
+			for i := 0; i < end; i++ {
+				comparisons++
+				if a[i] > a[i+1] {
+					a[i], a[i+1] = a[i+1], a[i]
+					swapped = true
+				}
+			}
+			if !swapped {
+				break
+			}
+		}
+	case SelectionSort:
+		for i := 0; i < len(a); i++ {
+			min := i
+			for j := i + 1; j < len(a); j++ {
+				comparisons++
+				if a[j] < a[min] {
+					min = j
+				}
+			}
+			a[i], a[min] = a[min], a[i]
+		}
+	case MergeSort:
+		comparisons = mergeSort(a, make([]int, len(a)))
+	}
+	return comparisons
+}
+
+func mergeSort(a, buf []int) int {
+	if len(a) < 2 {
+		return 0
+	}
+	mid := len(a) / 2
+	comparisons := mergeSort(a[:mid], buf[:mid]) + mergeSort(a[mid:], buf[mid:])
+
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(a) {
+		comparisons++
+		if a[i] <= a[j] {
+			buf[k] = a[i]
+			i++
+		} else {
+			buf[k] = a[j]
+			j++
+		}
+		k++
+	}
+	k += copy(buf[k:], a[i:mid])
+	copy(buf[k:], a[j:])
+	copy(a, buf[:len(a)])
+	return comparisons
+}
+
+// ClassifySort 根据两种规模 (large 为 small 的 4 倍) 下各输入的耗时或比较次数判断排序算法：
+// 随机输入上不是 O(n²) 的是归并排序；已排序输入上仍是 O(n²) 的是选择排序，否则是冒泡排序
+func ClassifySort(small, large map[string]float64) string {
+	quadratic := func(kind string) bool {
+		return small[kind] > 0 && large[kind]/small[kind] > sortQuadraticGrowth
+	}
+	switch {
+	case !quadratic("random"):
+		return MergeSort
+	case quadratic("sorted"):
+		return SelectionSort
+	default:
+		return BubbleSort
+	}
+}
@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSortInput(t *testing.T) {
+	random.Init()
+
+	assert.Equal(t, []int{1, 2, 3, 4}, GenerateSortInput("sorted", 4))
+	assert.Equal(t, []int{4, 3, 2, 1}, GenerateSortInput("reversed", 4))
+	numbers := GenerateSortInput("random", 100)
+	assert.Len(t, numbers, 100)
+	for _, n := range numbers {
+		assert.True(t, n >= 1 && n <= 100)
+	}
+	assert.Equal(t, "3\n1\n2\n", FormatSortInput([]int{3, 1, 2}))
+}
+
+func TestSortComparisons(t *testing.T) {
+	n := 100
+	sorted := GenerateSortInput("sorted", n)
+	reversed := GenerateSortInput("reversed", n)
+
+	assert.Equal(t, n-1, SortComparisons(BubbleSort, sorted))
+	assert.Equal(t, n*(n-1)/2, SortComparisons(BubbleSort, reversed))
+	assert.Equal(t, n*(n-1)/2, SortComparisons(SelectionSort, sorted))
+	assert.Equal(t, n*(n-1)/2, SortComparisons(SelectionSort, reversed))
+	assert.Less(t, SortComparisons(MergeSort, reversed), 8*n)
+
+	// 输入不被修改
+	assert.True(t, sort.IntsAreSorted(sorted))
+	assert.Equal(t, n, reversed[0])
+}
+
+func TestClassifySort(t *testing.T) {
+	random.Init()
+
+	for _, algorithm := range []string{BubbleSort, MergeSort, SelectionSort} {
+		small := make(map[string]float64)
+		large := make(map[string]float64)
+		for _, kind := range SortInputKinds {
+			small[kind] = float64(SortComparisons(algorithm, GenerateSortInput(kind, 1000)))
+			large[kind] = float64(SortComparisons(algorithm, GenerateSortInput(kind, 4000)))
+		}
+		assert.Equal(t, algorithm, ClassifySort(small, large))
+	}
+}
@@ -0,0 +1,264 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// SpellerMaxLength 是 dictionary.h 中 LENGTH 的值 (单词最大长度)
+const SpellerMaxLength = 45
+
+// SpellerDictionary 是参考实现使用的字典
+type SpellerDictionary struct {
+	words map[string]struct{}
+	size  int
+}
+
+// LoadSpellerDictionary 加载字典 (每行一个小写单词)
+func LoadSpellerDictionary(data []byte) *SpellerDictionary {
+	fields := strings.Fields(string(data))
+	dict := &SpellerDictionary{words: make(map[string]struct{}, len(fields))}
+	for _, word := range fields {
+		dict.words[word] = struct{}{}
+		dict.size++
+	}
+	return dict
+}
+
+// Size 返回字典中的单词数 (与 size() 的返回值一致)
+func (d *SpellerDictionary) Size() int {
+	return d.size
+}
+
+// Check 不区分大小写地检查单词是否在字典中
+func (d *SpellerDictionary) Check(word string) bool {
+	_, ok := d.words[strings.ToLower(word)]
+	return ok
+}
+
+// SpellerWords 按 speller.c 的规则从文本中切分单词：
+// 只允许字母和 (非开头的) 撇号；超过 45 个字符的单词与含数字的单词被跳过；
+// 与 speller.c 一样，文件末尾没有分隔符的最后一个单词不会被计入
+func SpellerWords(text []byte) []string {
+	var words []string
+	word := make([]byte, 0, SpellerMaxLength+1)
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case isASCIIAlpha(c) || (c == '\'' && len(word) > 0):
+			word = append(word, c)
+			if len(word) > SpellerMaxLength {
+				// 跳过剩余的字母 (同时会消耗掉紧随其后的一个字符)
+				for i++; i < len(text) && isASCIIAlpha(text[i]); i++ {
+				}
+				word = word[:0]
+			}
+		case c >= '0' && c <= '9':
+			// 跳过含数字的单词 (同时会消耗掉紧随其后的一个字符)
+			for i++; i < len(text) && isASCIIAlnum(text[i]); i++ {
+			}
+			word = word[:0]
+		case len(word) > 0:
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	return words
+}
+
+func isASCIIAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIAlnum(c byte) bool {
+	return isASCIIAlpha(c) || (c >= '0' && c <= '9')
+}
+
+// SpellCheckResult 是 speller 的检查结果
+type SpellCheckResult struct {
+	Misspelled        []string
+	WordsInDictionary int
+	WordsInText       int
+}
+
+// SpellCheck 用参考实现检查文本，结果应与正确的 speller 输出一致
+func SpellCheck(dict *SpellerDictionary, text []byte) SpellCheckResult {
+	words := SpellerWords(text)
+	result := SpellCheckResult{
+		Misspelled:        []string{},
+		WordsInDictionary: dict.Size(),
+		WordsInText:       len(words),
+	}
+	for _, word := range words {
+		if !dict.Check(word) {
+			result.Misspelled = append(result.Misspelled, word)
+		}
+	}
+	return result
+}
+
+// SpellerReport 是解析后的 speller 输出
+type SpellerReport struct {
+	Misspelled        []string
+	WordsMisspelled   int
+	WordsInDictionary int
+	WordsInText       int
+	// Times 为各阶段耗时 (秒)，键为 load、check、size、unload、TOTAL
+	Times map[string]float64
+}
+
+// ParseSpellerOutput 解析 speller 的输出 (MISSPELLED WORDS 列表、统计数字和 TIME IN 行)
+func ParseSpellerOutput(output string) (*SpellerReport, error) {
+	report := &SpellerReport{Misspelled: []string{}, Times: make(map[string]float64)}
+	counts := map[string]*int{
+		"WORDS MISSPELLED":    &report.WordsMisspelled,
+		"WORDS IN DICTIONARY": &report.WordsInDictionary,
+		"WORDS IN TEXT":       &report.WordsInText,
+	}
+	found := make(map[string]bool)
+
+	inMisspelled := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "MISSPELLED WORDS" {
+			inMisspelled = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if ok {
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+			if dst, isCount := counts[key]; isCount {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse %q", line)
+				}
+				*dst = n
+				found[key] = true
+				inMisspelled = false
+				continue
+			}
+			if phase, isTime := strings.CutPrefix(key, "TIME IN "); isTime {
+				t, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse %q", line)
+				}
+				report.Times[phase] = t
+				found[key] = true
+				continue
+			}
+		}
+
+		if inMisspelled && line != "" {
+			report.Misspelled = append(report.Misspelled, line)
+		}
+	}
+
+	for key := range counts {
+		if !found[key] {
+			return nil, fmt.Errorf("output is missing %q", key)
+		}
+	}
+	return report, nil
+}
+
+// DiffSpellerWords 按多重集比较拼错单词列表
+// missing 为参考实现报告但学生未报告的单词，extra 为学生多报告的单词 (均保持原顺序)
+func DiffSpellerWords(expected, actual []string) (missing, extra []string) {
+	remaining := make(map[string]int)
+	for _, word := range actual {
+		remaining[word]++
+	}
+	for _, word := range expected {
+		if remaining[word] > 0 {
+			remaining[word]--
+		} else {
+			missing = append(missing, word)
+		}
+	}
+	for i := len(actual) - 1; i >= 0; i-- {
+		if remaining[actual[i]] > 0 {
+			remaining[actual[i]]--
+			extra = append([]string{actual[i]}, extra...)
+		}
+	}
+	return missing, extra
+}
+
+// GenerateSpellerCorpus 生成随机字典和文本
+// 字典包含单字母单词、45 字符单词和带撇号的单词；文本混合大小写变体、
+// 子串/超串形式的拼错单词、含数字的单词、超长单词以及以撇号开头的单词
+func GenerateSpellerCorpus(dictWords, textWords int) (dict []byte, text []byte) {
+	seen := make(map[string]bool)
+	var words []string
+	add := func(word string) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+
+	add(string(rune('a' + random.RandomInt(0, 26))))
+	add(randomLowerWord(SpellerMaxLength))
+	for len(words) < dictWords {
+		word := randomLowerWord(random.RandomInt(2, 12))
+		if random.RandomInt(0, 5) == 0 {
+			word = word[:len(word)-1] + "'" + word[len(word)-1:]
+		}
+		add(word)
+	}
+	sorted := append([]string(nil), words...)
+	sort.Strings(sorted)
+	dict = []byte(strings.Join(sorted, "\n") + "\n")
+
+	separators := []string{" ", " ", " ", "\n", ", ", ". ", "; ", " - ", "\"", "! "}
+	var b strings.Builder
+	for i := 0; i < textWords; i++ {
+		word := words[random.RandomInt(0, len(words))]
+		switch random.RandomInt(0, 10) {
+		case 0:
+			// 超串：字典单词后多一个字母
+			word += string(rune('a' + random.RandomInt(0, 26)))
+		case 1:
+			// 子串：去掉最后一个字母
+			if len(word) > 1 {
+				word = word[:len(word)-1]
+			}
+		case 2:
+			word += strconv.Itoa(random.RandomInt(0, 100))
+		case 3:
+			word = randomLowerWord(SpellerMaxLength + 1 + random.RandomInt(0, 10))
+		case 4:
+			word = "'" + word
+		}
+		b.WriteString(randomCase(word))
+		b.WriteString(separators[random.RandomInt(0, len(separators))])
+	}
+	b.WriteString("\n")
+	return dict, []byte(b.String())
+}
+
+func randomLowerWord(length int) string {
+	word := make([]byte, length)
+	for i := range word {
+		word[i] = byte('a' + random.RandomInt(0, 26))
+	}
+	return string(word)
+}
+
+func randomCase(word string) string {
+	switch random.RandomInt(0, 4) {
+	case 0:
+		return strings.ToUpper(word)
+	case 1:
+		return strings.ToUpper(word[:1]) + word[1:]
+	default:
+		return word
+	}
+}
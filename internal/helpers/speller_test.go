@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpellerWords(t *testing.T) {
+	long := strings.Repeat("a", SpellerMaxLength)
+	tooLong := strings.Repeat("b", SpellerMaxLength+1)
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"The cat sat.\n", []string{"The", "cat", "sat"}},
+		{"don't 'quoted' it's\n", []string{"don't", "quoted'", "it's"}},
+		{long + " " + tooLong + " ok\n", []string{long, "ok"}},
+		{"abc123 x2y cs50 fine\n", []string{"fine"}},
+		{"last word", []string{"last"}},
+		{"", nil},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, SpellerWords([]byte(tc.text)), "text=%q", tc.text)
+	}
+}
+
+func TestSpellCheck(t *testing.T) {
+	dict := LoadSpellerDictionary([]byte("cat\ncaterpillar\nthe\n"))
+	assert.Equal(t, 3, dict.Size())
+
+	result := SpellCheck(dict, []byte("The Cat ate the caterpillar.\n"))
+	assert.Equal(t, []string{"ate"}, result.Misspelled)
+	assert.Equal(t, 3, result.WordsInDictionary)
+	assert.Equal(t, 5, result.WordsInText)
+}
+
+func TestParseSpellerOutput(t *testing.T) {
+	output := `
+MISSPELLED WORDS
+
+ate
+Caterpilar
+
+WORDS MISSPELLED:     2
+WORDS IN DICTIONARY:  3
+WORDS IN TEXT:        5
+TIME IN load:         0.01
+TIME IN check:        0.02
+TIME IN size:         0.00
+TIME IN unload:       0.00
+TIME IN TOTAL:        0.03
+`
+	report, err := ParseSpellerOutput(output)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ate", "Caterpilar"}, report.Misspelled)
+	assert.Equal(t, 2, report.WordsMisspelled)
+	assert.Equal(t, 3, report.WordsInDictionary)
+	assert.Equal(t, 5, report.WordsInText)
+	assert.InDelta(t, 0.02, report.Times["check"], 1e-9)
+	assert.InDelta(t, 0.03, report.Times["TOTAL"], 1e-9)
+
+	_, err = ParseSpellerOutput("MISSPELLED WORDS\n\nWORDS MISSPELLED: 0\n")
+	assert.Error(t, err)
+}
+
+func TestDiffSpellerWords(t *testing.T) {
+	missing, extra := DiffSpellerWords([]string{"ca", "cats", "ca"}, []string{"ca", "cat", "cats"})
+	assert.Equal(t, []string{"ca"}, missing)
+	assert.Equal(t, []string{"cat"}, extra)
+
+	missing, extra = DiffSpellerWords([]string{"a", "b"}, []string{"a", "b"})
+	assert.Empty(t, missing)
+	assert.Empty(t, extra)
+}
+
+func TestGenerateSpellerCorpus(t *testing.T) {
+	random.Init()
+
+	dict, text := GenerateSpellerCorpus(100, 500)
+	words := strings.Fields(string(dict))
+	assert.Len(t, words, 100)
+	for _, word := range words {
+		assert.LessOrEqual(t, len(word), SpellerMaxLength, "word=%q", word)
+		assert.NotEqual(t, byte('\''), word[0], "word=%q", word)
+	}
+	assert.True(t, strings.HasSuffix(string(text), "\n"))
+
+	result := SpellCheck(LoadSpellerDictionary(dict), text)
+	assert.NotEmpty(t, result.Misspelled)
+	assert.Less(t, len(result.Misspelled), result.WordsInText)
+}
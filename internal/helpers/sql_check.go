@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLCheck is the expected result of the query in one SQL file
+type SQLCheck struct {
+	Filename string
+	Expected [][]string
+	// Alternatives are other accepted results (e.g. two valid answers to an open question)
+	Alternatives [][][]string
+	Comparison   SQLComparison
+	// Budget is how long the query may run (0 uses the sandbox timeout)
+	Budget time.Duration
+}
+
+// SQLCheckReport describes a passing query
+type SQLCheckReport struct {
+	Elapsed time.Duration
+	// Warnings are problems in the query plan that make the query slow
+	Warnings []string
+}
+
+// UnorderedSQLCheck expects a single column of values in any order
+func UnorderedSQLCheck(filename string, expected []string) SQLCheck {
+	return SQLCheck{Filename: filename, Expected: SingleColumnRows(expected)}
+}
+
+// OrderedSQLCheck expects a single column of values in order
+func OrderedSQLCheck(filename string, expected []string) SQLCheck {
+	return SQLCheck{Filename: filename, Expected: SingleColumnRows(expected), Comparison: SQLComparison{Ordered: true}}
+}
+
+// ValueSQLCheck expects a single value
+func ValueSQLCheck(filename, expected string) SQLCheck {
+	return SQLCheck{Filename: filename, Expected: [][]string{{expected}}}
+}
+
+// FloatSQLCheck expects a single number within tolerance
+func FloatSQLCheck(filename string, expected, tolerance float64) SQLCheck {
+	return SQLCheck{
+		Filename:   filename,
+		Expected:   [][]string{{strconv.FormatFloat(expected, 'f', -1, 64)}},
+		Comparison: SQLComparison{Columns: []SQLColumn{NumericColumn(tolerance)}},
+	}
+}
+
+// PairsSQLCheck expects two columns in order (the columns may be selected in either order)
+func PairsSQLCheck(filename string, expected [][2]string) SQLCheck {
+	rows := make([][]string, len(expected))
+	for i, row := range expected {
+		rows[i] = []string{row[0], row[1]}
+	}
+	return SQLCheck{Filename: filename, Expected: rows, Comparison: SQLComparison{Ordered: true, AnyColumnOrder: true}}
+}
+
+// WithBudget returns the check with a time budget for the query
+func (c SQLCheck) WithBudget(budget time.Duration) SQLCheck {
+	c.Budget = budget
+	return c
+}
+
+// Run checks the query in workDir: it must be a single read-only query without
+// hard-coded ids, and return the expected rows. If perturbed is not nil the query
+// must also return the same rows on the perturbed copy of the database, unless it
+// uses LIMIT: rows tied at the cut-off may change with the ids (and so the scan order)
+func (c SQLCheck) Run(sandbox, perturbed *SQLSandbox, workDir string) (*SQLCheckReport, error) {
+	query, err := ReadSQLFile(workDir, c.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if issues := LintSQLQuery(query); len(issues) > 0 {
+		messages := make([]string, len(issues))
+		for i, issue := range issues {
+			messages[i] = issue.String()
+		}
+		return nil, fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+
+	budget := c.Budget
+	if budget == 0 {
+		budget = sandbox.Timeout
+	}
+	start := time.Now()
+	actual, err := sandbox.QueryWithin(query, budget)
+	if err != nil {
+		return nil, err
+	}
+	report := &SQLCheckReport{Elapsed: time.Since(start)}
+	if err := c.Compare(actual).Error(); err != nil {
+		return nil, err
+	}
+
+	if perturbed != nil && !hasSQLLimit(query) {
+		if err := CompareWithPerturbed(actual, perturbed, query, budget); err != nil {
+			return nil, err
+		}
+	}
+
+	if analysis, err := sandbox.AnalyzeQuery(query); err == nil {
+		report.Warnings = analysis.Warnings
+	}
+	return report, nil
+}
+
+// Compare compares a query result with the expected rows and each alternative,
+// returning the closest match
+func (c SQLCheck) Compare(actual *QueryResult) *SQLDiff {
+	best := CompareQueryResult(c.Expected, actual, c.Comparison)
+	for _, alternative := range c.Alternatives {
+		if best.Empty() {
+			break
+		}
+		diff := CompareQueryResult(alternative, actual, c.Comparison)
+		if diff.size() < best.size() || diff.Empty() {
+			best = diff
+		}
+	}
+	return best
+}
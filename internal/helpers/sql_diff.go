@@ -0,0 +1,555 @@
+package helpers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLite storage classes reported for each value in a query result
+const (
+	SQLInteger = "INTEGER"
+	SQLReal    = "REAL"
+	SQLText    = "TEXT"
+	SQLBlob    = "BLOB"
+	SQLNull    = "NULL"
+)
+
+// maxSQLDiffRows is the number of rows shown for each kind of difference
+const maxSQLDiffRows = 10
+
+// SQLValue is a single value in a query result together with its SQLite type
+// Value holds the typed Go value (int64, float64, string, []byte or nil); Text is its
+// string form, formatted the way database/sql converts it to a string
+type SQLValue struct {
+	Type  string
+	Text  string
+	Value interface{}
+}
+
+// IsNumeric reports whether the value is an INTEGER or REAL
+func (v SQLValue) IsNumeric() bool {
+	return v.Type == SQLInteger || v.Type == SQLReal
+}
+
+// String formats the value for error messages (text is quoted, numbers are not)
+func (v SQLValue) String() string {
+	switch v.Type {
+	case SQLNull:
+		return "NULL"
+	case SQLText, SQLBlob:
+		return strconv.Quote(v.Text)
+	}
+	return v.Text
+}
+
+// QueryResult is the typed result of a query
+type QueryResult struct {
+	Columns []string
+	Rows    [][]SQLValue
+}
+
+// ExecuteQuery executes a SQL query and returns every row with the type of each value
+func ExecuteQuery(db *sql.DB, query string) (*QueryResult, error) {
+	return ExecuteQueryContext(context.Background(), db, query, 0)
+}
+
+// ExecuteQueryContext executes a SQL query until ctx is done; if rowLimit is positive,
+// returning more than rowLimit rows is an error
+func ExecuteQueryContext(ctx context.Context, db *sql.DB, query string, rowLimit int) (*QueryResult, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("query error: %v", err)
+	}
+
+	result := &QueryResult{Columns: columns}
+	for rows.Next() {
+		if rowLimit > 0 && len(result.Rows) == rowLimit {
+			return nil, &SQLRowLimitError{Limit: rowLimit}
+		}
+		raw := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range raw {
+			pointers[i] = &raw[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("scan error: %v", err)
+		}
+		row := make([]SQLValue, len(columns))
+		for i, v := range raw {
+			row[i] = toSQLValue(v)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %v", err)
+	}
+	return result, nil
+}
+
+// toSQLValue converts a value scanned by go-sqlite3 (formatted like database/sql does for strings)
+func toSQLValue(v interface{}) SQLValue {
+	switch v := v.(type) {
+	case nil:
+		return SQLValue{Type: SQLNull}
+	case int64:
+		return SQLValue{Type: SQLInteger, Text: strconv.FormatInt(v, 10), Value: v}
+	case float64:
+		return SQLValue{Type: SQLReal, Text: strconv.FormatFloat(v, 'g', -1, 64), Value: v}
+	case bool:
+		if v {
+			return SQLValue{Type: SQLInteger, Text: "1", Value: int64(1)}
+		}
+		return SQLValue{Type: SQLInteger, Text: "0", Value: int64(0)}
+	case []byte:
+		return SQLValue{Type: SQLBlob, Text: string(v), Value: v}
+	case string:
+		return SQLValue{Type: SQLText, Text: v, Value: v}
+	case time.Time:
+		text := v.Format(time.RFC3339Nano)
+		return SQLValue{Type: SQLText, Text: text, Value: text}
+	}
+	text := fmt.Sprint(v)
+	return SQLValue{Type: SQLText, Text: text, Value: text}
+}
+
+// Column returns the text of the i-th column of every row
+func (r *QueryResult) Column(i int) []string {
+	values := make([]string, len(r.Rows))
+	for j, row := range r.Rows {
+		values[j] = row[i].Text
+	}
+	return values
+}
+
+// SQLColumnMode is how values in one column are compared with the expected values
+type SQLColumnMode int
+
+const (
+	// SQLExact compares text exactly, and numbers by value (9.0 equals 9)
+	SQLExact SQLColumnMode = iota
+	// SQLCaseInsensitive compares text ignoring case
+	SQLCaseInsensitive
+	// SQLNumeric compares numbers within SQLColumn.Tolerance
+	SQLNumeric
+)
+
+// SQLColumn configures the comparison of one expected column
+type SQLColumn struct {
+	Mode      SQLColumnMode
+	Tolerance float64
+}
+
+// NumericColumn compares a column as numbers within tolerance
+func NumericColumn(tolerance float64) SQLColumn {
+	return SQLColumn{Mode: SQLNumeric, Tolerance: tolerance}
+}
+
+// CaseInsensitiveColumn compares a column ignoring case
+func CaseInsensitiveColumn() SQLColumn {
+	return SQLColumn{Mode: SQLCaseInsensitive}
+}
+
+// SQLComparison configures how a query result is compared with the expected rows
+type SQLComparison struct {
+	// Ordered requires rows in the expected order
+	Ordered bool
+	// Columns configures each expected column; columns not listed are compared with SQLExact
+	Columns []SQLColumn
+	// AnyColumnOrder accepts the columns in any order (e.g. SELECT year, title instead of title, year)
+	AnyColumnOrder bool
+	// IgnoreTypes skips the check that numeric columns return numbers and text columns return text
+	IgnoreTypes bool
+}
+
+// maxSQLColumnPermutations bounds the column orders tried when AnyColumnOrder is set
+const maxSQLColumnPermutations = 720
+
+// SQLDiff describes how a query result differs from the expected rows
+type SQLDiff struct {
+	// ExpectedColumns and ActualColumns differ when the query selects the wrong number of columns
+	ExpectedColumns int
+	ActualColumns   int
+
+	// ColumnOrder maps each expected column to the actual column it was matched with
+	ColumnOrder []int
+
+	// TypeMismatches lists columns whose values have the wrong type (e.g. REAL vs TEXT)
+	TypeMismatches []string
+
+	Missing    []string
+	Unexpected []string
+	Duplicates []string
+
+	// OutOfOrder is the first row (1-based) that is out of order, 0 if none
+	OutOfOrder       int
+	OutOfOrderDetail string
+
+	ExpectedRows int
+	ActualRows   int
+}
+
+// DiffQueryResult compares a query result with the expected rows using exact comparison
+func DiffQueryResult(expected [][]string, actual *QueryResult, ordered bool) *SQLDiff {
+	return CompareQueryResult(expected, actual, SQLComparison{Ordered: ordered})
+}
+
+// CompareQueryResult compares a query result with the expected rows
+// A column whose expected values are all numbers must return INTEGER or REAL values,
+// and a column with no numbers must return TEXT. With AnyColumnOrder, every column
+// order is tried and the closest match is reported
+func CompareQueryResult(expected [][]string, actual *QueryResult, cmp SQLComparison) *SQLDiff {
+	columns := expectedColumnCount(expected)
+	if columns != len(actual.Columns) {
+		return &SQLDiff{
+			ExpectedColumns: columns,
+			ActualColumns:   len(actual.Columns),
+			ExpectedRows:    len(expected),
+			ActualRows:      len(actual.Rows),
+		}
+	}
+
+	identity := make([]int, columns)
+	for i := range identity {
+		identity[i] = i
+	}
+	best := diffPermutedResult(expected, actual, identity, cmp)
+	if best.Empty() || !cmp.AnyColumnOrder {
+		return best
+	}
+
+	tried := 0
+	permuteColumns(identity, func(order []int) bool {
+		tried++
+		diff := diffPermutedResult(expected, actual, order, cmp)
+		if diff.size() < best.size() {
+			best = diff
+		}
+		return !best.Empty() && tried < maxSQLColumnPermutations
+	})
+	return best
+}
+
+// diffPermutedResult compares expected column i with actual column order[i]
+func diffPermutedResult(expected [][]string, actual *QueryResult, order []int, cmp SQLComparison) *SQLDiff {
+	permuted := &QueryResult{Columns: make([]string, len(order)), Rows: make([][]SQLValue, len(actual.Rows))}
+	for i, col := range order {
+		permuted.Columns[i] = actual.Columns[col]
+	}
+	for r, row := range actual.Rows {
+		permuted.Rows[r] = make([]SQLValue, len(order))
+		for i, col := range order {
+			permuted.Rows[r][i] = row[col]
+		}
+	}
+
+	diff := &SQLDiff{
+		ExpectedColumns: len(order),
+		ActualColumns:   len(order),
+		ColumnOrder:     append([]int(nil), order...),
+		ExpectedRows:    len(expected),
+		ActualRows:      len(actual.Rows),
+	}
+	if !cmp.IgnoreTypes {
+		diff.TypeMismatches = sqlTypeMismatches(expected, permuted)
+	}
+
+	// Match every expected row with the first unused actual row that equals it
+	matches := func(e []string, a []SQLValue) bool { return sqlRowMatches(e, a, cmp.Columns) }
+	used := make([]bool, len(permuted.Rows))
+	var missing []string
+	for _, row := range expected {
+		found := false
+		for i, candidate := range permuted.Rows {
+			if !used[i] && matches(row, candidate) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, formatExpectedSQLRow(row))
+		}
+	}
+
+	// Unmatched actual rows are duplicates when they equal some expected row
+	var unexpected []string
+	extra := make(map[int]int)
+	var duplicated []int
+	for i, row := range permuted.Rows {
+		if used[i] {
+			continue
+		}
+		j := indexOfSQLRow(expected, row, matches)
+		if j < 0 {
+			unexpected = append(unexpected, formatSQLRow(row))
+			continue
+		}
+		if extra[j] == 0 {
+			duplicated = append(duplicated, j)
+		}
+		extra[j]++
+	}
+
+	diff.Missing = groupSQLRows(missing)
+	diff.Unexpected = groupSQLRows(unexpected)
+	for _, j := range duplicated {
+		want := 0
+		for _, row := range expected {
+			if sqlRowKey(row) == sqlRowKey(expected[j]) {
+				want++
+			}
+		}
+		diff.Duplicates = append(diff.Duplicates, fmt.Sprintf("%s (appears %d times, expected %d)",
+			formatExpectedSQLRow(expected[j]), want+extra[j], want))
+	}
+
+	// Order only matters once the right rows are present; otherwise the first
+	// difference is just a consequence of missing or extra rows
+	if cmp.Ordered && len(diff.Missing) == 0 && len(diff.Unexpected) == 0 && len(diff.Duplicates) == 0 {
+		for i := range expected {
+			if !matches(expected[i], permuted.Rows[i]) {
+				diff.OutOfOrder = i + 1
+				diff.OutOfOrderDetail = fmt.Sprintf("expected %s, got %s",
+					formatExpectedSQLRow(expected[i]), formatSQLRow(permuted.Rows[i]))
+				break
+			}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the result matched
+func (d *SQLDiff) Empty() bool {
+	return d.ExpectedColumns == d.ActualColumns && d.size() == 0
+}
+
+// size is the number of differences, used to pick the closest column order
+func (d *SQLDiff) size() int {
+	n := len(d.TypeMismatches) + len(d.Missing) + len(d.Unexpected) + len(d.Duplicates)
+	if d.OutOfOrder > 0 {
+		n++
+	}
+	return n
+}
+
+// Error returns a readable description of the differences, or nil if the result matched
+func (d *SQLDiff) Error() error {
+	if d.Empty() {
+		return nil
+	}
+	if d.ExpectedColumns != d.ActualColumns {
+		return fmt.Errorf("expected %d column(s), got %d", d.ExpectedColumns, d.ActualColumns)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "result mismatch (expected %d rows, got %d rows)", d.ExpectedRows, d.ActualRows)
+	for _, m := range d.TypeMismatches {
+		b.WriteString("\n  " + m)
+	}
+	writeSQLDiffSection(&b, "missing rows", d.Missing)
+	writeSQLDiffSection(&b, "unexpected rows", d.Unexpected)
+	writeSQLDiffSection(&b, "duplicate rows", d.Duplicates)
+	if d.OutOfOrder > 0 {
+		fmt.Fprintf(&b, "\n  rows are in the wrong order, first at row %d: %s", d.OutOfOrder, d.OutOfOrderDetail)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+func writeSQLDiffSection(b *strings.Builder, title string, rows []string) {
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n  %s (%d):", title, len(rows))
+	for i, row := range rows {
+		if i == maxSQLDiffRows {
+			fmt.Fprintf(b, "\n    ... and %d more", len(rows)-maxSQLDiffRows)
+			break
+		}
+		b.WriteString("\n    " + row)
+	}
+}
+
+func expectedColumnCount(expected [][]string) int {
+	if len(expected) == 0 {
+		return 1
+	}
+	return len(expected[0])
+}
+
+// sqlRowMatches compares an expected row with an actual row column by column
+func sqlRowMatches(expected []string, actual []SQLValue, columns []SQLColumn) bool {
+	for i, e := range expected {
+		column := SQLColumn{}
+		if i < len(columns) {
+			column = columns[i]
+		}
+		if !sqlValueMatches(e, actual[i], column) {
+			return false
+		}
+	}
+	return true
+}
+
+func sqlValueMatches(expected string, actual SQLValue, column SQLColumn) bool {
+	if actual.Type == SQLNull {
+		return false
+	}
+	switch column.Mode {
+	case SQLCaseInsensitive:
+		return strings.EqualFold(expected, actual.Text)
+	case SQLNumeric:
+		e, err1 := strconv.ParseFloat(expected, 64)
+		a, err2 := strconv.ParseFloat(actual.Text, 64)
+		return err1 == nil && err2 == nil && math.Abs(a-e) <= column.Tolerance
+	}
+	if actual.IsNumeric() {
+		e, err1 := strconv.ParseFloat(expected, 64)
+		a, err2 := strconv.ParseFloat(actual.Text, 64)
+		if err1 == nil && err2 == nil {
+			return a == e
+		}
+	}
+	return expected == actual.Text
+}
+
+func indexOfSQLRow(rows [][]string, row []SQLValue, matches func([]string, []SQLValue) bool) int {
+	for i, r := range rows {
+		if matches(r, row) {
+			return i
+		}
+	}
+	return -1
+}
+
+// permuteColumns calls visit with every non-identity permutation of order until visit returns false
+func permuteColumns(order []int, visit func([]int) bool) {
+	p := append([]int(nil), order...)
+	var generate func(k int) bool
+	generate = func(k int) bool {
+		if k == len(p) {
+			for i := range p {
+				if p[i] != order[i] {
+					return visit(p)
+				}
+			}
+			return true
+		}
+		for i := k; i < len(p); i++ {
+			p[k], p[i] = p[i], p[k]
+			ok := generate(k + 1)
+			p[k], p[i] = p[i], p[k]
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	generate(0)
+}
+
+// groupSQLRows collapses repeated rows into one entry with a count, keeping the first occurrence order
+func groupSQLRows(rows []string) []string {
+	counts := make(map[string]int)
+	var order []string
+	for _, row := range rows {
+		if counts[row] == 0 {
+			order = append(order, row)
+		}
+		counts[row]++
+	}
+	grouped := make([]string, len(order))
+	for i, row := range order {
+		grouped[i] = withCount(row, counts[row])
+	}
+	return grouped
+}
+
+// sqlTypeMismatches checks each column whose expected values are all numbers, or all non-numbers
+func sqlTypeMismatches(expected [][]string, actual *QueryResult) []string {
+	var mismatches []string
+	for col := 0; col < expectedColumnCount(expected) && len(expected) > 0; col++ {
+		numbers := 0
+		for _, row := range expected {
+			if _, err := strconv.ParseFloat(row[col], 64); err == nil {
+				numbers++
+			}
+		}
+		var wantNumeric bool
+		switch numbers {
+		case len(expected):
+			wantNumeric = true
+		case 0:
+			wantNumeric = false
+		default:
+			continue
+		}
+
+		for i, row := range actual.Rows {
+			v := row[col]
+			if v.IsNumeric() == wantNumeric && v.Type != SQLNull {
+				continue
+			}
+			want := "TEXT"
+			if wantNumeric {
+				want = "INTEGER or REAL"
+			}
+			mismatches = append(mismatches, fmt.Sprintf("column %d (%s): expected %s values, got %s (first at row %d: %s)",
+				col+1, actual.Columns[col], want, v.Type, i+1, v))
+			break
+		}
+	}
+	return mismatches
+}
+
+func sqlRowKey(row []string) string {
+	return strings.Join(row, "\x1f")
+}
+
+func formatSQLRow(row []SQLValue) string {
+	if len(row) == 1 {
+		return row[0].String()
+	}
+	items := make([]string, len(row))
+	for i, v := range row {
+		items[i] = v.String()
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+func formatExpectedSQLRow(row []string) string {
+	values := make([]SQLValue, len(row))
+	for i, s := range row {
+		values[i] = SQLValue{Type: SQLText, Text: s}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			values[i].Type = SQLReal
+		}
+	}
+	return formatSQLRow(values)
+}
+
+func withCount(row string, n int) string {
+	if n == 1 {
+		return row
+	}
+	return fmt.Sprintf("%s (x%d)", row, n)
+}
+
+// SingleColumnRows converts a list of values into single-column expected rows
+func SingleColumnRows(values []string) [][]string {
+	rows := make([][]string, len(values))
+	for i, v := range values {
+		rows[i] = []string{v}
+	}
+	return rows
+}
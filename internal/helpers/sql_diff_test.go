@@ -0,0 +1,153 @@
+package helpers
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestSQLDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE movies (id INTEGER, title TEXT, year NUMERIC, rating REAL);
+		INSERT INTO movies VALUES
+			(1, 'Iron Man', 2008, 7.9),
+			(2, 'The Dark Knight', 2008, 9.0),
+			(3, 'Kung Fu Panda', 2008, 7.6),
+			(4, 'Toy Story 4', 2019, NULL);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func queryTestSQLDB(t *testing.T, db *sql.DB, query string) *QueryResult {
+	result, err := ExecuteQuery(db, query)
+	require.NoError(t, err)
+	return result
+}
+
+func TestExecuteQueryTypes(t *testing.T) {
+	db := openTestSQLDB(t)
+	result := queryTestSQLDB(t, db, "SELECT id, title, rating FROM movies WHERE id IN (1, 4) ORDER BY id")
+
+	assert.Equal(t, []string{"id", "title", "rating"}, result.Columns)
+	assert.Equal(t, []SQLValue{
+		{Type: SQLInteger, Text: "1", Value: int64(1)},
+		{Type: SQLText, Text: "Iron Man", Value: "Iron Man"},
+		{Type: SQLReal, Text: "7.9", Value: 7.9},
+	}, result.Rows[0])
+	assert.Equal(t, SQLValue{Type: SQLNull}, result.Rows[1][2])
+	assert.Equal(t, []string{"Iron Man", "Toy Story 4"}, result.Column(1))
+}
+
+func TestDiffQueryResultMatches(t *testing.T) {
+	db := openTestSQLDB(t)
+	expected := SingleColumnRows([]string{"Kung Fu Panda", "Iron Man", "The Dark Knight"})
+
+	result := queryTestSQLDB(t, db, "SELECT title FROM movies WHERE year = 2008")
+	assert.NoError(t, DiffQueryResult(expected, result, false).Error())
+
+	result = queryTestSQLDB(t, db, "SELECT title FROM movies WHERE year = 2008 ORDER BY rating")
+	assert.NoError(t, DiffQueryResult(expected, result, true).Error())
+}
+
+func TestDiffQueryResultRows(t *testing.T) {
+	db := openTestSQLDB(t)
+	expected := SingleColumnRows([]string{"Iron Man", "The Dark Knight", "Slumdog Millionaire"})
+
+	diff := DiffQueryResult(expected, queryTestSQLDB(t, db, "SELECT title FROM movies"), false)
+	assert.Equal(t, []string{`"Slumdog Millionaire"`}, diff.Missing)
+	assert.Equal(t, []string{`"Kung Fu Panda"`, `"Toy Story 4"`}, diff.Unexpected)
+	assert.Empty(t, diff.Duplicates)
+	assert.Contains(t, diff.Error().Error(), "expected 3 rows, got 4 rows")
+
+	diff = DiffQueryResult(SingleColumnRows([]string{"2008"}),
+		queryTestSQLDB(t, db, "SELECT year FROM movies WHERE year = 2008"), false)
+	assert.Equal(t, []string{"2008 (appears 3 times, expected 1)"}, diff.Duplicates)
+	assert.Empty(t, diff.Missing)
+	assert.Empty(t, diff.Unexpected)
+}
+
+func TestDiffQueryResultOrder(t *testing.T) {
+	db := openTestSQLDB(t)
+	expected := SingleColumnRows([]string{"Kung Fu Panda", "Iron Man", "The Dark Knight"})
+
+	diff := DiffQueryResult(expected, queryTestSQLDB(t, db, "SELECT title FROM movies WHERE year = 2008 ORDER BY title"), true)
+	assert.Equal(t, 1, diff.OutOfOrder)
+	assert.Contains(t, diff.Error().Error(), `first at row 1: expected "Kung Fu Panda", got "Iron Man"`)
+
+	// 缺少行时不报告顺序
+	diff = DiffQueryResult(expected, queryTestSQLDB(t, db, "SELECT title FROM movies WHERE id = 1"), true)
+	assert.Zero(t, diff.OutOfOrder)
+}
+
+func TestDiffQueryResultColumnsAndTypes(t *testing.T) {
+	db := openTestSQLDB(t)
+
+	diff := DiffQueryResult(SingleColumnRows([]string{"Iron Man"}),
+		queryTestSQLDB(t, db, "SELECT title, year FROM movies WHERE id = 1"), false)
+	assert.EqualError(t, diff.Error(), "expected 1 column(s), got 2")
+
+	diff = DiffQueryResult([][]string{{"Iron Man", "7.9"}},
+		queryTestSQLDB(t, db, "SELECT title, CAST(rating AS TEXT) FROM movies WHERE id = 1"), false)
+	assert.Len(t, diff.TypeMismatches, 1)
+	assert.Contains(t, diff.TypeMismatches[0], "column 2")
+	assert.Contains(t, diff.TypeMismatches[0], "expected INTEGER or REAL values, got TEXT")
+	assert.Empty(t, diff.Missing)
+}
+
+func TestDiffQueryResultTruncates(t *testing.T) {
+	expected := make([]string, 25)
+	for i := range expected {
+		expected[i] = string(rune('a' + i))
+	}
+	diff := DiffQueryResult(SingleColumnRows(expected), &QueryResult{Columns: []string{"name"}}, false)
+	assert.Len(t, diff.Missing, 25)
+	assert.Contains(t, diff.Error().Error(), "missing rows (25):")
+	assert.Contains(t, diff.Error().Error(), "... and 15 more")
+}
+
+func TestCompareQueryResultColumnModes(t *testing.T) {
+	db := openTestSQLDB(t)
+	result := queryTestSQLDB(t, db, "SELECT UPPER(title), year, rating / 3 FROM movies WHERE id = 1")
+
+	expected := [][]string{{"Iron Man", "2008.0", "2.63"}}
+	assert.False(t, CompareQueryResult(expected, result, SQLComparison{}).Empty())
+
+	cmp := SQLComparison{Columns: []SQLColumn{CaseInsensitiveColumn(), {}, NumericColumn(0.01)}}
+	assert.NoError(t, CompareQueryResult(expected, result, cmp).Error())
+
+	cmp.Columns[2] = NumericColumn(0.0001)
+	diff := CompareQueryResult(expected, result, cmp)
+	assert.Equal(t, []string{`("Iron Man", 2008.0, 2.63)`}, diff.Missing)
+	assert.Len(t, diff.Unexpected, 1)
+}
+
+func TestCompareQueryResultColumnOrder(t *testing.T) {
+	db := openTestSQLDB(t)
+	result := queryTestSQLDB(t, db, "SELECT rating, year, title FROM movies WHERE year = 2008 ORDER BY rating DESC")
+	expected := [][]string{
+		{"The Dark Knight", "2008", "9"},
+		{"Iron Man", "2008", "7.9"},
+		{"Kung Fu Panda", "2008", "7.6"},
+	}
+
+	assert.False(t, CompareQueryResult(expected, result, SQLComparison{Ordered: true}).Empty())
+
+	diff := CompareQueryResult(expected, result, SQLComparison{Ordered: true, AnyColumnOrder: true})
+	assert.NoError(t, diff.Error())
+	assert.Equal(t, []int{2, 1, 0}, diff.ColumnOrder)
+
+	// 列顺序任意时，报告最接近的列顺序下的差异
+	expected[2][0] = "Kung Fu Panda 2"
+	diff = CompareQueryResult(expected, result, SQLComparison{Ordered: true, AnyColumnOrder: true})
+	assert.Equal(t, []string{`("Kung Fu Panda 2", 2008, 7.6)`}, diff.Missing)
+	assert.Equal(t, []string{`("Kung Fu Panda", 2008, 7.6)`}, diff.Unexpected)
+}
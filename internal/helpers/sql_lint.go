@@ -0,0 +1,353 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQLTokenKind is the kind of a SQL token
+type SQLTokenKind int
+
+const (
+	// SQLWord is a keyword or identifier (quoted identifiers have Quoted set)
+	SQLWord SQLTokenKind = iota
+	SQLString
+	SQLNumber
+	SQLOperator
+	SQLSemicolon
+)
+
+// SQLToken is a token of SQLite SQL; comments and whitespace are skipped
+type SQLToken struct {
+	Kind   SQLTokenKind
+	Text   string
+	Quoted bool
+	Line   int
+	// Start and End are byte offsets of the token in the source
+	Start int
+	End   int
+}
+
+// SQLStatement is one statement of a SQL file, without its terminating semicolon
+type SQLStatement struct {
+	Text   string
+	Line   int
+	Tokens []SQLToken
+}
+
+// SQLIssue is a problem found in a SQL submission
+type SQLIssue struct {
+	Line    int
+	Message string
+}
+
+func (i SQLIssue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// sqlWriteKeywords are statements that could modify the database
+// (PRAGMA is checked separately: most pragmas only read, see sqlPragmaWrites)
+var sqlWriteKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "DROP": true, "ALTER": true,
+	"CREATE": true, "REPLACE": true, "ATTACH": true, "DETACH": true,
+	"VACUUM": true, "REINDEX": true,
+}
+
+// sqlSchemaPragmas take a table or index name as argument and only read the schema
+var sqlSchemaPragmas = map[string]bool{
+	"TABLE_INFO": true, "TABLE_XINFO": true, "TABLE_LIST": true, "INDEX_LIST": true,
+	"INDEX_INFO": true, "INDEX_XINFO": true, "FOREIGN_KEY_LIST": true,
+}
+
+// sqlComparisonOperators compare an id column with a value
+var sqlComparisonOperators = map[string]bool{"=": true, "==": true, "!=": true, "<>": true}
+
+// TokenizeSQL splits SQLite SQL into tokens
+func TokenizeSQL(src string) []SQLToken {
+	var tokens []SQLToken
+	line := 1
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		start, startLine := i, line
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i + 4
+			}
+			line += strings.Count(src[i:end], "\n")
+			i = end
+			continue
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			var text strings.Builder
+			i++
+			for i < len(src) {
+				if src[i] == closing {
+					// A doubled quote is an escaped quote
+					if closing != ']' && i+1 < len(src) && src[i+1] == closing {
+						text.WriteByte(closing)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				if src[i] == '\n' {
+					line++
+				}
+				text.WriteByte(src[i])
+				i++
+			}
+			kind := SQLWord
+			if c == '\'' {
+				kind = SQLString
+			}
+			tokens = append(tokens, SQLToken{Kind: kind, Text: text.String(), Quoted: kind == SQLWord, Line: startLine, Start: start, End: i})
+			continue
+		case isSQLDigit(c) || (c == '.' && i+1 < len(src) && isSQLDigit(src[i+1])):
+			i = scanSQLNumber(src, i)
+			tokens = append(tokens, SQLToken{Kind: SQLNumber, Text: src[start:i], Line: line, Start: start, End: i})
+			continue
+		case isSQLWordStart(c):
+			for i < len(src) && (isSQLWordStart(src[i]) || isSQLDigit(src[i]) || src[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, SQLToken{Kind: SQLWord, Text: src[start:i], Line: line, Start: start, End: i})
+			continue
+		case c == ';':
+			i++
+			tokens = append(tokens, SQLToken{Kind: SQLSemicolon, Text: ";", Line: line, Start: start, End: i})
+			continue
+		}
+
+		i++
+		if i < len(src) {
+			switch src[start : i+1] {
+			case "==", "!=", "<>", "<=", ">=", "||", "<<", ">>":
+				i++
+			}
+		}
+		tokens = append(tokens, SQLToken{Kind: SQLOperator, Text: src[start:i], Line: line, Start: start, End: i})
+	}
+	return tokens
+}
+
+func isSQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSQLWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// scanSQLNumber returns the end of the number starting at i (integer, decimal, exponent or hex)
+func scanSQLNumber(src string, i int) int {
+	if strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X") {
+		i += 2
+		for i < len(src) && strings.IndexByte("0123456789abcdefABCDEF", src[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	for i < len(src) && (isSQLDigit(src[i]) || src[i] == '.') {
+		i++
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && isSQLDigit(src[j]) {
+			i = j
+			for i < len(src) && isSQLDigit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// SplitSQLStatements splits SQL into statements at semicolons, skipping empty statements
+func SplitSQLStatements(src string) []SQLStatement {
+	var statements []SQLStatement
+	var current []SQLToken
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		first, last := current[0], current[len(current)-1]
+		statements = append(statements, SQLStatement{
+			Text:   src[first.Start:last.End],
+			Line:   first.Line,
+			Tokens: current,
+		})
+		current = nil
+	}
+	for _, token := range TokenizeSQL(src) {
+		if token.Kind == SQLSemicolon {
+			flush()
+			continue
+		}
+		current = append(current, token)
+	}
+	flush()
+	return statements
+}
+
+// LintSQLQuery checks a file that should contain a single read-only query:
+// it must not contain several statements or statements that modify the database,
+// and must not compare id columns with literal numbers (hard-coded ids)
+func LintSQLQuery(src string) []SQLIssue {
+	statements := SplitSQLStatements(src)
+	if len(statements) == 0 {
+		return []SQLIssue{{Line: 1, Message: "no SQL query found"}}
+	}
+
+	var issues []SQLIssue
+	if len(statements) > 1 {
+		issues = append(issues, SQLIssue{
+			Line:    statements[1].Line,
+			Message: fmt.Sprintf("found %d statements; each file should contain a single query", len(statements)),
+		})
+	}
+	for _, statement := range statements {
+		issues = append(issues, lintSQLStatement(statement)...)
+	}
+	return issues
+}
+
+func lintSQLStatement(statement SQLStatement) []SQLIssue {
+	var issues []SQLIssue
+	tokens := statement.Tokens
+
+	if issue, ok := SQLWriteIssue(statement); ok {
+		issues = append(issues, issue)
+	}
+
+	for i := range tokens {
+		if column, literal, ok := sqlLiteralIDComparison(tokens, i); ok {
+			issues = append(issues, SQLIssue{
+				Line: tokens[i].Line,
+				Message: fmt.Sprintf("compares %s with the literal %s; look ids up with a subquery or JOIN instead of hard-coding them",
+					column, literal),
+			})
+		}
+	}
+	return issues
+}
+
+// SQLWriteIssue returns an issue if the statement could modify the database
+func SQLWriteIssue(statement SQLStatement) (SQLIssue, bool) {
+	for i, token := range statement.Tokens {
+		keyword := strings.ToUpper(token.Text)
+		if token.Kind != SQLWord || token.Quoted {
+			continue
+		}
+		if keyword == "PRAGMA" && sqlPragmaWrites(statement.Tokens[i+1:]) {
+			return SQLIssue{
+				Line:    token.Line,
+				Message: "PRAGMA changing a setting is not allowed; queries must only read the database",
+			}, true
+		}
+		if !sqlWriteKeywords[keyword] {
+			continue
+		}
+		// REPLACE(...) is the string function
+		if keyword == "REPLACE" && i+1 < len(statement.Tokens) && statement.Tokens[i+1].Text == "(" {
+			continue
+		}
+		return SQLIssue{
+			Line:    token.Line,
+			Message: keyword + " is not allowed; queries must only read the database with SELECT",
+		}, true
+	}
+	return SQLIssue{}, false
+}
+
+// sqlPragmaWrites reports whether the PRAGMA followed by tokens sets a value, as in
+// "PRAGMA journal_mode = WAL" or "PRAGMA journal_mode(WAL)"; "PRAGMA foreign_keys"
+// and "PRAGMA table_info(people)" only read
+func sqlPragmaWrites(tokens []SQLToken) bool {
+	// Skip a schema name: PRAGMA main.table_info(people)
+	if len(tokens) >= 2 && tokens[1].Text == "." {
+		tokens = tokens[2:]
+	}
+	if len(tokens) < 2 {
+		return false
+	}
+	switch tokens[1].Text {
+	case "=":
+		return true
+	case "(":
+		return !sqlSchemaPragmas[strings.ToUpper(tokens[0].Text)]
+	}
+	return false
+}
+
+// hasSQLLimit reports whether src uses LIMIT anywhere (including in subqueries)
+func hasSQLLimit(src string) bool {
+	for _, token := range TokenizeSQL(src) {
+		if token.Kind == SQLWord && !token.Quoted && strings.EqualFold(token.Text, "LIMIT") {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlLiteralIDComparison detects "id = 42", "artist_id IN (1, 2)" and "42 = songs.artist_id" starting at tokens[i]
+func sqlLiteralIDComparison(tokens []SQLToken, i int) (column, literal string, ok bool) {
+	at := func(j int) SQLToken {
+		if j < len(tokens) {
+			return tokens[j]
+		}
+		return SQLToken{Kind: SQLSemicolon}
+	}
+
+	token := tokens[i]
+	if token.Kind == SQLWord && isSQLIDColumn(token.Text) && at(i+1).Text != "." {
+		next := at(i + 1)
+		if next.Kind == SQLOperator && sqlComparisonOperators[next.Text] && at(i+2).Kind == SQLNumber {
+			return token.Text, at(i + 2).Text, true
+		}
+		if next.Kind == SQLWord && strings.EqualFold(next.Text, "IN") && at(i+2).Text == "(" && at(i+3).Kind == SQLNumber {
+			return token.Text, at(i + 3).Text, true
+		}
+	}
+
+	if token.Kind == SQLNumber && sqlComparisonOperators[at(i+1).Text] {
+		// Literal on the left: 42 = artist_id or 42 = songs.artist_id
+		j := i + 2
+		if at(j).Kind == SQLWord && at(j+1).Text == "." {
+			j += 2
+		}
+		if at(j).Kind == SQLWord && isSQLIDColumn(at(j).Text) && at(j+1).Text != "." {
+			return at(j).Text, token.Text, true
+		}
+	}
+	return "", "", false
+}
+
+// isSQLIDColumn reports whether a column name looks like an id (id, rowid, artist_id, ...)
+func isSQLIDColumn(name string) bool {
+	name = strings.ToLower(name)
+	return name == "id" || name == "rowid" || strings.HasSuffix(name, "_id")
+}
@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	tokens := TokenizeSQL("SELECT name -- comment; not a statement\nFROM \"my table\" WHERE x >= 1.5e3 AND y = 'it''s';")
+
+	var texts []string
+	for _, token := range tokens {
+		texts = append(texts, token.Text)
+	}
+	assert.Equal(t, []string{"SELECT", "name", "FROM", "my table", "WHERE", "x", ">=", "1.5e3", "AND", "y", "=", "it's", ";"}, texts)
+	assert.True(t, tokens[3].Quoted)
+	assert.Equal(t, SQLString, tokens[11].Kind)
+	assert.Equal(t, 2, tokens[2].Line)
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	statements := SplitSQLStatements("SELECT 1;\n\n-- next\nSELECT ';' FROM t;\n;")
+	require.Len(t, statements, 2)
+	assert.Equal(t, "SELECT 1", statements[0].Text)
+	assert.Equal(t, "SELECT ';' FROM t", statements[1].Text)
+	assert.Equal(t, 4, statements[1].Line)
+}
+
+func TestHasSQLLimit(t *testing.T) {
+	assert.True(t, hasSQLLimit("SELECT title FROM movies ORDER BY rating DESC limit 5"))
+	assert.True(t, hasSQLLimit("SELECT name FROM people WHERE id IN (SELECT person_id FROM stars LIMIT 1)"))
+	assert.False(t, hasSQLLimit(`SELECT "limit", 'LIMIT' FROM songs -- LIMIT 5`))
+}
+
+func TestLintSQLQuery(t *testing.T) {
+	assert.Empty(t, LintSQLQuery("SELECT name FROM songs WHERE artist_id = (SELECT id FROM artists WHERE name = 'Post Malone');"))
+	assert.Empty(t, LintSQLQuery("SELECT REPLACE(name, 'a', 'b') FROM songs WHERE year = 2008"))
+
+	issues := LintSQLQuery("SELECT name FROM songs\nWHERE artist_id = 54;")
+	require.Len(t, issues, 1)
+	assert.Equal(t, 2, issues[0].Line)
+	assert.Contains(t, issues[0].String(), "compares artist_id with the literal 54")
+
+	assert.Len(t, LintSQLQuery("SELECT title FROM movies WHERE movies.id IN (1, 2)"), 1)
+	assert.Len(t, LintSQLQuery("SELECT title FROM movies, stars WHERE 42 = stars.person_id"), 1)
+
+	issues = LintSQLQuery("SELECT name FROM songs;\nDELETE FROM songs;")
+	require.Len(t, issues, 2)
+	assert.Contains(t, issues[0].Message, "found 2 statements")
+	assert.Contains(t, issues[1].Message, "DELETE is not allowed")
+
+	assert.Equal(t, []SQLIssue{{Line: 1, Message: "no SQL query found"}}, LintSQLQuery("-- TODO\n"))
+}
+
+func TestCreatePerturbedDatabase(t *testing.T) {
+	random.Init()
+
+	sandbox := newTestSQLSandbox(t, "songs.db", `
+		CREATE TABLE artists (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE songs (id INTEGER PRIMARY KEY, name TEXT, artist_id INTEGER);
+		INSERT INTO artists VALUES (1, 'Drake'), (2, 'Post Malone');
+		INSERT INTO songs VALUES (1, 'God''s Plan', 1), (2, 'Rockstar', 2), (3, 'Psycho', 2);
+	`)
+	perturbed, err := sandbox.Perturbed()
+	require.NoError(t, err)
+	defer perturbed.Close()
+
+	maxOld, err := sandbox.Query("SELECT MAX(id) FROM artists")
+	require.NoError(t, err)
+	minNew, err := perturbed.Query("SELECT MIN(id) FROM artists")
+	require.NoError(t, err)
+	assert.Greater(t, minNew.Rows[0][0].Value.(int64), maxOld.Rows[0][0].Value.(int64))
+
+	correct := "SELECT name FROM songs WHERE artist_id = (SELECT id FROM artists WHERE name = 'Post Malone')"
+	original, err := sandbox.Query(correct)
+	require.NoError(t, err)
+	assert.NoError(t, CompareWithPerturbed(original, perturbed, correct, time.Second))
+
+	hardCoded := "SELECT name FROM songs WHERE artist_id = 2"
+	original, err = sandbox.Query(hardCoded)
+	require.NoError(t, err)
+	assert.ErrorContains(t, CompareWithPerturbed(original, perturbed, hardCoded, time.Second), "hard-coded ids")
+}
+
+func TestSplitSQLScript(t *testing.T) {
+	statements := SplitSQLScript(".schema\n-- crime scene\nSELECT description FROM crime_scene_reports\n.tables\nWHERE day = 28;\n.tables\nSELECT name FROM People WHERE id = 1;\n")
+	require.Len(t, statements, 2)
+	assert.Equal(t, 3, statements[0].Line)
+	assert.Equal(t, 7, statements[1].Line)
+	assert.Contains(t, statements[0].Text, ".tables")
+	assert.Equal(t, []string{"people"}, statements[1].ReferencedTables([]string{"crime_scene_reports", "people"}))
+
+	_, ok := SQLWriteIssue(statements[1])
+	assert.False(t, ok)
+	issue, ok := SQLWriteIssue(SplitSQLScript("\nDROP TABLE people;")[0])
+	assert.True(t, ok)
+	assert.Equal(t, "line 2: DROP is not allowed; queries must only read the database with SELECT", issue.String())
+
+	// 只读的 PRAGMA 可以出现在日志中，修改设置的不可以
+	for _, pragma := range []string{"PRAGMA table_info(people)", "PRAGMA main.index_list('people')", "PRAGMA foreign_keys"} {
+		_, ok = SQLWriteIssue(SplitSQLStatements(pragma)[0])
+		assert.False(t, ok, pragma)
+	}
+	for _, pragma := range []string{"PRAGMA journal_mode = WAL", "PRAGMA main.user_version = 2", "PRAGMA foreign_keys(ON)"} {
+		_, ok = SQLWriteIssue(SplitSQLStatements(pragma)[0])
+		assert.True(t, ok, pragma)
+	}
+}
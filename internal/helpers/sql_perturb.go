@@ -0,0 +1,264 @@
+package helpers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bootcs-cn/tester-utils/random"
+)
+
+// sqlIDReference is a column that holds ids of another table
+type sqlIDReference struct {
+	table  string
+	column string
+	target string
+}
+
+// CreatePerturbedDatabase copies db to path and renumbers the id column of every table:
+// ids are shuffled and moved past the original range, and columns referencing them
+// (declared foreign keys, or columns named <table>_id) are updated to match.
+// A query that is correct returns the same rows on the copy; one that hard-codes ids does not
+func CreatePerturbedDatabase(db *sql.DB, path string) error {
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("could not copy database: %v", err)
+	}
+
+	copied, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("could not open database copy: %v", err)
+	}
+	defer copied.Close()
+	// Temporary tables live on one connection
+	copied.SetMaxOpenConns(1)
+
+	if _, err := copied.Exec("PRAGMA journal_mode = OFF; PRAGMA synchronous = OFF; PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("could not configure database copy: %v", err)
+	}
+
+	tables, err := sqliteTables(copied)
+	if err != nil {
+		return err
+	}
+	var idTables []string
+	for _, table := range tables {
+		columns, err := sqliteColumns(copied, table)
+		if err != nil {
+			return err
+		}
+		if containsString(columns, "id") {
+			idTables = append(idTables, table)
+		}
+	}
+	references, err := sqliteIDReferences(copied, tables, idTables)
+	if err != nil {
+		return err
+	}
+
+	tx, err := copied.Begin()
+	if err != nil {
+		return fmt.Errorf("could not perturb database: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range idTables {
+		if err := renumberSQLiteIDs(tx, table); err != nil {
+			return fmt.Errorf("could not renumber %s: %v", table, err)
+		}
+	}
+	for _, ref := range references {
+		_, err := tx.Exec(fmt.Sprintf(
+			`UPDATE %[1]s SET %[2]s = (SELECT new FROM %[3]s WHERE old = %[1]s.%[2]s) WHERE %[2]s IN (SELECT old FROM %[3]s)`,
+			quoteSQLIdentifier(ref.table), quoteSQLIdentifier(ref.column), perturbMapTable(ref.target)))
+		if err != nil {
+			return fmt.Errorf("could not update %s.%s: %v", ref.table, ref.column, err)
+		}
+	}
+	for _, table := range idTables {
+		_, err := tx.Exec(fmt.Sprintf(`UPDATE %[1]s SET id = (SELECT new FROM %[2]s WHERE old = %[1]s.id) WHERE id IN (SELECT old FROM %[2]s)`,
+			quoteSQLIdentifier(table), perturbMapTable(table)))
+		if err != nil {
+			return fmt.Errorf("could not update %s.id: %v", table, err)
+		}
+		if _, err := tx.Exec("DROP TABLE " + perturbMapTable(table)); err != nil {
+			return fmt.Errorf("could not update %s.id: %v", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not perturb database: %v", err)
+	}
+	return nil
+}
+
+// renumberSQLiteIDs creates a temporary old -> new id mapping for table
+func renumberSQLiteIDs(tx *sql.Tx, table string) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT DISTINCT id FROM %s WHERE typeof(id) = 'integer'", quoteSQLIdentifier(table)))
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	maxID := int64(0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+		if id > maxID {
+			maxID = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	mapping := perturbMapTable(table)
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TEMP TABLE %s (old INTEGER PRIMARY KEY, new INTEGER)", mapping)); err != nil {
+		return err
+	}
+	insert, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (old, new) VALUES (?, ?)", mapping))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	// New ids do not overlap the old ones, so updating rows one by one never collides
+	offset := maxID + int64(random.RandomInt(1000, 10000))
+	for i, id := range random.ShuffleArray(ids) {
+		if _, err := insert.Exec(id, offset+int64(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func perturbMapTable(table string) string {
+	return quoteSQLIdentifier("perturb_" + table)
+}
+
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func sqliteTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("could not list tables: %v", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func sqliteColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info(%s)", quoteSQLString(table)))
+	if err != nil {
+		return nil, fmt.Errorf("could not list columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, strings.ToLower(name))
+	}
+	return columns, rows.Err()
+}
+
+// sqliteIDReferences finds columns referencing the id of another table: declared
+// foreign keys, or by name (artist_id -> artists.id) for databases without them
+func sqliteIDReferences(db *sql.DB, tables, idTables []string) ([]sqlIDReference, error) {
+	var references []sqlIDReference
+	seen := make(map[string]bool)
+	add := func(ref sqlIDReference) {
+		key := ref.table + "." + ref.column
+		if !seen[key] && containsString(idTables, ref.target) {
+			seen[key] = true
+			references = append(references, ref)
+		}
+	}
+
+	for _, table := range tables {
+		rows, err := db.Query(fmt.Sprintf(`SELECT "table", "from", coalesce("to", '') FROM pragma_foreign_key_list(%s)`, quoteSQLString(table)))
+		if err != nil {
+			return nil, fmt.Errorf("could not list foreign keys of %s: %v", table, err)
+		}
+		for rows.Next() {
+			var target, from, to string
+			if err := rows.Scan(&target, &from, &to); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if to == "" || strings.EqualFold(to, "id") {
+				add(sqlIDReference{table: table, column: from, target: target})
+			}
+		}
+		rows.Close()
+
+		columns, err := sqliteColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			name := strings.TrimSuffix(column, "_id")
+			if name == column {
+				continue
+			}
+			for _, target := range []string{name, name + "s", name + "es"} {
+				if containsString(idTables, target) {
+					add(sqlIDReference{table: table, column: column, target: target})
+					break
+				}
+			}
+		}
+	}
+	return references, nil
+}
+
+func quoteSQLString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// CompareWithPerturbed runs query on the original and perturbed databases and reports
+// rows that differ (ignoring order)
+func CompareWithPerturbed(original *QueryResult, perturbed *SQLSandbox, query string, timeout time.Duration) error {
+	actual, err := perturbed.QueryWithin(query, timeout)
+	var timeoutErr *SQLTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("query fails after the ids in the database are changed: %v", err)
+	}
+	if len(original.Rows) == 0 {
+		if len(actual.Rows) > 0 {
+			return fmt.Errorf("query returns %d rows after the ids in the database are changed but none before, so it likely relies on hard-coded ids", len(actual.Rows))
+		}
+		return nil
+	}
+	expected := make([][]string, len(original.Rows))
+	for i, row := range original.Rows {
+		expected[i] = make([]string, len(row))
+		for j, v := range row {
+			expected[i][j] = v.Text
+		}
+	}
+	diff := CompareQueryResult(expected, actual, SQLComparison{IgnoreTypes: true})
+	if err := diff.Error(); err != nil {
+		return fmt.Errorf("query returns different rows after the ids in the database are changed, so it likely relies on hard-coded ids: %v", err)
+	}
+	return nil
+}
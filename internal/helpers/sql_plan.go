@@ -0,0 +1,234 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQLLargeTableRows is the row count above which a full scan inside a loop is reported
+const SQLLargeTableRows = 10000
+
+// sqlNotAliases are keywords that can follow a table name in FROM without being an alias
+var sqlNotAliases = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true,
+	"NATURAL": true, "OUTER": true, "ON": true, "USING": true, "GROUP": true, "ORDER": true, "LIMIT": true,
+	"HAVING": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "WINDOW": true, "INDEXED": true, "NOT": true,
+}
+
+// SQLPlanStep is one row of EXPLAIN QUERY PLAN
+type SQLPlanStep struct {
+	ID     int
+	Parent int
+	Detail string
+}
+
+// SQLPlan is the query plan chosen by SQLite
+type SQLPlan struct {
+	Steps []SQLPlanStep
+}
+
+// SQLQueryAnalysis is the plan of a query and the problems found in it
+type SQLQueryAnalysis struct {
+	Plan     *SQLPlan
+	Warnings []string
+}
+
+// String formats the plan as a tree, like the sqlite3 shell does
+func (p *SQLPlan) String() string {
+	var b strings.Builder
+	b.WriteString("QUERY PLAN")
+	p.writeChildren(&b, 0, "")
+	return b.String()
+}
+
+func (p *SQLPlan) writeChildren(b *strings.Builder, parent int, indent string) {
+	children := p.children(parent)
+	for i, step := range children {
+		branch, next := "|--", "|  "
+		if i == len(children)-1 {
+			branch, next = "`--", "   "
+		}
+		b.WriteString("\n" + indent + branch + step.Detail)
+		p.writeChildren(b, step.ID, indent+next)
+	}
+}
+
+func (p *SQLPlan) children(parent int) []SQLPlanStep {
+	var children []SQLPlanStep
+	for _, step := range p.Steps {
+		if step.Parent == parent {
+			children = append(children, step)
+		}
+	}
+	return children
+}
+
+func (p *SQLPlan) step(id int) (SQLPlanStep, bool) {
+	for _, step := range p.Steps {
+		if step.ID == id {
+			return step, true
+		}
+	}
+	return SQLPlanStep{}, false
+}
+
+// ExplainQueryPlan returns the plan SQLite chooses for query
+func (s *SQLSandbox) ExplainQueryPlan(query string) (*SQLPlan, error) {
+	db, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("EXPLAIN QUERY PLAN " + query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %v", err)
+	}
+	defer rows.Close()
+
+	plan := &SQLPlan{}
+	for rows.Next() {
+		var step SQLPlanStep
+		var unused int
+		if err := rows.Scan(&step.ID, &step.Parent, &unused, &step.Detail); err != nil {
+			return nil, fmt.Errorf("scan error: %v", err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, rows.Err()
+}
+
+// AnalyzeQuery explains query and warns about full scans of large tables that run
+// once per row of an outer loop (inner loops of joins and correlated subqueries)
+func (s *SQLSandbox) AnalyzeQuery(query string) (*SQLQueryAnalysis, error) {
+	plan, err := s.ExplainQueryPlan(query)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.tableRowCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	aliases := sqlTableAliases(TokenizeSQL(query), tables)
+
+	analysis := &SQLQueryAnalysis{Plan: plan}
+	for _, step := range plan.Steps {
+		name, ok := sqlScannedTable(step.Detail)
+		if !ok {
+			continue
+		}
+		table, ok := aliases[strings.ToLower(name)]
+		if !ok || counts[table] < SQLLargeTableRows {
+			continue
+		}
+		switch {
+		case plan.insideCorrelatedSubquery(step):
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+				"full scan of %s (%d rows) inside a correlated subquery runs once for every row of the outer query", table, counts[table]))
+		case plan.innerLoop(step):
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf(
+				"full scan of %s (%d rows) is the inner loop of a join, so it runs once for every row of the outer loop", table, counts[table]))
+		}
+	}
+	return analysis, nil
+}
+
+// sqlScannedTable returns the table or alias of a full table scan ("SCAN stars"),
+// ignoring scans that use an index
+func sqlScannedTable(detail string) (string, bool) {
+	if !strings.HasPrefix(detail, "SCAN ") || strings.Contains(detail, " INDEX") {
+		return "", false
+	}
+	fields := strings.Fields(strings.TrimPrefix(detail, "SCAN "))
+	if len(fields) == 0 || fields[0] == "CONSTANT" || fields[0] == "SUBQUERY" {
+		return "", false
+	}
+	if fields[0] == "TABLE" && len(fields) > 1 {
+		return fields[1], true
+	}
+	return fields[0], true
+}
+
+// insideCorrelatedSubquery reports whether step is nested in a correlated subquery
+func (p *SQLPlan) insideCorrelatedSubquery(step SQLPlanStep) bool {
+	for parent, ok := p.step(step.Parent); ok; parent, ok = p.step(parent.Parent) {
+		if strings.HasPrefix(parent.Detail, "CORRELATED") {
+			return true
+		}
+	}
+	return false
+}
+
+// innerLoop reports whether an earlier sibling of step is also a loop over a table
+func (p *SQLPlan) innerLoop(step SQLPlanStep) bool {
+	for _, sibling := range p.children(step.Parent) {
+		if sibling.ID == step.ID {
+			return false
+		}
+		if strings.HasPrefix(sibling.Detail, "SCAN ") || strings.HasPrefix(sibling.Detail, "SEARCH ") {
+			if !strings.HasPrefix(sibling.Detail, "SCAN CONSTANT") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sqlTableAliases maps table names and their aliases (lowercase) to table names
+func sqlTableAliases(tokens []SQLToken, tables []string) map[string]string {
+	aliases := make(map[string]string)
+	for _, table := range tables {
+		aliases[strings.ToLower(table)] = table
+	}
+	for i, token := range tokens {
+		if token.Kind != SQLWord {
+			continue
+		}
+		table, ok := aliases[strings.ToLower(token.Text)]
+		if !ok || i+1 >= len(tokens) {
+			continue
+		}
+		next := tokens[i+1]
+		if strings.EqualFold(next.Text, "AS") && i+2 < len(tokens) {
+			next = tokens[i+2]
+		} else if next.Kind != SQLWord || sqlNotAliases[strings.ToUpper(next.Text)] {
+			continue
+		}
+		if next.Kind == SQLWord {
+			aliases[strings.ToLower(next.Text)] = table
+		}
+	}
+	return aliases
+}
+
+// tableRowCounts counts the rows of every table once per sandbox
+func (s *SQLSandbox) tableRowCounts() (map[string]int64, error) {
+	if s.rowCounts != nil {
+		return s.rowCounts, nil
+	}
+	tables, err := s.Tables()
+	if err != nil {
+		return nil, err
+	}
+	db, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	counts := make(map[string]int64)
+	for _, table := range tables {
+		var n int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + quoteSQLIdentifier(table)).Scan(&n); err != nil {
+			return nil, fmt.Errorf("could not count rows of %s: %v", table, err)
+		}
+		counts[table] = n
+	}
+	s.rowCounts = counts
+	return counts, nil
+}
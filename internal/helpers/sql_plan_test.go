@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestPlanSandbox(t *testing.T) *SQLSandbox {
+	return newTestSQLSandbox(t, "movies.db", `
+		CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE stars (movie_id INTEGER, person_id INTEGER);
+		CREATE TABLE movies (id INTEGER PRIMARY KEY, title TEXT);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 20000)
+		INSERT INTO stars SELECT i % 100, i FROM n;
+		INSERT INTO movies VALUES (1, 'Toy Story');
+	`)
+}
+
+func TestAnalyzeQuery(t *testing.T) {
+	sandbox := openTestPlanSandbox(t)
+
+	analysis, err := sandbox.AnalyzeQuery("SELECT name FROM people WHERE id IN (SELECT person_id FROM stars WHERE movie_id = 1)")
+	require.NoError(t, err)
+	assert.Empty(t, analysis.Warnings)
+	assert.Contains(t, analysis.Plan.String(), "SCAN stars")
+
+	analysis, err = sandbox.AnalyzeQuery("SELECT title FROM movies m WHERE (SELECT COUNT(*) FROM stars s WHERE s.movie_id = m.id) > 1")
+	require.NoError(t, err)
+	require.Len(t, analysis.Warnings, 1)
+	assert.Contains(t, analysis.Warnings[0], "full scan of stars (20000 rows) inside a correlated subquery")
+	assert.Contains(t, analysis.Plan.String(), "QUERY PLAN\n|--SCAN m\n`--CORRELATED SCALAR SUBQUERY 1\n   `--SCAN s")
+
+	analysis, err = sandbox.AnalyzeQuery("SELECT COUNT(*) FROM stars a JOIN stars b ON a.person_id + 1 = b.person_id + 0")
+	require.NoError(t, err)
+	require.Len(t, analysis.Warnings, 1)
+	assert.Contains(t, analysis.Warnings[0], "is the inner loop of a join")
+}
+
+func TestQueryWithinReportsPlan(t *testing.T) {
+	sandbox := openTestPlanSandbox(t)
+
+	_, err := sandbox.QueryWithin("SELECT COUNT(*) FROM stars a, stars b, stars c", 100*time.Millisecond)
+	var timeout *SQLTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Contains(t, err.Error(), "query timed out after 100ms")
+	assert.Contains(t, err.Error(), "QUERY PLAN")
+	assert.Contains(t, err.Error(), "full scan of stars")
+}
@@ -0,0 +1,221 @@
+package helpers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// DefaultSQLQueryTimeout is how long a single student query may run
+	DefaultSQLQueryTimeout = 10 * time.Second
+	// DefaultSQLRowLimit is the most rows a single student query may return
+	DefaultSQLRowLimit = 100000
+)
+
+// SQLTimeoutError is returned when a query runs longer than its time budget;
+// it carries the query plan so the student can see why the query is slow
+type SQLTimeoutError struct {
+	Timeout  time.Duration
+	Analysis *SQLQueryAnalysis
+}
+
+func (e *SQLTimeoutError) Error() string {
+	message := fmt.Sprintf("query timed out after %v (it is too slow, or never finishes)", e.Timeout)
+	if e.Analysis == nil {
+		return message
+	}
+	for _, warning := range e.Analysis.Warnings {
+		message += "\n" + warning
+	}
+	return message + "\n" + e.Analysis.Plan.String()
+}
+
+// SQLRowLimitError is returned when a query returns more rows than the sandbox allows
+type SQLRowLimitError struct {
+	Limit int
+}
+
+func (e *SQLRowLimitError) Error() string {
+	return fmt.Sprintf("query returned more than %d rows", e.Limit)
+}
+
+// SQLSandbox runs student queries against a private, read-only copy of a database.
+// The copy is written from the pristine distribution bytes (or, when those are not
+// available, snapshotted from the submission), so queries never change the student's
+// file or affect each other, and every query opens its own connection with a timeout
+// and row limit
+type SQLSandbox struct {
+	Path     string
+	Timeout  time.Duration
+	RowLimit int
+
+	dir       string
+	rowCounts map[string]int64
+}
+
+// NewSQLSandbox writes the database contents data into a temporary directory as name;
+// the copy is read-only and opened with mode=ro&immutable=1
+func NewSQLSandbox(name string, data []byte) (*SQLSandbox, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", name)
+	}
+
+	sandbox, err := newSQLSandboxDir(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(sandbox.Path, data, 0444); err != nil {
+		sandbox.Close()
+		return nil, fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return sandbox, nil
+}
+
+// CopySQLSandbox snapshots the database at source (opened read-only) into a read-only
+// temporary copy; it is the fallback when the distribution database is not available
+func CopySQLSandbox(source string) (*SQLSandbox, error) {
+	name := filepath.Base(source)
+	if _, err := os.Stat(source); err != nil {
+		return nil, fmt.Errorf("%s does not exist", name)
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+
+	sandbox, err := newSQLSandboxDir(name)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", readOnlySQLiteDSN(source, false))
+	if err != nil {
+		sandbox.Close()
+		return nil, fmt.Errorf("failed to open %s: %v", name, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM INTO ?", sandbox.Path); err != nil {
+		sandbox.Close()
+		return nil, fmt.Errorf("failed to copy %s: %v", name, err)
+	}
+	if err := os.Chmod(sandbox.Path, 0444); err != nil {
+		sandbox.Close()
+		return nil, fmt.Errorf("failed to copy %s: %v", name, err)
+	}
+	return sandbox, nil
+}
+
+// newSQLSandboxDir creates the temporary directory a sandbox copy named name lives in
+func newSQLSandboxDir(name string) (*SQLSandbox, error) {
+	dir, err := os.MkdirTemp("", "sql-sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temp dir: %v", err)
+	}
+	return &SQLSandbox{
+		Path:     filepath.Join(dir, filepath.Base(name)),
+		Timeout:  DefaultSQLQueryTimeout,
+		RowLimit: DefaultSQLRowLimit,
+		dir:      dir,
+	}, nil
+}
+
+// Perturbed returns a sandbox over a copy of this database with its ids shuffled
+// (see CreatePerturbedDatabase)
+func (s *SQLSandbox) Perturbed() (*SQLSandbox, error) {
+	perturbed, err := newSQLSandboxDir(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	perturbed.Timeout = s.Timeout
+	perturbed.RowLimit = s.RowLimit
+
+	db, err := s.Open()
+	if err != nil {
+		perturbed.Close()
+		return nil, err
+	}
+	defer db.Close()
+	if err := CreatePerturbedDatabase(db, perturbed.Path); err != nil {
+		perturbed.Close()
+		return nil, err
+	}
+	return perturbed, nil
+}
+
+// Open opens a new read-only connection to the sandbox database
+func (s *SQLSandbox) Open() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", readOnlySQLiteDSN(s.Path, true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", filepath.Base(s.Path), err)
+	}
+	return db, nil
+}
+
+// Query runs query on its own connection; it fails with *SQLTimeoutError if it runs
+// longer than Timeout and with *SQLRowLimitError if it returns more than RowLimit rows
+func (s *SQLSandbox) Query(query string) (*QueryResult, error) {
+	return s.QueryWithin(query, s.Timeout)
+}
+
+// QueryWithin is Query with a different time budget
+func (s *SQLSandbox) QueryWithin(query string, timeout time.Duration) (*QueryResult, error) {
+	db, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result, err := ExecuteQueryContext(ctx, db, query, s.RowLimit)
+	if ctx.Err() == context.DeadlineExceeded {
+		timeoutErr := &SQLTimeoutError{Timeout: timeout}
+		if analysis, err := s.AnalyzeQuery(query); err == nil {
+			timeoutErr.Analysis = analysis
+		}
+		return nil, timeoutErr
+	}
+	return result, err
+}
+
+// Tables returns the names of the tables in the database
+func (s *SQLSandbox) Tables() ([]string, error) {
+	db, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return sqliteTables(db)
+}
+
+// Close deletes the sandbox copy
+func (s *SQLSandbox) Close() {
+	os.RemoveAll(s.dir)
+}
+
+// OpenSQLiteReadOnly opens a database read-only, e.g. to inspect what an app has written
+func OpenSQLiteReadOnly(path string) (*sql.DB, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	db, err := sql.Open("sqlite3", readOnlySQLiteDSN(path, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", filepath.Base(path), err)
+	}
+	return db, nil
+}
+
+// readOnlySQLiteDSN returns a URI opening path read-only; immutable also skips locking,
+// which is only safe for the sandbox's private copy
+func readOnlySQLiteDSN(path string, immutable bool) string {
+	query := "mode=ro"
+	if immutable {
+		query += "&immutable=1"
+	}
+	return (&url.URL{Scheme: "file", Path: path, RawQuery: query}).String()
+}
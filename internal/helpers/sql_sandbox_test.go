@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSQLSandbox 用 script 建一个数据库，以它的内容创建沙箱
+func newTestSQLSandbox(t *testing.T, name, script string) *SQLSandbox {
+	source := filepath.Join(t.TempDir(), name)
+	db, err := sql.Open("sqlite3", source)
+	require.NoError(t, err)
+	_, err = db.Exec(script)
+	require.NoError(t, err)
+	db.Close()
+	data, err := os.ReadFile(source)
+	require.NoError(t, err)
+
+	sandbox, err := NewSQLSandbox(name, data)
+	require.NoError(t, err)
+	t.Cleanup(sandbox.Close)
+	return sandbox
+}
+
+func TestSQLSandbox(t *testing.T) {
+	sandbox := newTestSQLSandbox(t, "songs.db",
+		"CREATE TABLE songs (id INTEGER, name TEXT); INSERT INTO songs VALUES (1, 'a'), (2, 'b'), (3, 'c');")
+
+	info, err := os.Stat(sandbox.Path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	result, err := sandbox.Query("SELECT name FROM songs ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, result.Column(0))
+
+	// 写入被拒绝，沙箱副本不变
+	_, err = sandbox.Query("DELETE FROM songs")
+	assert.Error(t, err)
+	result, err = sandbox.Query("SELECT COUNT(*) FROM songs")
+	require.NoError(t, err)
+	assert.Equal(t, "3", result.Rows[0][0].Text)
+
+	sandbox.RowLimit = 2
+	_, err = sandbox.Query("SELECT name FROM songs")
+	assert.IsType(t, &SQLRowLimitError{}, err)
+
+	sandbox.Timeout = 200 * time.Millisecond
+	_, err = sandbox.Query("WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c")
+	assert.IsType(t, &SQLTimeoutError{}, err)
+
+	_, err = NewSQLSandbox("missing.db", nil)
+	assert.EqualError(t, err, "missing.db is empty")
+}
+
+func TestCopySQLSandbox(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "songs.db")
+	db, err := sql.Open("sqlite3", source)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE songs (id INTEGER, name TEXT); INSERT INTO songs VALUES (1, 'a'), (2, 'b');")
+	require.NoError(t, err)
+	db.Close()
+
+	sandbox, err := CopySQLSandbox(source)
+	require.NoError(t, err)
+	t.Cleanup(sandbox.Close)
+	assert.NotEqual(t, source, sandbox.Path)
+
+	info, err := os.Stat(sandbox.Path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	result, err := sandbox.Query("SELECT name FROM songs ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, result.Column(0))
+
+	_, err = CopySQLSandbox(filepath.Join(t.TempDir(), "missing.db"))
+	assert.EqualError(t, err, "missing.db does not exist")
+}
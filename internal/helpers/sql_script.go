@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// SplitSQLScript splits a sqlite3 script (such as a log of queries) into statements,
+// skipping sqlite3 dot-commands like .schema or .tables between statements
+func SplitSQLScript(src string) []SQLStatement {
+	lines := strings.SplitAfter(src, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), ".") && !sqlStatementOpen(b.String()) {
+			// Keep the newline so statement line numbers are unchanged
+			if strings.HasSuffix(line, "\n") {
+				b.WriteString("\n")
+			}
+			continue
+		}
+		b.WriteString(line)
+	}
+	return SplitSQLStatements(b.String())
+}
+
+// sqlStatementOpen reports whether src ends inside an unterminated statement
+func sqlStatementOpen(src string) bool {
+	tokens := TokenizeSQL(src)
+	return len(tokens) > 0 && tokens[len(tokens)-1].Kind != SQLSemicolon
+}
+
+// ReferencedTables returns which of tables the statement mentions (compared case-insensitively)
+func (s SQLStatement) ReferencedTables(tables []string) []string {
+	var referenced []string
+	for _, table := range tables {
+		for _, token := range s.Tokens {
+			if token.Kind == SQLWord && strings.EqualFold(token.Text, table) {
+				referenced = append(referenced, table)
+				break
+			}
+		}
+	}
+	return referenced
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import "fmt"
+
+// chiSquareCritical 是显著性水平 α = 0.0001 时卡方分布的临界值 (下标为自由度)
+// 取很小的 α，避免正确的随机实现因偶然波动而失败
+var chiSquareCritical = []float64{0, 15.137, 18.421, 21.108, 23.513, 25.745}
+
+// ChiSquareStatistic 计算卡方统计量 Σ (O - E)² / E
+func ChiSquareStatistic(observed []int, expected []float64) float64 {
+	stat := 0.0
+	for i, o := range observed {
+		if expected[i] == 0 {
+			continue
+		}
+		d := float64(o) - expected[i]
+		stat += d * d / expected[i]
+	}
+	return stat
+}
+
+// ChiSquareUniform 检验观测值是否服从均匀分布
+// 返回卡方统计量；当统计量超过临界值 (α = 0.0001) 时返回错误
+func ChiSquareUniform(observed []int) (float64, error) {
+	df := len(observed) - 1
+	if df < 1 || df >= len(chiSquareCritical) {
+		return 0, fmt.Errorf("unsupported number of categories: %d", len(observed))
+	}
+
+	total := 0
+	for _, o := range observed {
+		total += o
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("no observations")
+	}
+
+	expected := make([]float64, len(observed))
+	for i := range expected {
+		expected[i] = float64(total) / float64(len(observed))
+	}
+
+	stat := ChiSquareStatistic(observed, expected)
+	if stat > chiSquareCritical[df] {
+		return stat, fmt.Errorf("chi-square %.2f exceeds critical value %.2f (df=%d)", stat, chiSquareCritical[df], df)
+	}
+	return stat, nil
+}
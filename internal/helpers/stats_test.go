@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChiSquareStatistic(t *testing.T) {
+	assert.InDelta(t, 0.0, ChiSquareStatistic([]int{50, 50}, []float64{50, 50}), 1e-9)
+	assert.InDelta(t, 4.0, ChiSquareStatistic([]int{60, 40}, []float64{50, 50}), 1e-9)
+}
+
+func TestChiSquareUniform(t *testing.T) {
+	tests := []struct {
+		observed []int
+		wantErr  bool
+	}{
+		{[]int{5012, 4988}, false},
+		{[]int{10000, 0}, true},
+		{[]int{3350, 3310, 3340}, false},
+		{[]int{5000, 5000, 0}, true},
+		{[]int{0, 0}, true},
+		{[]int{7}, true},
+	}
+
+	for _, tc := range tests {
+		_, err := ChiSquareUniform(tc.observed)
+		if tc.wantErr {
+			assert.Error(t, err, "observed=%v", tc.observed)
+		} else {
+			assert.NoError(t, err, "observed=%v", tc.observed)
+		}
+	}
+}
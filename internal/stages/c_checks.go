@@ -0,0 +1,37 @@
+package stages
+
+import (
+	"fmt"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+)
+
+// runCFunctionChecks 为 source 生成并编译函数级测试驱动程序，然后依次运行每个检查
+func runCFunctionChecks(logger *logger.Logger, workDir, source string, checks []helpers.CFunctionCheck, flags ...string) error {
+	logger.Infof("Compiling test driver...")
+	tester := helpers.NewCFunctionTester(workDir, source, flags...)
+	if err := tester.Build(checks); err != nil {
+		return err
+	}
+	defer tester.Cleanup()
+	logger.Successf("test driver compiles")
+
+	for i, check := range checks {
+		logger.Infof("Testing %s...", check.Name)
+		if err := tester.Run(i).Error(); err != nil {
+			return fmt.Errorf("test failed for %s: %v", check.Name, err)
+		}
+		logger.Successf("✓ %s", check.Name)
+	}
+	return nil
+}
+
+// cSteps 把几组检查步骤按顺序连接起来
+func cSteps(groups ...[]helpers.CStep) []helpers.CStep {
+	var steps []helpers.CStep
+	for _, group := range groups {
+		steps = append(steps, group...)
+	}
+	return steps
+}
@@ -2,8 +2,12 @@ package stages
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
 	"github.com/bootcs-cn/tester-utils/runner"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
@@ -28,6 +32,16 @@ func testDna(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("dna.py exists")
 
+	// 找到并检查 Python 解释器 (.venv、BCS_PYTHON 或系统 python3)
+	env, err := resolvePython(harness)
+	if err != nil {
+		return err
+	}
+	python, err := env.Command(workDir)
+	if err != nil {
+		return err
+	}
+
 	// 2. 测试用例 (对齐 CS50 check50 的 test1-test20)
 	tests := []struct {
 		database string
@@ -62,7 +76,7 @@ func testDna(harness *test_case_harness.TestCaseHarness) error {
 	for _, tc := range tests {
 		logger.Infof("Testing %s...", tc.name)
 
-		r := runner.Run(workDir, "python3", "dna.py", tc.database, tc.sequence).
+		r := runner.Run(workDir, python, "dna.py", tc.database, tc.sequence).
 			WithTimeout(5 * time.Second).
 			Execute().
 			Stdout(tc.expected).
@@ -75,6 +89,95 @@ func testDna(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ %s", tc.name)
 	}
 
+	// 3. 随机生成的数据库和序列，期望结果由参考实现决定
+	tmpDir, err := os.MkdirTemp("", "dna-*")
+	if err != nil {
+		return fmt.Errorf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	generatedTests := []struct {
+		kind helpers.DNACaseKind
+		name string
+	}{
+		{helpers.DNAPlanted, "correctly identifies a generated sequence"},
+		{helpers.DNAPlanted, "correctly identifies another generated sequence"},
+		{helpers.DNANearMiss, "ignores STR runs broken by a single base"},
+		{helpers.DNAOverlap, "handles STRs whose runs overlap"},
+		{helpers.DNAUnmatched, "reports no match for a sequence one STR away from a person"},
+	}
+
+	for i, tc := range generatedTests {
+		logger.Infof("Testing %s...", tc.name)
+
+		c, err := helpers.GenerateDNACase(tc.kind)
+		if err != nil {
+			return err
+		}
+		databasePath := filepath.Join(tmpDir, fmt.Sprintf("database%d.csv", i))
+		sequencePath := filepath.Join(tmpDir, fmt.Sprintf("sequence%d.txt", i))
+		if err := os.WriteFile(databasePath, []byte(c.Database.CSV()), 0644); err != nil {
+			return fmt.Errorf("could not write database: %v", err)
+		}
+		if err := os.WriteFile(sequencePath, []byte(c.Sequence), 0644); err != nil {
+			return fmt.Errorf("could not write sequence: %v", err)
+		}
+
+		r := runner.Run(workDir, python, "dna.py", databasePath, sequencePath).
+			WithTimeout(5 * time.Second).
+			Execute().
+			Exit(0)
+		if err := r.Error(); err != nil {
+			return fmt.Errorf("%s: %v\n%s", tc.name, err, c.Describe())
+		}
+		if actual := strings.TrimSpace(r.GetStdout()); actual != c.Expected {
+			return fmt.Errorf("%s: expected %q, got %q\n%s", tc.name, c.Expected, actual, c.Describe())
+		}
+
+		logger.Successf("✓ %s", tc.name)
+	}
+
+	// 4. 直接调用 longest_match 函数 (导入 dna.py 时不会运行 __main__ 下的代码)
+	longestMatchTests := []struct {
+		sequence    string
+		subsequence string
+		expected    int
+		name        string
+	}{
+		{"AGATCAGATCAGATC", "AGATC", 3, "longest_match counts consecutive repeats"},
+		{"TTAGATCTTAGATCAGATCTT", "AGATC", 2, "longest_match returns the longest run rather than the total"},
+		{"TTTTTTCT", "AGATC", 0, "longest_match returns 0 when there is no match"},
+		{"GGAATGAATGAATG", "AATG", 3, "longest_match finds a run at the end of the sequence"},
+		{"AAAAAAA", "AA", 3, "longest_match handles runs that overlap themselves"},
+	}
+
+	calls := make([]helpers.PythonCall, len(longestMatchTests))
+	for i, tc := range longestMatchTests {
+		calls[i] = helpers.PythonCall{Function: "longest_match", Args: []interface{}{tc.sequence, tc.subsequence}}
+	}
+	result, err := helpers.CallPythonFunctions(env.Path, workDir, "dna.py", calls, 10*time.Second)
+	if err != nil {
+		return err
+	}
+
+	for i, tc := range longestMatchTests {
+		logger.Infof("Testing %s...", tc.name)
+
+		var actual int
+		if err := result.Calls[i].Decode(&actual); err != nil {
+			if result.ImportError != nil {
+				return fmt.Errorf("%s: %v (importing dna.py raised %v)", tc.name, err, result.ImportError)
+			}
+			return fmt.Errorf("%s: %v", tc.name, err)
+		}
+		if actual != tc.expected {
+			return fmt.Errorf("%s: longest_match(%q, %q) returned %d, expected %d",
+				tc.name, tc.sequence, tc.subsequence, actual, tc.expected)
+		}
+
+		logger.Successf("✓ %s", tc.name)
+	}
+
 	logger.Successf("All tests passed!")
 	return nil
 }
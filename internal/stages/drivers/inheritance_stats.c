@@ -0,0 +1,105 @@
+// inheritance 统计测试驱动，放在学生代码 (main 已重命名) 之后
+// 用法: ./inheritance_stats <families> <seed>
+
+#undef malloc
+#undef calloc
+#undef free
+
+static long stats_nodes = 0;
+static long stats_invalid = 0;
+static long stats_picks[2][2] = {{0, 0}, {0, 0}};
+static long stats_base[3] = {0, 0, 0};
+
+static int stats_allele_index(char allele)
+{
+    switch (allele)
+    {
+        case 'A':
+            return 0;
+        case 'B':
+            return 1;
+        case 'O':
+            return 2;
+        default:
+            return -1;
+    }
+}
+
+// 遍历家族树，统计每个孩子从父母处继承的是第几个等位基因
+static void stats_walk(person *p, int generations)
+{
+    if (p == NULL)
+    {
+        stats_invalid++;
+        return;
+    }
+    stats_nodes++;
+
+    if (generations > 1)
+    {
+        for (int i = 0; i < 2; i++)
+        {
+            person *parent = p->parents[i];
+            if (parent == NULL)
+            {
+                stats_invalid++;
+                continue;
+            }
+            if (p->alleles[i] != parent->alleles[0] && p->alleles[i] != parent->alleles[1])
+            {
+                stats_invalid++;
+            }
+            else if (parent->alleles[0] != parent->alleles[1])
+            {
+                stats_picks[i][p->alleles[i] == parent->alleles[0] ? 0 : 1]++;
+            }
+            stats_walk(parent, generations - 1);
+        }
+        return;
+    }
+
+    if (p->parents[0] != NULL || p->parents[1] != NULL)
+    {
+        stats_invalid++;
+    }
+    for (int i = 0; i < 2; i++)
+    {
+        int index = stats_allele_index(p->alleles[i]);
+        if (index < 0)
+        {
+            stats_invalid++;
+        }
+        else
+        {
+            stats_base[index]++;
+        }
+    }
+}
+
+int main(int argc, char *argv[])
+{
+    if (argc != 3)
+    {
+        fprintf(stderr, "Usage: ./inheritance_stats families seed\n");
+        return 2;
+    }
+    int families = atoi(argv[1]);
+    srand((unsigned int) atoi(argv[2]));
+
+    for (int i = 0; i < families; i++)
+    {
+        person *p = create_family(GENERATIONS);
+        stats_walk(p, GENERATIONS);
+        free_family(p);
+    }
+
+    printf("families %d\n", families);
+    printf("nodes %ld\n", stats_nodes);
+    printf("invalid %ld\n", stats_invalid);
+    printf("picks0 %ld %ld\n", stats_picks[0][0], stats_picks[0][1]);
+    printf("picks1 %ld %ld\n", stats_picks[1][0], stats_picks[1][1]);
+    printf("base %ld %ld %ld\n", stats_base[0], stats_base[1], stats_base[2]);
+    printf("mallocs %ld\n", stats_mallocs);
+    printf("frees %ld\n", stats_frees);
+    return 0;
+}
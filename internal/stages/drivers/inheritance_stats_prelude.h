@@ -0,0 +1,43 @@
+// inheritance 统计测试的前置代码，放在学生代码之前
+// 通过宏替换 malloc/calloc/free 来统计内存分配与释放次数 (代替 valgrind)
+
+#include <stdbool.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <time.h>
+
+static long stats_mallocs = 0;
+static long stats_frees = 0;
+
+static void *stats_malloc(size_t size)
+{
+    void *p = malloc(size);
+    if (p != NULL)
+    {
+        stats_mallocs++;
+    }
+    return p;
+}
+
+static void *stats_calloc(size_t count, size_t size)
+{
+    void *p = calloc(count, size);
+    if (p != NULL)
+    {
+        stats_mallocs++;
+    }
+    return p;
+}
+
+static void stats_free(void *p)
+{
+    if (p != NULL)
+    {
+        stats_frees++;
+    }
+    free(p);
+}
+
+#define malloc(size) stats_malloc(size)
+#define calloc(count, size) stats_calloc(count, size)
+#define free(p) stats_free(p)
@@ -0,0 +1,55 @@
+package stages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+	"github.com/bootcs-cn/tester-utils/runner"
+)
+
+// electionGenerateAttempts 是生成满足条件的随机选举时的最大尝试次数
+const electionGenerateAttempts = 1000
+
+// electionSimulation 是一次端到端的选举模拟
+type electionSimulation struct {
+	name     string
+	election helpers.Election
+	expected []string
+}
+
+// runElectionSimulations 像用户一样运行编译好的程序 (命令行传入候选人，标准输入投票)
+// 并将打印的获胜者与参考实现比较；失败时输出生成的选举
+func runElectionSimulations(logger *logger.Logger, workDir, binary string, sims []electionSimulation) error {
+	for _, sim := range sims {
+		logger.Infof("Testing %s...", sim.name)
+
+		r := runner.Run(workDir, binary, sim.election.Args()...).
+			WithTimeout(10 * time.Second).
+			Stdin(sim.election.Input()).
+			Exit(0)
+		if err := r.Error(); err != nil {
+			return fmt.Errorf("%s: %v\n%s", sim.name, err, sim.election)
+		}
+
+		actual := helpers.ParseElectionWinners(r.GetStdout())
+		if !winnersMatch(sim.expected, actual) {
+			return fmt.Errorf("%s: expected winners %v, got %v\n%s", sim.name, sim.expected, actual, sim.election)
+		}
+
+		logger.Successf("✓ %s", sim.name)
+	}
+	return nil
+}
+
+// generateElection 反复调用 generate，直到 accept 返回 true
+func generateElection(generate func() helpers.Election, accept func(helpers.Election) bool) (helpers.Election, error) {
+	for i := 0; i < electionGenerateAttempts; i++ {
+		e := generate()
+		if accept(e) {
+			return e, nil
+		}
+	}
+	return helpers.Election{}, fmt.Errorf("could not generate a suitable election after %d attempts", electionGenerateAttempts)
+}
@@ -8,10 +8,25 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
 
+// fiftyvilleMaxProblems 是最多报告的出错或写入的查询数
+const fiftyvilleMaxProblems = 10
+
+// fiftyvilleQueryBudget 是 log.sql 中每个查询的时间预算 (fiftyville.db 很小，正常的查询都在毫秒级)
+const fiftyvilleQueryBudget = 2 * time.Second
+
+// fiftyvilleEvidenceTables 是证据链上的表 (犯罪报告 -> 证人 -> 面包店、ATM、电话、航班)
+var fiftyvilleEvidenceTables = []string{
+	"crime_scene_reports", "interviews", "bakery_security_logs", "atm_transactions", "phone_calls", "flights",
+}
+
+// fiftyvilleAnswerTables 是从证据得出小偷、城市和同伙所需的表
+var fiftyvilleAnswerTables = []string{"people", "airports", "bank_accounts", "passengers"}
+
 func fiftyvilleTestCase() tester_definition.TestCase {
 	return tester_definition.TestCase{
 		Slug:     "fiftyville",
@@ -24,7 +39,7 @@ func testFiftyville(harness *test_case_harness.TestCaseHarness) error {
 	logger := harness.Logger
 	workDir := harness.SubmissionDir
 
-	// 1. 检查 log.sql 和 answers.txt 存在
+	// 1. 检查 log.sql 和 answers.txt 存在 (tester 内置了 fiftyville.db 时不需要提交数据库)
 	logger.Infof("Checking log.sql and answers.txt exist...")
 	if !harness.FileExists("log.sql") {
 		return fmt.Errorf("log.sql does not exist")
@@ -34,19 +49,70 @@ func testFiftyville(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("log.sql and answers.txt exist")
 
-	// 2. 检查 log.sql 包含 SELECT 查询
+	// 2. 解析 log.sql 中的查询 (跳过 .schema 等 sqlite3 命令)
 	logger.Infof("Checking log file contains SELECT queries...")
 	logContent, err := os.ReadFile(filepath.Join(workDir, "log.sql"))
 	if err != nil {
 		return fmt.Errorf("failed to read log.sql: %v", err)
 	}
-	logLower := strings.ToLower(string(logContent))
-	if !strings.Contains(logLower, "select") {
+	statements := helpers.SplitSQLScript(string(logContent))
+	if len(statements) == 0 {
 		return fmt.Errorf("missing SELECT queries in log.sql")
 	}
-	logger.Successf("log file contains SELECT queries")
+	logger.Successf("log file contains %d queries", len(statements))
+
+	// 3. 在 fiftyville.db 的只读沙箱中执行每个查询
+	// 调查中写错的查询很常见，只作为警告报告；修改数据库的语句才算失败
+	logger.Infof("Checking queries in log.sql only read the database...")
+	sandbox, err := openDistributionDatabase(logger, workDir, "fiftyville.db")
+	if err != nil {
+		return err
+	}
+	defer sandbox.Close()
+	tables, err := sandbox.Tables()
+	if err != nil {
+		return err
+	}
+
+	var writes, failures []string
+	queried := make(map[string]bool)
+	for _, statement := range statements {
+		if issue, ok := helpers.SQLWriteIssue(statement); ok {
+			writes = append(writes, issue.String())
+			continue
+		}
+		if _, err := sandbox.QueryWithin(statement.Text, fiftyvilleQueryBudget); err != nil {
+			failures = append(failures, fmt.Sprintf("line %d: %v", statement.Line, err))
+			continue
+		}
+		if analysis, err := sandbox.AnalyzeQuery(statement.Text); err == nil {
+			for _, warning := range analysis.Warnings {
+				logger.Infof("  warning (line %d): %s", statement.Line, warning)
+			}
+		}
+		for _, table := range statement.ReferencedTables(tables) {
+			queried[strings.ToLower(table)] = true
+		}
+	}
+	if len(writes) > 0 {
+		return fmt.Errorf("log.sql modifies the database:\n  %s", strings.Join(limitProblems(writes), "\n  "))
+	}
+	for _, failure := range limitProblems(failures) {
+		logger.Infof("  warning: query fails, %s", failure)
+	}
+	logger.Successf("%d of %d queries in log.sql run", len(statements)-len(failures), len(statements))
+
+	// 4. 检查日志查询了证据链上的表，以及得出答案所需的表
+	logger.Infof("Checking log.sql follows the evidence...")
+	if missing := missingTables(queried, fiftyvilleEvidenceTables); len(missing) > 0 {
+		return fmt.Errorf("log.sql never queries %s, which hold evidence about the theft", strings.Join(missing, ", "))
+	}
+	if missing := missingTables(queried, fiftyvilleAnswerTables); len(missing) > 0 {
+		return fmt.Errorf("log.sql never queries %s, which are needed to identify the thief, city and accomplice", strings.Join(missing, ", "))
+	}
+	logger.Successf("log.sql follows the evidence")
 
-	// 3. 检查谜题是否解决
+	// 5. 检查谜题是否解决
 	logger.Infof("Checking mystery solved...")
 	answersContent, err := os.ReadFile(filepath.Join(workDir, "answers.txt"))
 	if err != nil {
@@ -88,3 +154,23 @@ func testFiftyville(harness *test_case_harness.TestCaseHarness) error {
 	logger.Successf("All tests passed!")
 	return nil
 }
+
+// limitProblems 最多保留 fiftyvilleMaxProblems 个问题，其余的合并成一行
+func limitProblems(problems []string) []string {
+	if len(problems) <= fiftyvilleMaxProblems {
+		return problems
+	}
+	return append(problems[:fiftyvilleMaxProblems:fiftyvilleMaxProblems],
+		fmt.Sprintf("... and %d more", len(problems)-fiftyvilleMaxProblems))
+}
+
+// missingTables 返回 tables 中没有被查询过的表
+func missingTables(queried map[string]bool, tables []string) []string {
+	var missing []string
+	for _, table := range tables {
+		if !queried[table] {
+			missing = append(missing, table)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,163 @@
+package stages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+)
+
+// ArtifactsDirEnv 指定保存诊断文件 (如差异图) 的目录，未设置时不保存
+const ArtifactsDirEnv = "BOOTCS_ARTIFACTS_DIR"
+
+// filterSpec 描述 filter 的一个命令行选项及其参考实现
+type filterSpec struct {
+	flag      string
+	name      string
+	reference func(*helpers.BMPImage) *helpers.BMPImage
+}
+
+var (
+	grayscaleFilter = filterSpec{"-g", "grayscale", helpers.FilterGrayscale}
+	sepiaFilter     = filterSpec{"-s", "sepia", helpers.FilterSepia}
+	reflectFilter   = filterSpec{"-r", "reflect", helpers.FilterReflect}
+	blurFilter      = filterSpec{"-b", "blur", helpers.FilterBlur}
+	edgesFilter     = filterSpec{"-e", "edges", helpers.FilterEdges}
+)
+
+// filterImageSizes 是端到端测试使用的图片尺寸
+// 覆盖需要行填充的奇数宽度、单行、单列以及较大的图片
+var filterImageSizes = []struct {
+	width  int
+	height int
+	desc   string
+}{
+	{1, 1, "single pixel"},
+	{3, 3, "odd width needing row padding"},
+	{5, 2, "odd width needing row padding"},
+	{1, 7, "single column"},
+	{9, 1, "single row"},
+	{4, 4, "width without row padding"},
+	{37, 23, "odd width needing row padding"},
+	{600, 400, "large image"},
+}
+
+// compileFilter 编译完整的 filter 程序 (filter.c + helpers.c)
+func compileFilter(workDir string, extraFlags ...string) error {
+	args := []string{
+		"-ggdb3", "-gdwarf-4", "-O0", "-Qunused-arguments",
+		"-std=c11", "-Wall", "-Werror", "-Wextra",
+		"-Wno-sign-compare", "-Wno-unused-parameter", "-Wno-unused-variable",
+	}
+	args = append(args, extraFlags...)
+	args = append(args, "-lm", "-o", "filter", "filter.c", "helpers.c")
+
+	cmd := exec.Command("clang", args...)
+	cmd.Dir = workDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s\n%s", err, string(out))
+	}
+	return nil
+}
+
+// runFilterImageTests 在生成的图片上运行学生的 filter 程序，并与参考实现逐像素比较
+func runFilterImageTests(logger *logger.Logger, workDir string, filters []filterSpec) error {
+	tempDir, err := os.MkdirTemp("", "filter_images_*")
+	if err != nil {
+		return fmt.Errorf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filterPath, err := filepath.Abs(filepath.Join(workDir, "filter"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range filters {
+		logger.Infof("Testing %s on generated images...", f.name)
+		for _, size := range filterImageSizes {
+			input := helpers.RandomBMPImage(size.width, size.height)
+			if err := runFilterOnImage(tempDir, filterPath, f, input); err != nil {
+				return fmt.Errorf("%s on %dx%d image (%s): %v", f.name, size.width, size.height, size.desc, err)
+			}
+		}
+		logger.Successf("✓ %s correctly filters generated images", f.name)
+	}
+	return nil
+}
+
+// runFilterOnImage 对单张图片运行 filter 并校验输出
+func runFilterOnImage(tempDir, filterPath string, f filterSpec, input *helpers.BMPImage) error {
+	inPath := filepath.Join(tempDir, "in.bmp")
+	outPath := filepath.Join(tempDir, "out.bmp")
+	os.Remove(outPath)
+
+	if err := helpers.WriteBMP(inPath, input); err != nil {
+		return fmt.Errorf("could not write input image: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, filterPath, f.flag, inPath, outPath)
+	cmd.Dir = tempDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("filter timed out after 10s")
+		}
+		return fmt.Errorf("filter failed: %s\n%s", err, string(out))
+	}
+
+	actual, err := helpers.ReadBMP(outPath)
+	if err != nil {
+		return fmt.Errorf("could not read output image: %v", err)
+	}
+	if actual.Width != input.Width || actual.Height != input.Height {
+		return fmt.Errorf("output image is %dx%d, expected %dx%d",
+			actual.Width, actual.Height, input.Width, input.Height)
+	}
+
+	expected := f.reference(input)
+	mismatch, count := helpers.FirstPixelMismatch(expected, actual)
+	if mismatch == nil {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d pixel(s) differ; first wrong pixel at (x=%d, y=%d): expected %s, got %s (original %s)",
+		count, mismatch.X, mismatch.Y, mismatch.Expected, mismatch.Actual, input.Pixels[mismatch.Y][mismatch.X])
+	if path, err := saveFilterDiff(f, input, expected, actual); err != nil {
+		msg += fmt.Sprintf("\ncould not save diff image: %v", err)
+	} else if path != "" {
+		msg += fmt.Sprintf("\ndiff image saved to %s (mismatched pixels in red)", path)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// saveFilterDiff 在设置了 BOOTCS_ARTIFACTS_DIR 时保存输入图、期望图、实际输出和差异图
+func saveFilterDiff(f filterSpec, input, expected, actual *helpers.BMPImage) (string, error) {
+	dir := os.Getenv(ArtifactsDirEnv)
+	if dir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	prefix := filepath.Join(dir, fmt.Sprintf("%s-%dx%d", f.name, input.Width, input.Height))
+	images := map[string]*helpers.BMPImage{
+		"input":    input,
+		"expected": expected,
+		"actual":   actual,
+		"diff":     helpers.DiffImage(expected, actual),
+	}
+	for suffix, img := range images {
+		if err := helpers.WriteBMP(prefix+"-"+suffix+".bmp", img); err != nil {
+			return "", err
+		}
+	}
+	return prefix + "-diff.bmp", nil
+}
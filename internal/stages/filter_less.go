@@ -169,8 +169,23 @@ func testFilterLess(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ %s", tc.name)
 	}
 
+	// 9. 在生成的 BMP 图片上运行完整的 filter 程序，逐像素对比参考实现
+	if !harness.FileExists("filter.c") {
+		return fmt.Errorf("filter.c does not exist")
+	}
+	logger.Infof("Compiling filter.c with helpers.c...")
+	if err := compileFilter(workDir); err != nil {
+		return fmt.Errorf("filter.c does not compile: %v", err)
+	}
+	logger.Successf("filter.c compiles")
+
+	if err := runFilterImageTests(logger, workDir, []filterSpec{grayscaleFilter, sepiaFilter, reflectFilter, blurFilter}); err != nil {
+		return err
+	}
+
 	// 清理编译产物
 	os.Remove(filepath.Join(workDir, "testing"))
+	os.Remove(filepath.Join(workDir, "filter"))
 
 	logger.Successf("All filter tests passed!")
 	return nil
@@ -167,8 +167,23 @@ func testFilterMore(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ %s", tc.name)
 	}
 
+	// 8. 在生成的 BMP 图片上运行完整的 filter 程序，逐像素对比参考实现
+	if !harness.FileExists("filter.c") {
+		return fmt.Errorf("filter.c does not exist")
+	}
+	logger.Infof("Compiling filter.c with helpers.c...")
+	if err := compileFilter(workDir, "-Wno-gnu-folding-constant", "-Wshadow"); err != nil {
+		return fmt.Errorf("filter.c does not compile: %v", err)
+	}
+	logger.Successf("filter.c compiles")
+
+	if err := runFilterImageTests(logger, workDir, []filterSpec{grayscaleFilter, reflectFilter, blurFilter, edgesFilter}); err != nil {
+		return err
+	}
+
 	// 清理编译产物
 	os.Remove(filepath.Join(workDir, "testing"))
+	os.Remove(filepath.Join(workDir, "filter"))
 
 	logger.Successf("All filter-more tests passed!")
 	return nil
@@ -0,0 +1,403 @@
+package stages
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
+	"github.com/bootcs-cn/tester-utils/test_case_harness"
+	"github.com/bootcs-cn/tester-utils/tester_definition"
+)
+
+const (
+	securityOwner    = "bcs_owner"
+	securityPassword = "Secur1ty-probe"
+)
+
+func financeSecurityTestCase() tester_definition.TestCase {
+	return tester_definition.TestCase{
+		Slug:     "finance-security",
+		Timeout:  180 * time.Second,
+		TestFunc: testFinanceSecurity,
+	}
+}
+
+// securityProbe sends hostile requests to the app and collects what goes wrong
+type securityProbe struct {
+	runner   *scenarioRunner
+	owner    *httpClient
+	findings []helpers.SecurityFinding
+}
+
+// testFinanceSecurity probes finance for SQL injection, unescaped output and
+// GET requests that change data; every finding names the request that caused it
+func testFinanceSecurity(harness *test_case_harness.TestCaseHarness) error {
+	logger := harness.Logger
+	scenario, err := loadScenario(financeApp.Scenario)
+	if err != nil {
+		return err
+	}
+	runner, err := startFlaskApp(harness, financeApp, scenario)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(runner.workDir)
+
+	probe := &securityProbe{runner: runner}
+	logger.Infof("Registering %s, who owns shares of AAAA...", securityOwner)
+	if err := probe.setup(); err != nil {
+		return fmt.Errorf("%v%s\nThe security probes need a working app; make the finance stage pass first", err, runner.server.diagnose(0))
+	}
+	logger.Successf("%s registered and bought AAAA", securityOwner)
+
+	checks := []struct {
+		name string
+		run  func() error
+	}{
+		{"SQL injection in /login", probe.sqlInjectionLogin},
+		{"SQL injection in /register", probe.sqlInjectionRegister},
+		{"SQL injection in /quote, /buy and /sell", probe.sqlInjectionTrading},
+		{"XSS through usernames", probe.xssUsernames},
+		{"XSS through symbols", probe.xssSymbols},
+		{"GET requests that change data", probe.getRequests},
+	}
+	for _, check := range checks {
+		logger.Infof("Probing %s...", check.name)
+		found := len(probe.findings)
+		mark := runner.server.logs.Mark()
+		if err := check.run(); err != nil {
+			return fmt.Errorf("%v%s", err, runner.server.diagnose(mark))
+		}
+		if len(probe.findings) == found {
+			logger.Successf("No issues found")
+			continue
+		}
+		for _, finding := range probe.findings[found:] {
+			logger.Errorf("%s", finding)
+		}
+	}
+
+	if len(probe.findings) > 0 {
+		return fmt.Errorf("found %d security issue(s), listed above with the request that triggered each", len(probe.findings))
+	}
+	logger.Successf("All tests passed!")
+	return nil
+}
+
+// setup registers and logs in the owner and buys shares, so that the probes
+// have a user, a password hash and holdings to go after
+func (p *securityProbe) setup() error {
+	owner, err := newHTTPClient(p.runner.baseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+	p.owner = owner
+
+	steps := []struct {
+		path string
+		form url.Values
+	}{
+		{"/register", url.Values{"username": {securityOwner}, "password": {securityPassword}, "confirmation": {securityPassword}}},
+		{"/login", url.Values{"username": {securityOwner}, "password": {securityPassword}}},
+		{"/buy", url.Values{"symbol": {"AAAA"}, "shares": {"2"}}},
+	}
+	for _, step := range steps {
+		resp, _, err := owner.postForm(step.path, step.form)
+		if err != nil {
+			return fmt.Errorf("failed to POST %s: %v", step.path, err)
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("POST %s returned %d", step.path, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// report records a finding
+func (p *securityProbe) report(category, method, path string, form url.Values, problem string, args ...interface{}) {
+	p.findings = append(p.findings, helpers.SecurityFinding{
+		Category: category,
+		Method:   method,
+		Path:     path,
+		Form:     form,
+		Problem:  fmt.Sprintf(problem, args...),
+	})
+}
+
+// send makes a request; GET requests carry the form in the query string
+func (p *securityProbe) send(client *httpClient, method, path string, form url.Values) (*http.Response, string, error) {
+	var resp *http.Response
+	var body string
+	var err error
+	if method == "POST" {
+		resp, body, err = client.postForm(path, form)
+	} else {
+		query := path
+		if len(form) > 0 {
+			query += "?" + form.Encode()
+		}
+		resp, body, err = client.get(query)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to %s %s: %v", method, path, err)
+	}
+	return resp, body, nil
+}
+
+// sendInjection sends an injection payload and reports a crash or a leaked SQL error;
+// a crash is reported with the exception from the server's traceback
+func (p *securityProbe) sendInjection(client *httpClient, path string, form url.Values) (*http.Response, error) {
+	server := p.runner.server
+	mark := server.logs.Mark()
+	resp, body, err := p.send(client, "POST", path, form)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		status := fmt.Sprintf("%d", resp.StatusCode)
+		if traceback := helpers.LastPythonTraceback(server.outputSince(mark)); traceback != "" {
+			status += ", " + helpers.TracebackException(traceback)
+		}
+		p.report("SQL injection", "POST", path, form,
+			"server error (%s); quotes in the input seem to break a SQL query, pass values with ? placeholders instead of formatting them into the query",
+			status)
+	} else if helpers.LeaksSQLError(body) {
+		p.report("SQL injection", "POST", path, form,
+			"the response shows a SQL error or traceback; quotes in the input seem to break a SQL query")
+	}
+	return resp, nil
+}
+
+// snapshot returns a digest of every table, see helpers.SnapshotDatabase
+func (p *securityProbe) snapshot() (map[string]string, error) {
+	db, err := helpers.OpenSQLiteReadOnly(p.runner.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return helpers.SnapshotDatabase(db)
+}
+
+// loggedIn reports whether a client's session is logged in: / only renders for logged-in users
+func (p *securityProbe) loggedIn(client *httpClient) (bool, error) {
+	resp, _, err := client.get("/")
+	if err != nil {
+		return false, fmt.Errorf("failed to GET /: %v", err)
+	}
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// sqlInjectionLogin tries to log in with payloads instead of a username and password
+func (p *securityProbe) sqlInjectionLogin() error {
+	forms := []url.Values{
+		{"username": {securityOwner + "' --"}, "password": {"wrong password"}},
+		{"username": {securityOwner + "' OR '1'='1"}, "password": {"wrong password"}},
+	}
+	for _, payload := range helpers.SQLInjectionPayloads {
+		forms = append(forms, url.Values{"username": {payload}, "password": {payload}})
+	}
+
+	for _, form := range forms {
+		client, err := newHTTPClient(p.runner.baseURL)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP client: %v", err)
+		}
+		if _, err := p.sendInjection(client, "/login", form); err != nil {
+			return err
+		}
+		loggedIn, err := p.loggedIn(client)
+		if err != nil {
+			return err
+		}
+		if loggedIn {
+			p.report("SQL injection", "POST", "/login", form,
+				"logged in without a valid password; the payload changed the login query")
+		}
+	}
+	return nil
+}
+
+// sqlInjectionRegister registers payloads as usernames: they should be stored as they are, or rejected
+func (p *securityProbe) sqlInjectionRegister() error {
+	for _, payload := range helpers.SQLInjectionPayloads {
+		client, err := newHTTPClient(p.runner.baseURL)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP client: %v", err)
+		}
+		form := url.Values{"username": {payload}, "password": {securityPassword}, "confirmation": {securityPassword}}
+		resp, err := p.sendInjection(client, "/register", form)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			continue
+		}
+
+		db, err := helpers.OpenSQLiteReadOnly(p.runner.dbPath)
+		if err != nil {
+			return err
+		}
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", payload).Scan(&count)
+		db.Close()
+		if err != nil {
+			return fmt.Errorf("could not read users: %v", err)
+		}
+		if count != 1 {
+			p.report("SQL injection", "POST", "/register", form,
+				"registration succeeded, but users has %d rows with exactly this username instead of 1; the payload changed the query", count)
+		}
+	}
+	return nil
+}
+
+// sqlInjectionTrading sends payloads as symbols and share counts; none of them is a valid
+// trade, so none of them may change the database
+func (p *securityProbe) sqlInjectionTrading() error {
+	var requests []struct {
+		path string
+		form url.Values
+	}
+	add := func(path string, form url.Values) {
+		requests = append(requests, struct {
+			path string
+			form url.Values
+		}{path, form})
+	}
+	for _, payload := range helpers.SQLInjectionPayloads {
+		add("/quote", url.Values{"symbol": {"AAAA" + payload}})
+		add("/buy", url.Values{"symbol": {"AAAA" + payload}, "shares": {"1"}})
+		add("/buy", url.Values{"symbol": {"AAAA"}, "shares": {"1" + payload}})
+		add("/sell", url.Values{"symbol": {"AAAA" + payload}, "shares": {"1"}})
+		add("/sell", url.Values{"symbol": {"AAAA"}, "shares": {"1" + payload}})
+	}
+
+	for _, request := range requests {
+		before, err := p.snapshot()
+		if err != nil {
+			return err
+		}
+		if _, err := p.sendInjection(p.owner, request.path, request.form); err != nil {
+			return err
+		}
+		after, err := p.snapshot()
+		if err != nil {
+			return err
+		}
+		if changed := helpers.ChangedTables(before, after); len(changed) > 0 {
+			p.report("SQL injection", "POST", request.path, request.form,
+				"the request is invalid, but it changed the %s table(s) in %s",
+				strings.Join(changed, ", "), filepath.Base(p.runner.dbPath))
+		}
+	}
+	return nil
+}
+
+// xssMarker returns a random marker, so that a page cannot contain the payload by chance
+func xssMarker() string {
+	return fmt.Sprintf("bcs-xss-%d", random.RandomInt(100000, 999999))
+}
+
+// xssUsernames registers users whose names are XSS payloads and checks every page they see
+func (p *securityProbe) xssUsernames() error {
+	marker := xssMarker()
+	for _, payload := range helpers.XSSPayloads(marker) {
+		client, err := newHTTPClient(p.runner.baseURL)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP client: %v", err)
+		}
+		requests := []struct {
+			method string
+			path   string
+			form   url.Values
+		}{
+			{"POST", "/register", url.Values{"username": {payload}, "password": {securityPassword}, "confirmation": {securityPassword}}},
+			{"POST", "/login", url.Values{"username": {payload}, "password": {securityPassword}}},
+			{"GET", "/", nil},
+			{"GET", "/quote", nil},
+			{"GET", "/buy", nil},
+			{"GET", "/sell", nil},
+			{"GET", "/history", nil},
+		}
+		for _, request := range requests {
+			resp, body, err := p.send(client, request.method, request.path, request.form)
+			if err != nil {
+				return err
+			}
+			if helpers.HasInjectedMarkup(body, marker) {
+				p.report("XSS", request.method, request.path, request.form,
+					"the username %q is rendered as HTML; let Jinja escape it (no |safe or Markup)", payload)
+			}
+			// An app may refuse such usernames; then there is no session to look at
+			if request.method == "POST" && resp.StatusCode >= 400 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// xssSymbols submits XSS payloads as symbols, which apps often echo in error messages
+func (p *securityProbe) xssSymbols() error {
+	marker := xssMarker()
+	for _, payload := range helpers.XSSPayloads(marker) {
+		for _, path := range []string{"/quote", "/buy", "/sell"} {
+			form := url.Values{"symbol": {payload}}
+			if path != "/quote" {
+				form.Set("shares", "1")
+			}
+			_, body, err := p.send(p.owner, "POST", path, form)
+			if err != nil {
+				return err
+			}
+			if helpers.HasInjectedMarkup(body, marker) {
+				p.report("XSS", "POST", path, form,
+					"the symbol is echoed as HTML; let Jinja escape it (no |safe or Markup)")
+			}
+		}
+	}
+	return nil
+}
+
+// getRequests sends the buy, sell and register forms as GET requests, which must not change data:
+// otherwise a link or an image on another site can trade for a logged-in user
+func (p *securityProbe) getRequests() error {
+	stranger, err := newHTTPClient(p.runner.baseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+	requests := []struct {
+		client *httpClient
+		path   string
+		form   url.Values
+	}{
+		{p.owner, "/buy", url.Values{"symbol": {"AAAA"}, "shares": {"1"}}},
+		{p.owner, "/sell", url.Values{"symbol": {"AAAA"}, "shares": {"1"}}},
+		{stranger, "/register", url.Values{"username": {"bcs_get"}, "password": {securityPassword}, "confirmation": {securityPassword}}},
+	}
+	for _, request := range requests {
+		before, err := p.snapshot()
+		if err != nil {
+			return err
+		}
+		if _, _, err := p.send(request.client, "GET", request.path, request.form); err != nil {
+			return err
+		}
+		after, err := p.snapshot()
+		if err != nil {
+			return err
+		}
+		if changed := helpers.ChangedTables(before, after); len(changed) > 0 {
+			p.report("GET changes data", "GET", request.path, request.form,
+				"changed the %s table(s); only POST requests should change data (check request.method)",
+				strings.Join(changed, ", "))
+		}
+	}
+	return nil
+}
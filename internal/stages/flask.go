@@ -0,0 +1,332 @@
+package stages
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+)
+
+const (
+	// ServerStartupTimeout is the maximum time to wait for Flask server to start
+	ServerStartupTimeout = 10 * time.Second
+
+	// ConnectTimeout is the timeout for each readiness request
+	ConnectTimeout = 500 * time.Millisecond
+
+	// CheckInterval is the interval between server readiness checks
+	CheckInterval = 100 * time.Millisecond
+
+	// ShutdownTimeout is how long the server may take to exit after SIGTERM before it is killed
+	ShutdownTimeout = 3 * time.Second
+
+	// ServerLogLines is how many lines of the server's output are kept for error messages
+	ServerLogLines = 500
+)
+
+// flaskServer manages a Flask application process
+type flaskServer struct {
+	cmd     *exec.Cmd
+	port    int
+	baseURL string
+	// logs holds the tail of the app's stdout and stderr
+	logs *helpers.LogBuffer
+	// exited is closed once the process has exited; waitErr is then its exit status
+	exited   chan struct{}
+	waitErr  error
+	stopOnce sync.Once
+}
+
+// startFlaskServer starts the Flask application with the given Python interpreter and
+// returns a flaskServer; extraEnv is added to the app's environment (e.g. the quote server URL)
+func startFlaskServer(python, workDir string, port int, extraEnv []string) (*flaskServer, error) {
+	// Set environment variables for Flask
+	env := os.Environ()
+	env = append(env, "FLASK_APP=app.py")
+	env = append(env, "FLASK_ENV=development")
+	env = append(env, "PYTHONUNBUFFERED=1")
+	env = append(env, extraEnv...)
+	env = append(env, fmt.Sprintf("FLASK_RUN_PORT=%d", port))
+
+	// Start Flask using python -m flask run
+	cmd := exec.Command(python, "-m", "flask", "run", "--port", fmt.Sprintf("%d", port))
+	cmd.Dir = workDir
+	cmd.Env = env
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// Keep the tail of stdout/stderr for error messages
+	logs := helpers.NewLogBuffer(ServerLogLines)
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start Flask: %v", err)
+	}
+
+	server := &flaskServer{
+		cmd:     cmd,
+		port:    port,
+		baseURL: fmt.Sprintf("http://127.0.0.1:%d", port),
+		logs:    logs,
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		server.waitErr = cmd.Wait()
+		close(server.exited)
+	}()
+
+	// Wait for server to be ready
+	if err := server.waitForReady(ServerStartupTimeout); err != nil {
+		server.stop()
+		return nil, fmt.Errorf("Flask server failed to start: %v%s", err, server.diagnose(0))
+	}
+
+	return server, nil
+}
+
+// waitForReady waits until the server answers an HTTP request, failing as soon as the process exits
+func (s *flaskServer) waitForReady(timeout time.Duration) error {
+	client := &http.Client{
+		Timeout: ConnectTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	deadline := time.After(timeout)
+	for {
+		// Any response, even a redirect to /login or an error page, means the app is serving
+		resp, err := client.Get(s.baseURL + "/")
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		select {
+		case <-s.exited:
+			return fmt.Errorf("the app exited before it was ready (%v)", s.waitErr)
+		case <-deadline:
+			return fmt.Errorf("server did not answer HTTP requests within %v", timeout)
+		case <-time.After(CheckInterval):
+		}
+	}
+}
+
+// running reports whether the server process is still alive
+func (s *flaskServer) running() bool {
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// outputSince returns the server's output since log mark; the output of the
+// last request may still be in the pipe, so it is given a moment to arrive
+func (s *flaskServer) outputSince(mark int) []string {
+	if s.running() {
+		time.Sleep(CheckInterval)
+	}
+	return s.logs.Since(mark)
+}
+
+// diagnose explains a failure from the server's output since log mark: the last
+// Python traceback, or the tail of the log if the process has exited
+func (s *flaskServer) diagnose(mark int) string {
+	lines := s.outputSince(mark)
+	if traceback := helpers.LastPythonTraceback(lines); traceback != "" {
+		return "\nServer traceback:\n" + traceback
+	}
+	if s.running() {
+		return ""
+	}
+	message := fmt.Sprintf("\nThe Flask server exited (%v)", s.waitErr)
+	if len(lines) > 0 {
+		if len(lines) > 20 {
+			lines = lines[len(lines)-20:]
+		}
+		message += ", its last output:\n" + strings.Join(lines, "\n")
+	}
+	return message
+}
+
+// stop terminates the server's process group, waiting for it to exit before
+// killing whatever is left
+func (s *flaskServer) stop() {
+	s.stopOnce.Do(func() {
+		pgid := -s.cmd.Process.Pid
+		syscall.Kill(pgid, syscall.SIGTERM)
+		if !waitForProcessGroup(pgid, s.exited, ShutdownTimeout) {
+			syscall.Kill(pgid, syscall.SIGKILL)
+			waitForProcessGroup(pgid, s.exited, ShutdownTimeout)
+		}
+	})
+}
+
+// waitForProcessGroup waits until the group leader has been reaped and no process
+// of the group is left; it returns false on timeout
+func waitForProcessGroup(pgid int, exited <-chan struct{}, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	select {
+	case <-exited:
+	case <-deadline:
+		return false
+	}
+	for syscall.Kill(pgid, 0) == nil {
+		select {
+		case <-deadline:
+			return false
+		case <-time.After(CheckInterval / 10):
+		}
+	}
+	return true
+}
+
+// httpClient wraps http.Client with session/cookie support
+type httpClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+// newHTTPClient creates a new HTTP client with cookie jar
+func newHTTPClient(baseURL string) (*httpClient, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &httpClient{
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse // Don't follow redirects
+			},
+		},
+		baseURL: baseURL,
+	}, nil
+}
+
+// clone returns a new client holding a copy of this client's cookies
+func (c *httpClient) clone() (*httpClient, error) {
+	clone, err := newHTTPClient(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	clone.client.Jar.SetCookies(u, c.client.Jar.Cookies(u))
+	return clone, nil
+}
+
+// tamperCookies changes the value of every cookie, as a forged session cookie would
+func (c *httpClient) tamperCookies() {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return
+	}
+	cookies := c.client.Jar.Cookies(u)
+	for _, cookie := range cookies {
+		value := []byte(cookie.Value)
+		for i, b := range value {
+			// Rotate letters and digits so the value keeps its shape but no longer matches
+			switch {
+			case b >= 'a' && b <= 'z':
+				value[i] = 'a' + (b-'a'+1)%26
+			case b >= 'A' && b <= 'Z':
+				value[i] = 'A' + (b-'A'+1)%26
+			case b >= '0' && b <= '9':
+				value[i] = '0' + (b-'0'+1)%10
+			}
+		}
+		cookie.Value = string(value)
+	}
+	c.client.Jar.SetCookies(u, cookies)
+}
+
+// get performs a GET request
+func (c *httpClient) get(path string) (*http.Response, string, error) {
+	resp, err := c.client.Get(c.baseURL + path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, string(body), err
+}
+
+// postForm performs a POST request with form data
+func (c *httpClient) postForm(path string, data url.Values) (*http.Response, string, error) {
+	resp, err := c.client.PostForm(c.baseURL+path, data)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, string(body), err
+}
+
+// findAvailablePort finds an available TCP port
+func findAvailablePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// copyDir copies a directory recursively, symlinking .venv for speed
+func copyDir(src, dst string) error {
+	// First, check if .venv exists and create a symlink for it
+	venvSrc := filepath.Join(src, ".venv")
+	if info, err := os.Stat(venvSrc); err == nil && info.IsDir() {
+		venvDst := filepath.Join(dst, ".venv")
+		if err := os.Symlink(venvSrc, venvDst); err != nil {
+			return fmt.Errorf("failed to symlink .venv: %v", err)
+		}
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+
+		// Skip flask_session directory
+		if strings.Contains(path, "flask_session") {
+			return nil
+		}
+
+		// Skip .venv directory since we already symlinked it
+		if info.IsDir() && info.Name() == ".venv" {
+			return filepath.SkipDir
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, info.Mode())
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dstPath, data, info.Mode())
+	})
+}
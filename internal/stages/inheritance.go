@@ -1,18 +1,34 @@
 package stages
 
 import (
+	_ "embed"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
 
+const (
+	// inheritanceStatsFamilies 是统计检验中创建的家族数量
+	inheritanceStatsFamilies = 2000
+	// inheritanceFamilySize 是三代家族的人数 (1 + 2 + 4)
+	inheritanceFamilySize = 7
+)
+
+//go:embed drivers/inheritance_stats_prelude.h
+var inheritanceStatsPrelude string
+
+//go:embed drivers/inheritance_stats.c
+var inheritanceStatsDriver string
+
 func inheritanceTestCase() tester_definition.TestCase {
 	return tester_definition.TestCase{
 		Slug:     "inheritance",
@@ -45,89 +61,87 @@ func testInheritance(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("inheritance.c compiles")
 
-	// 3. 创建测试程序
-	// 读取学生的 inheritance.c，将 main 重命名为 distro_main
-	inheritanceCode, err := harness.ReadFile("inheritance.c")
-	if err != nil {
-		return fmt.Errorf("could not read inheritance.c: %v", err)
+	// 3. 检查 create_family 创建的家族结构和等位基因 (多个随机种子)
+	if err := runCFunctionChecks(logger, workDir, "inheritance.c", inheritanceChecks(), inheritanceDriverFlags...); err != nil {
+		return err
 	}
 
-	// 使用正则替换 main 函数
-	mainRegex := regexp.MustCompile(`int\s+main\s*\(`)
-	modifiedCode := mainRegex.ReplaceAllString(string(inheritanceCode), "int distro_main(")
-
-	// 读取测试代码
-	testCodeBytes, err := harness.ReadFile("inheritance_test.c")
+	// 4. 统计检验：生成大量家族，检查等位基因的随机性以及 free_family 是否释放了所有节点
+	logger.Infof("Compiling statistics harness...")
+	inheritanceCode, err := harness.ReadFile("inheritance.c")
 	if err != nil {
-		return fmt.Errorf("inheritance_test.c does not exist: %v", err)
+		return fmt.Errorf("could not read inheritance.c: %v", err)
 	}
-	testCode := string(testCodeBytes)
-
-	// 写入组合的测试文件
-	combinedCode := modifiedCode + "\n" + testCode
-	testFilePath := filepath.Join(workDir, "inheritance_combined_test.c")
-	if err := os.WriteFile(testFilePath, []byte(combinedCode), 0644); err != nil {
-		return fmt.Errorf("could not write test file: %v", err)
+	statsCode := inheritanceStatsPrelude + "\n" + helpers.RenameMain("inheritance.c", inheritanceCode) + inheritanceStatsDriver
+	statsFilePath := filepath.Join(workDir, "inheritance_stats.c")
+	if err := os.WriteFile(statsFilePath, []byte(statsCode), 0644); err != nil {
+		return fmt.Errorf("could not write statistics harness: %v", err)
 	}
-
-	// 编译测试程序
-	logger.Infof("Compiling test harness...")
 	cmd = exec.Command("clang",
 		"-ggdb3", "-gdwarf-4", "-O0", "-Qunused-arguments",
 		"-std=c11", "-Wall", "-Wextra",
-		"-Wno-sign-compare", "-Wno-unused-parameter", "-Wno-unused-variable",
-		"-lm", "-o", "inheritance_test", "inheritance_combined_test.c")
+		"-Wno-sign-compare", "-Wno-unused-parameter", "-Wno-unused-variable", "-Wno-unused-function",
+		"-lm", "-o", "inheritance_stats", "inheritance_stats.c")
 	cmd.Dir = workDir
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("test harness does not compile: %s\n%s", err, string(out))
+		return fmt.Errorf("statistics harness does not compile: %s\n%s", err, string(out))
 	}
-	logger.Successf("test harness compiles")
+	logger.Successf("statistics harness compiles")
 
-	// 4. 测试正确的家族大小
-	logger.Infof("Testing correct family size...")
-	cmd = exec.Command("./inheritance_test")
+	cmd = exec.Command("./inheritance_stats",
+		strconv.Itoa(inheritanceStatsFamilies), strconv.Itoa(random.RandomInt(1, 1<<30)))
 	cmd.Dir = workDir
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("test program failed: %s\n%s", err, string(out))
+		return fmt.Errorf("statistics harness failed: %s\n%s", err, string(out))
 	}
-
-	output := strings.TrimSpace(string(out))
-	if !strings.Contains(output, "size_true") {
-		return fmt.Errorf("incorrect family size: expected 3 generations")
+	stats, err := parseInheritanceStats(string(out))
+	if err != nil {
+		return err
 	}
-	logger.Successf("✓ correct family size")
 
-	// 5. 测试等位基因正确继承
-	logger.Infof("Testing alleles inherited correctly...")
-	if !strings.Contains(output, "allele_true") {
-		return fmt.Errorf("alleles not inherited correctly from parents")
+	logger.Infof("Testing every family has the correct structure...")
+	expectedNodes := inheritanceStatsFamilies * inheritanceFamilySize
+	if stats["nodes"][0] != expectedNodes || stats["invalid"][0] != 0 {
+		return fmt.Errorf("created %d people in %d families (expected %d) with %d invalid parents or alleles",
+			stats["nodes"][0], inheritanceStatsFamilies, expectedNodes, stats["invalid"][0])
 	}
-	logger.Successf("✓ alleles inherited correctly")
+	logger.Successf("✓ every family has the correct structure")
 
-	// 6. 多次运行验证一致性
-	logger.Infof("Testing multiple runs for consistency...")
-	for i := 0; i < 5; i++ {
-		cmd = exec.Command("./inheritance_test")
-		cmd.Dir = workDir
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("run %d failed: %s\n%s", i+1, err, string(out))
-		}
-		output := strings.TrimSpace(string(out))
-		if !strings.Contains(output, "size_true") || !strings.Contains(output, "allele_true") {
-			return fmt.Errorf("run %d: got %s", i+1, output)
+	logger.Infof("Testing alleles are inherited randomly from each parent...")
+	for i, key := range []string{"picks0", "picks1"} {
+		picks := stats[key]
+		if _, err := helpers.ChiSquareUniform(picks); err != nil {
+			total := picks[0] + picks[1]
+			return fmt.Errorf("allele %d was inherited from the parent's first allele %d of %d times (%.1f%%, expected about 50%%): %v",
+				i, picks[0], total, 100*float64(picks[0])/float64(total), err)
 		}
 	}
-	logger.Successf("✓ multiple runs consistent")
+	logger.Successf("✓ alleles are inherited randomly from each parent")
+
+	logger.Infof("Testing oldest generation alleles are uniformly random...")
+	if _, err := helpers.ChiSquareUniform(stats["base"]); err != nil {
+		return fmt.Errorf("oldest generation alleles are not uniformly random (A=%d, B=%d, O=%d): %v",
+			stats["base"][0], stats["base"][1], stats["base"][2], err)
+	}
+	logger.Successf("✓ oldest generation alleles are uniformly random")
 
-	// 7. 内存检查 (valgrind) - 如果可用
+	logger.Infof("Testing free_family frees every person...")
+	if stats["mallocs"][0] != stats["frees"][0] {
+		return fmt.Errorf("allocated %d blocks of memory but freed %d", stats["mallocs"][0], stats["frees"][0])
+	}
+	if stats["mallocs"][0] < expectedNodes {
+		return fmt.Errorf("allocated only %d blocks of memory for %d people", stats["mallocs"][0], expectedNodes)
+	}
+	logger.Successf("✓ free_family frees every person")
+
+	// 5. 内存检查 (valgrind) - 如果可用
 	logger.Infof("Testing program is free of memory errors...")
 	if _, err := exec.LookPath("valgrind"); err != nil {
 		logger.Infof("valgrind not available, skipping memory check")
 	} else {
 		cmd = exec.Command("valgrind", "--error-exitcode=1", "--leak-check=full",
-			"--show-leak-kinds=all", "--errors-for-leak-kinds=all", "-q", "./inheritance_test")
+			"--show-leak-kinds=all", "--errors-for-leak-kinds=all", "-q", "./inheritance")
 		cmd.Dir = workDir
 		out, err := cmd.CombinedOutput()
 		if err != nil {
@@ -138,9 +152,118 @@ func testInheritance(harness *test_case_harness.TestCaseHarness) error {
 
 	// 清理编译产物
 	os.Remove(filepath.Join(workDir, "inheritance"))
-	os.Remove(filepath.Join(workDir, "inheritance_test"))
-	os.Remove(filepath.Join(workDir, "inheritance_combined_test.c"))
+	os.Remove(filepath.Join(workDir, "inheritance_stats"))
+	os.Remove(statsFilePath)
 
 	logger.Successf("All inheritance tests passed!")
 	return nil
 }
+
+// inheritanceDriverFlags 是编译测试驱动程序的参数 (与编译 inheritance.c 相同，但不把警告当作错误)
+var inheritanceDriverFlags = []string{
+	"-ggdb3", "-gdwarf-4", "-O0", "-Qunused-arguments",
+	"-std=c11", "-Wall", "-Wextra",
+	"-Wno-sign-compare", "-Wno-unused-parameter", "-Wno-unused-variable",
+	"-lm",
+}
+
+// inheritanceSeedRuns 是用不同随机种子检查 create_family 的次数
+const inheritanceSeedRuns = 5
+
+// inheritanceStructure 返回检查 p 是 generations 代完整家族树的 C 表达式 (最老一代没有父母)
+func inheritanceStructure(p string, generations int) string {
+	if generations == 1 {
+		return fmt.Sprintf("(%[1]s != NULL && %[1]s->parents[0] == NULL && %[1]s->parents[1] == NULL)", p)
+	}
+	return fmt.Sprintf("(%s != NULL && %s && %s)", p,
+		inheritanceStructure(p+"->parents[0]", generations-1),
+		inheritanceStructure(p+"->parents[1]", generations-1))
+}
+
+// inheritanceAlleles 返回检查家族中等位基因的 C 表达式：最老一代是 A、B、O，
+// 其他人的 alleles[i] 是 parents[i] 的两个等位基因之一 (需要先确认结构完整)
+func inheritanceAlleles(p string, generations int) string {
+	if generations == 1 {
+		var checks []string
+		for i := 0; i < 2; i++ {
+			checks = append(checks, fmt.Sprintf("(%[1]s->alleles[%[2]d] == 'A' || %[1]s->alleles[%[2]d] == 'B' || %[1]s->alleles[%[2]d] == 'O')", p, i))
+		}
+		return "(" + strings.Join(checks, " && ") + ")"
+	}
+	var checks []string
+	for i := 0; i < 2; i++ {
+		parent := fmt.Sprintf("%s->parents[%d]", p, i)
+		checks = append(checks,
+			fmt.Sprintf("(%[1]s->alleles[%[2]d] == %[3]s->alleles[0] || %[1]s->alleles[%[2]d] == %[3]s->alleles[1])", p, i, parent),
+			inheritanceAlleles(parent, generations-1))
+	}
+	return "(" + strings.Join(checks, " && ") + ")"
+}
+
+// inheritanceChecks 是 inheritance 的函数级检查，每个检查用不同的随机种子创建家族
+// 检查的表达式很长，先存入变量，失败时只报告变量名
+func inheritanceChecks() []helpers.CFunctionCheck {
+	family := func(generations int) helpers.CStep {
+		return helpers.CStmt(fmt.Sprintf("srand(%d); person *p = create_family(%d);", random.RandomInt(1, 1<<30), generations))
+	}
+	check := func(name, expr string) []helpers.CStep {
+		return []helpers.CStep{helpers.CStmt(fmt.Sprintf("bool %s = %s;", name, expr)), helpers.ExpectBool(name, true)}
+	}
+
+	seeds := []helpers.CStep{helpers.CStmt("person *p = NULL; bool valid_family = false;")}
+	for i := 0; i < inheritanceSeedRuns; i++ {
+		seeds = append(seeds,
+			helpers.CStmt(fmt.Sprintf("srand(%d); p = create_family(3); valid_family = %s && %s;",
+				random.RandomInt(1, 1<<30), inheritanceStructure("p", 3), inheritanceAlleles("p", 3))),
+			helpers.ExpectBool("valid_family", true),
+			helpers.CStmt("free_family(p);"))
+	}
+
+	return []helpers.CFunctionCheck{
+		{Name: "create_family creates a person without parents for one generation", Steps: cSteps(
+			[]helpers.CStep{family(1)}, check("no_parents", inheritanceStructure("p", 1)))},
+		{Name: "create_family creates a family with correct size", Steps: cSteps(
+			[]helpers.CStep{family(3)}, check("three_generations", inheritanceStructure("p", 3)))},
+		{Name: "oldest generation has valid alleles", Steps: cSteps(
+			[]helpers.CStep{family(1)}, check("valid_alleles", inheritanceStructure("p", 1)+" && "+inheritanceAlleles("p", 1)))},
+		{Name: "alleles are inherited from the corresponding parent", Steps: cSteps(
+			[]helpers.CStep{family(3)}, check("alleles_inherited", inheritanceStructure("p", 3)+" && "+inheritanceAlleles("p", 3)))},
+		{Name: "create_family creates valid families with different random seeds", Steps: seeds},
+	}
+}
+
+// parseInheritanceStats 解析统计测试驱动的输出 (每行为 "key n1 n2 ...")
+func parseInheritanceStats(output string) (map[string][]int, error) {
+	expectedFields := map[string]int{
+		"families": 1, "nodes": 1, "invalid": 1,
+		"picks0": 2, "picks1": 2, "base": 3,
+		"mallocs": 1, "frees": 1,
+	}
+
+	stats := make(map[string][]int)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if _, ok := expectedFields[fields[0]]; !ok {
+			continue
+		}
+		values := make([]int, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected statistics output %q", line)
+			}
+			values = append(values, v)
+		}
+		stats[fields[0]] = values
+	}
+
+	for key, n := range expectedFields {
+		if len(stats[key]) != n {
+			return nil, fmt.Errorf("statistics harness output missing %q:\n%s", key, output)
+		}
+	}
+	return stats, nil
+}
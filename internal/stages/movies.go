@@ -1,9 +1,7 @@
 package stages
 
 import (
-	"database/sql"
 	"fmt"
-	"path/filepath"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -16,7 +14,7 @@ import (
 func moviesTestCase() tester_definition.TestCase {
 	return tester_definition.TestCase{
 		Slug:     "movies",
-		Timeout:  60 * time.Second,
+		Timeout:  120 * time.Second,
 		TestFunc: testMovies,
 	}
 }
@@ -35,132 +33,59 @@ func testMovies(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("SQL files exist")
 
-	// 2. 打开数据库
-	dbPath := filepath.Join(workDir, "movies.db")
-	db, err := sql.Open("sqlite3", dbPath)
+	// 2. 打开数据库的只读沙箱 (每个查询单独连接，有超时和行数限制) 及 id 打乱的副本
+	sandbox, perturbed, cleanup, err := openSQLSandboxes(logger, workDir, "movies.db")
 	if err != nil {
-		return fmt.Errorf("failed to open movies.db: %v", err)
+		return err
 	}
-	defer db.Close()
+	defer cleanup()
 
-	// Test 1: 2008 年电影 (无序)
-	logger.Infof("Testing 1.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "1.sql", expectedMovies1); err != nil {
-		return fmt.Errorf("1.sql: %v", err)
+	// 3. 运行各测试
+	if err := runSQLChecks(logger, workDir, sandbox, perturbed, moviesChecks); err != nil {
+		return err
 	}
-	logger.Successf("✓ 1.sql produces correct result")
 
-	// Test 2: Emma Stone 出生年份 (单值)
-	logger.Infof("Testing 2.sql produces correct result...")
-	if err := helpers.TestSQLSingleValue(db, workDir, "2.sql", "1988"); err != nil {
-		return fmt.Errorf("2.sql: %v", err)
-	}
-	logger.Successf("✓ 2.sql produces correct result")
+	logger.Successf("All tests passed!")
+	return nil
+}
 
-	// Test 3: 2018+ 电影按字母排序 (有序)
-	logger.Infof("Testing 3.sql produces correct result...")
-	if err := helpers.TestSQLSingleColOrdered(db, workDir, "3.sql", expectedMovies3); err != nil {
-		return fmt.Errorf("3.sql: %v", err)
-	}
-	logger.Successf("✓ 3.sql produces correct result")
+// moviesJoinBudget 是跨 movies、stars、people 等表的查询 (8-13.sql) 的时间预算
+// 用 JOIN 或不相关子查询在完整的 movies.db 上不到一秒，逐行执行的相关子查询则会超出预算
+const moviesJoinBudget = 5 * time.Second
 
+// moviesChecks 是每个 N.sql 的预期结果
+var moviesChecks = []helpers.SQLCheck{
+	// Test 1: 2008 年电影 (无序)
+	helpers.UnorderedSQLCheck("1.sql", expectedMovies1),
+	// Test 2: Emma Stone 出生年份 (单值)
+	helpers.ValueSQLCheck("2.sql", "1988"),
+	// Test 3: 2018+ 电影按字母排序 (有序)
+	helpers.OrderedSQLCheck("3.sql", expectedMovies3),
 	// Test 4: 10.0 评分电影数量 (单值)
-	logger.Infof("Testing 4.sql produces correct result...")
-	if err := helpers.TestSQLSingleValue(db, workDir, "4.sql", "2"); err != nil {
-		return fmt.Errorf("4.sql: %v", err)
-	}
-	logger.Successf("✓ 4.sql produces correct result")
-
+	helpers.ValueSQLCheck("4.sql", "2"),
 	// Test 5: Harry Potter 电影 (双列有序)
-	logger.Infof("Testing 5.sql produces correct result...")
-	if err := helpers.TestSQLDoubleColOrdered(db, workDir, "5.sql", expectedMovies5); err != nil {
-		return fmt.Errorf("5.sql: %v", err)
-	}
-	logger.Successf("✓ 5.sql produces correct result")
-
+	helpers.PairsSQLCheck("5.sql", expectedMovies5),
 	// Test 6: 2012 年平均评分 (浮点数)
-	logger.Infof("Testing 6.sql produces correct result...")
-	if err := helpers.TestSQLFloat(db, workDir, "6.sql", 7.74, 0.01); err != nil {
-		return fmt.Errorf("6.sql: %v", err)
-	}
-	logger.Successf("✓ 6.sql produces correct result")
-
+	helpers.FloatSQLCheck("6.sql", 7.74, 0.01),
 	// Test 7: 2010 年电影及评分 (双列有序)
-	logger.Infof("Testing 7.sql produces correct result...")
-	if err := helpers.TestSQLDoubleColOrdered(db, workDir, "7.sql", expectedMovies7); err != nil {
-		return fmt.Errorf("7.sql: %v", err)
-	}
-	logger.Successf("✓ 7.sql produces correct result")
-
+	helpers.PairsSQLCheck("7.sql", expectedMovies7),
 	// Test 8: Toy Story 演员 (无序)
-	logger.Infof("Testing 8.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "8.sql", expectedMovies8); err != nil {
-		return fmt.Errorf("8.sql: %v", err)
-	}
-	logger.Successf("✓ 8.sql produces correct result")
-
+	helpers.UnorderedSQLCheck("8.sql", expectedMovies8).WithBudget(moviesJoinBudget),
 	// Test 9: 2004 年电影演员按出生年份排序 (有序)
-	logger.Infof("Testing 9.sql produces correct result...")
-	if err := helpers.TestSQLSingleColOrdered(db, workDir, "9.sql", expectedMovies9); err != nil {
-		return fmt.Errorf("9.sql: %v", err)
-	}
-	logger.Successf("✓ 9.sql produces correct result")
-
+	helpers.OrderedSQLCheck("9.sql", expectedMovies9).WithBudget(moviesJoinBudget),
 	// Test 10: 9.0+ 评分电影导演 (无序)
-	logger.Infof("Testing 10.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "10.sql", expectedMovies10); err != nil {
-		return fmt.Errorf("10.sql: %v", err)
-	}
-	logger.Successf("✓ 10.sql produces correct result")
-
+	helpers.UnorderedSQLCheck("10.sql", expectedMovies10).WithBudget(moviesJoinBudget),
 	// Test 11: Chadwick Boseman 电影按评分排序 (有序)
-	logger.Infof("Testing 11.sql produces correct result...")
-	if err := helpers.TestSQLSingleColOrdered(db, workDir, "11.sql", expectedMovies11); err != nil {
-		return fmt.Errorf("11.sql: %v", err)
-	}
-	logger.Successf("✓ 11.sql produces correct result")
-
+	helpers.OrderedSQLCheck("11.sql", expectedMovies11).WithBudget(moviesJoinBudget),
 	// Test 12: Johnny Depp & Helena Bonham Carter 共同电影 (无序，支持两种答案)
-	logger.Infof("Testing 12.sql produces correct result...")
-	if err := testMovies12(db, workDir); err != nil {
-		return fmt.Errorf("12.sql: %v", err)
-	}
-	logger.Successf("✓ 12.sql produces correct result")
-
+	{
+		Filename:     "12.sql",
+		Expected:     helpers.SingleColumnRows(expectedMovies12a),
+		Alternatives: [][][]string{helpers.SingleColumnRows(expectedMovies12b)},
+		Budget:       moviesJoinBudget,
+	},
 	// Test 13: Kevin Bacon 合作演员 (无序)
-	logger.Infof("Testing 13.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "13.sql", expectedMovies13); err != nil {
-		return fmt.Errorf("13.sql: %v", err)
-	}
-	logger.Successf("✓ 13.sql produces correct result")
-
-	logger.Successf("All tests passed!")
-	return nil
-}
-
-// testMovies12 handles test12's two possible answers
-func testMovies12(db *sql.DB, workDir string) error {
-	query, err := helpers.ReadSQLFile(workDir, "12.sql")
-	if err != nil {
-		return err
-	}
-
-	actual, err := helpers.ExecuteQuerySingleCol(db, query)
-	if err != nil {
-		return err
-	}
-
-	// Try first answer (Johnny Depp & Helena Bonham Carter)
-	if helpers.EqualSets(actual, expectedMovies12a) {
-		return nil
-	}
-
-	// Try second answer (Bradley Cooper & Jennifer Lawrence)
-	if helpers.EqualSets(actual, expectedMovies12b) {
-		return nil
-	}
-
-	return fmt.Errorf("result does not match either expected answer")
+	helpers.UnorderedSQLCheck("13.sql", expectedMovies13).WithBudget(moviesJoinBudget),
 }
 
 // 预期结果数据 (对齐 CS50 check50)
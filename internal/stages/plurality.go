@@ -2,15 +2,12 @@ package stages
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
-	"regexp"
 	"sort"
-	"strings"
 	"time"
 
-	"github.com/bootcs-cn/tester-utils/runner"
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
@@ -43,128 +40,90 @@ func testPlurality(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("plurality compiles")
 
-	// 3. 创建测试程序
-	// 读取学生的 plurality.c，将 main 重命名为 distro_main
-	pluralityCode, err := harness.ReadFile("plurality.c")
-	if err != nil {
-		return fmt.Errorf("could not read plurality.c: %v", err)
+	// 3. 运行 vote 和 print_winner 函数测试
+	if err := runCFunctionChecks(logger, workDir, "plurality.c", pluralityChecks(), "-I..", "-lm", "-Wall"); err != nil {
+		return err
 	}
 
-	// 使用正则替换 main 函数
-	mainRegex := regexp.MustCompile(`int\s+main\s*\(`)
-	modifiedCode := mainRegex.ReplaceAllString(string(pluralityCode), "int distro_main(")
-
-	// 从学生目录读取测试代码
-	testCodeBytes, err := harness.ReadFile("plurality_test.c")
-	if err != nil {
-		return fmt.Errorf("plurality_test.c does not exist: %v", err)
+	// 4. 端到端随机选举模拟
+	if err := runElectionSimulations(logger, workDir, "plurality", pluralitySimulations()); err != nil {
+		return err
 	}
-	testCode := string(testCodeBytes)
 
-	// 写入组合的测试文件
-	combinedCode := modifiedCode + "\n" + testCode
-	testFilePath := filepath.Join(workDir, "plurality_combined_test.c")
-	if err := os.WriteFile(testFilePath, []byte(combinedCode), 0644); err != nil {
-		return fmt.Errorf("could not write test file: %v", err)
-	}
+	logger.Successf("All plurality tests passed!")
+	return nil
+}
 
-	// 编译测试程序
-	logger.Infof("Compiling test harness...")
-	cmd = exec.Command("clang", "-o", "plurality_test", "plurality_combined_test.c", "-I..", "-lm", "-Wall")
-	cmd.Dir = workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("test harness does not compile: %s\n%s", err, string(out))
-	}
-	logger.Successf("test harness compiles")
+// pluralityCandidates 设置 Alice、Bob、Charlie 三名候选人及其初始票数
+func pluralityCandidates(alice, bob, charlie int) helpers.CStep {
+	return helpers.CStmt(fmt.Sprintf(
+		`candidate_count = 3; `+
+			`candidates[0].name = "Alice"; candidates[0].votes = %d; `+
+			`candidates[1].name = "Bob"; candidates[1].votes = %d; `+
+			`candidates[2].name = "Charlie"; candidates[2].votes = %d;`,
+		alice, bob, charlie))
+}
 
-	// 4. 运行 vote 函数测试
-	voteTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"0", "0", "true", "vote returns true when given name of first candidate"},
-		{"0", "1", "true", "vote returns true when given name of middle candidate"},
-		{"0", "2", "true", "vote returns true when given name of last candidate"},
-		{"0", "3", "false", "vote returns false when given name of invalid candidate"},
-		{"0", "4", "1 0 0", "vote produces correct counts when all votes are zero"},
-		{"0", "5", "2 8 0", "vote produces correct counts after some have already voted"},
-		{"0", "6", "2 8 0", "vote leaves vote counts unchanged when voting for invalid candidate"},
+// pluralityVotes 检查三名候选人的票数
+func pluralityVotes(alice, bob, charlie int) []helpers.CStep {
+	return []helpers.CStep{
+		helpers.ExpectInt("candidates[0].votes", alice),
+		helpers.ExpectInt("candidates[1].votes", bob),
+		helpers.ExpectInt("candidates[2].votes", charlie),
 	}
+}
 
-	for _, tc := range voteTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "plurality_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+// pluralityChecks 是 plurality 的函数级检查 (对齐 CS50 check50)
+func pluralityChecks() []helpers.CFunctionCheck {
+	return []helpers.CFunctionCheck{
+		{Name: "vote returns true when given name of first candidate", Steps: []helpers.CStep{
+			pluralityCandidates(0, 0, 0), helpers.ExpectBool(`vote("Alice")`, true)}},
+		{Name: "vote returns true when given name of middle candidate", Steps: []helpers.CStep{
+			pluralityCandidates(0, 0, 0), helpers.ExpectBool(`vote("Bob")`, true)}},
+		{Name: "vote returns true when given name of last candidate", Steps: []helpers.CStep{
+			pluralityCandidates(0, 0, 0), helpers.ExpectBool(`vote("Charlie")`, true)}},
+		{Name: "vote returns false when given name of invalid candidate", Steps: []helpers.CStep{
+			pluralityCandidates(0, 0, 0), helpers.ExpectBool(`vote("David")`, false)}},
+		{Name: "vote produces correct counts when all votes are zero", Steps: append([]helpers.CStep{
+			pluralityCandidates(0, 0, 0), helpers.CStmt(`vote("Alice");`)},
+			pluralityVotes(1, 0, 0)...)},
+		{Name: "vote produces correct counts after some have already voted", Steps: append([]helpers.CStep{
+			pluralityCandidates(2, 7, 0), helpers.CStmt(`vote("Bob");`)},
+			pluralityVotes(2, 8, 0)...)},
+		{Name: "vote leaves vote counts unchanged when voting for invalid candidate", Steps: append([]helpers.CStep{
+			pluralityCandidates(2, 8, 0), helpers.ExpectBool(`vote("David")`, false)},
+			pluralityVotes(2, 8, 0)...)},
+		{Name: "print_winner identifies Alice as winner of election", Steps: []helpers.CStep{
+			pluralityCandidates(4, 2, 1), helpers.ExpectOutputLines("print_winner();", []string{"Alice"})}},
+		{Name: "print_winner identifies Bob as winner of election", Steps: []helpers.CStep{
+			pluralityCandidates(1, 5, 2), helpers.ExpectOutputLines("print_winner();", []string{"Bob"})}},
+		{Name: "print_winner identifies Charlie as winner of election", Steps: []helpers.CStep{
+			pluralityCandidates(2, 3, 7), helpers.ExpectOutputLines("print_winner();", []string{"Charlie"})}},
+		{Name: "print_winner prints multiple winners in case of tie", Steps: []helpers.CStep{
+			pluralityCandidates(5, 5, 2), helpers.ExpectOutputLines("print_winner();", []string{"Alice", "Bob"})}},
+		{Name: "print_winner prints all names when all candidates are tied", Steps: []helpers.CStep{
+			pluralityCandidates(3, 3, 3), helpers.ExpectOutputLines("print_winner();", []string{"Alice", "Bob", "Charlie"})}},
 	}
+}
 
-	// 5. 运行 print_winner 函数测试
-	winnerTests := []struct {
-		setup    string
-		test     string
-		expected []string // 期望的获胜者（可能多个）
+// pluralitySimulations 生成 plurality 的随机选举
+func pluralitySimulations() []electionSimulation {
+	elections := []struct {
 		name     string
+		election helpers.Election
 	}{
-		{"0", "7", []string{"Alice"}, "print_winner identifies Alice as winner of election"},
-		{"0", "8", []string{"Bob"}, "print_winner identifies Bob as winner of election"},
-		{"0", "9", []string{"Charlie"}, "print_winner identifies Charlie as winner of election"},
-		{"0", "10", []string{"Alice", "Bob"}, "print_winner prints multiple winners in case of tie"},
-		{"0", "11", []string{"Alice", "Bob", "Charlie"}, "print_winner prints all names when all candidates are tied"},
-	}
-
-	for _, tc := range winnerTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "plurality_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		// 检查输出是否包含所有期望的获胜者
-		output := r.GetStdout()
-		actualWinners := parseWinners(output)
-
-		if !winnersMatch(tc.expected, actualWinners) {
-			return fmt.Errorf("test failed for %s: expected winners %v, got %v", tc.name, tc.expected, actualWinners)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+		{"plurality simulation with random votes", helpers.RandomPluralityElection(3, random.RandomInt(5, 30), 0)},
+		{"plurality simulation with invalid votes", helpers.RandomPluralityElection(4, random.RandomInt(10, 30), 20)},
+		{"plurality simulation with a tie for first place", helpers.TiedPluralityElection(4, 2, random.RandomInt(3, 8))},
+		{"plurality simulation where all candidates tie", helpers.TiedPluralityElection(3, 3, random.RandomInt(1, 5))},
+		{"plurality simulation with maximum candidates", helpers.RandomPluralityElection(helpers.ElectionMaxCandidates, 500, 5)},
 	}
 
-	// 清理测试文件
-	os.Remove(testFilePath)
-	os.Remove(filepath.Join(workDir, "plurality_test"))
-
-	logger.Successf("All plurality tests passed!")
-	return nil
-}
-
-// parseWinners 从输出中解析获胜者名单
-func parseWinners(output string) []string {
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	winners := []string{}
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			winners = append(winners, line)
-		}
+	sims := make([]electionSimulation, 0, len(elections))
+	for _, e := range elections {
+		sims = append(sims, electionSimulation{e.name, e.election, helpers.PluralityWinners(e.election)})
 	}
-	return winners
+	return sims
 }
 
 // winnersMatch 检查两个获胜者列表是否匹配（顺序无关）
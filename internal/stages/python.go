@@ -0,0 +1,30 @@
+package stages
+
+import (
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/test_case_harness"
+)
+
+// resolvePython 找到提交使用的 Python 解释器 (见 helpers.ResolvePython)，检查 modules 能否导入
+func resolvePython(harness *test_case_harness.TestCaseHarness, modules ...helpers.PythonRequirement) (*helpers.PythonEnv, error) {
+	logger := harness.Logger
+	logger.Infof("Checking Python environment...")
+	env, err := helpers.ResolvePython(harness.SubmissionDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.CheckModules(harness.SubmissionDir, modules...); err != nil {
+		return nil, err
+	}
+	logger.Successf("Using %s", env)
+	return env, nil
+}
+
+// pythonCommand 返回可以传给 runner.Run(harness.SubmissionDir, ...) 的解释器路径
+func pythonCommand(harness *test_case_harness.TestCaseHarness) (string, error) {
+	env, err := resolvePython(harness)
+	if err != nil {
+		return "", err
+	}
+	return env.Command(harness.SubmissionDir)
+}
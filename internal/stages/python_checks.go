@@ -0,0 +1,56 @@
+package stages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+)
+
+// pythonMainCase 是直接调用 main() 的一个用例：以 stdin 为标准输入，输出应包含 expected
+type pythonMainCase struct {
+	name     string
+	stdin    string
+	expected string
+}
+
+// runPythonMainChecks 用函数驱动程序导入 module (不运行 __main__ 下的代码)，在同一个进程中直接调用 main() 并检查输出
+// 题目没有规定函数，所以模块没有 main 函数或导入时就运行了程序 (没有 if __name__ == "__main__":) 时跳过这些检查
+func runPythonMainChecks(logger *logger.Logger, python, workDir, module string, cases []pythonMainCase) error {
+	calls := make([]helpers.PythonCall, len(cases))
+	for i, tc := range cases {
+		calls[i] = helpers.PythonCall{Function: "main", Stdin: tc.stdin}
+	}
+	result, err := helpers.CallPythonFunctions(python, workDir, module, calls, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	switch {
+	case result.RanOnImport():
+		logger.Infof("%s runs when imported (no `if __name__ == \"__main__\":`), skipping main() checks", module)
+		return nil
+	case !result.Defines("main"):
+		logger.Infof("%s defines no main function, skipping main() checks", module)
+		return nil
+	}
+
+	for i, tc := range cases {
+		logger.Infof("Testing main() %s...", tc.name)
+		call := result.Calls[i]
+		if e := call.Exception; e != nil && !isCleanPythonExit(e) {
+			return fmt.Errorf("main() %s raised %v\n%s", tc.name, e, e.Traceback)
+		}
+		if !strings.Contains(call.Stdout, tc.expected) {
+			return fmt.Errorf("main() %s: expected output to contain %q, got %q", tc.name, tc.expected, call.Stdout)
+		}
+		logger.Successf("✓ main() %s", tc.name)
+	}
+	return nil
+}
+
+// isCleanPythonExit 判断异常是否为 sys.exit()、sys.exit(0) 或 sys.exit(None)
+func isCleanPythonExit(e *helpers.PythonException) bool {
+	return e.Type == "SystemExit" && (e.Message == "" || e.Message == "0" || e.Message == "None")
+}
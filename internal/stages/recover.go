@@ -1,12 +1,17 @@
 package stages
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
@@ -155,7 +160,28 @@ func testRecover(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("✓ recovers 049.jpg correctly")
 
-	// 9. 内存检查 (valgrind) - 清理后重新运行
+	// 9. 检查没有多余的文件 (如 050.jpg)
+	logger.Infof("Testing recovers exactly 50 images...")
+	if err := checkRecoveredFileNames(workDir, len(recoverHashes), "card.raw", "recover"); err != nil {
+		return err
+	}
+	logger.Successf("✓ recovers exactly 50 images")
+
+	// 10. 在生成的存储卡镜像上运行 recover
+	recoverPath, err := filepath.Abs(filepath.Join(workDir, "recover"))
+	if err != nil {
+		return err
+	}
+	for _, tc := range forensicImageCases {
+		logger.Infof("Testing %s...", tc.name)
+		image := helpers.GenerateForensicImage(tc.opts)
+		if err := runRecoverOnImage(recoverPath, image); err != nil {
+			return fmt.Errorf("%s: %v", tc.name, err)
+		}
+		logger.Successf("✓ %s", tc.name)
+	}
+
+	// 11. 内存检查 (valgrind) - 清理后重新运行
 	// 先清理之前生成的 JPEG 文件
 	for i := 0; i < 50; i++ {
 		os.Remove(filepath.Join(workDir, fmt.Sprintf("%03d.jpg", i)))
@@ -184,3 +210,113 @@ func testRecover(harness *test_case_harness.TestCaseHarness) error {
 	logger.Successf("All recover tests passed!")
 	return nil
 }
+
+// forensicImageCases 是生成的存储卡镜像测试
+var forensicImageCases = []struct {
+	name string
+	opts helpers.ForensicImageOptions
+}{
+	{
+		name: "handles card without any JPEGs",
+		opts: helpers.ForensicImageOptions{JPEGCount: 0, LeadingBlocks: 8},
+	},
+	{
+		name: "recovers single JPEG ending exactly at block boundary",
+		opts: helpers.ForensicImageOptions{JPEGCount: 1, LeadingBlocks: 2, MaxBlocksPerJPEG: 4, AlignedEnds: true},
+	},
+	{
+		name: "recovers JPEGs with all signature variants (0xe0-0xef)",
+		opts: helpers.ForensicImageOptions{JPEGCount: 16, LeadingBlocks: 3, MaxBlocksPerJPEG: 3},
+	},
+	{
+		name: "recovers JPEGs with slack space and trailing free blocks",
+		opts: helpers.ForensicImageOptions{JPEGCount: 23, LeadingBlocks: 5, TrailingBlocks: 4, MaxBlocksPerJPEG: 6},
+	},
+}
+
+// recoveredNamePattern 是 recover 输出文件名的格式 (###.jpg)
+var recoveredNamePattern = regexp.MustCompile(`^\d{3}\.jpg$`)
+
+// jpegExtensions 是 JPEG 文件的扩展名 (不区分大小写)，这些文件都必须命名为 ###.jpg
+var jpegExtensions = []string{".jpg", ".jpeg", ".jpe", ".jfif"}
+
+// runRecoverOnImage 在临时目录中对生成的镜像运行 recover，并校验恢复出的文件
+func runRecoverOnImage(recoverPath string, image helpers.ForensicImage) error {
+	tempDir, err := os.MkdirTemp("", "recover_test_*")
+	if err != nil {
+		return fmt.Errorf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "card.raw"), image.Raw, 0644); err != nil {
+		return fmt.Errorf("could not write card.raw: %v", err)
+	}
+
+	cmd := exec.Command(recoverPath, "card.raw")
+	cmd.Dir = tempDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("recover failed: %s\n%s", err, string(out))
+	}
+
+	if err := checkRecoveredFileNames(tempDir, len(image.Files), "card.raw"); err != nil {
+		return err
+	}
+
+	for i, expected := range image.Files {
+		filename := fmt.Sprintf("%03d.jpg", i)
+		actual, err := os.ReadFile(filepath.Join(tempDir, filename))
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", filename, err)
+		}
+		if !bytes.Equal(actual, expected) {
+			return fmt.Errorf("%s: recovered image does not match (%s)", filename, describeByteMismatch(expected, actual))
+		}
+	}
+	return nil
+}
+
+// checkRecoveredFileNames 检查目录中恰好有 000.jpg 到 (count-1).jpg，且没有其他 JPEG 文件 (如 000.JPG、000.jpeg)
+// ignore 中列出的文件 (输入文件、编译产物等) 不参与检查
+func checkRecoveredFileNames(dir string, count int, ignore ...string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("could not list %s: %v", dir, err)
+	}
+
+	var recovered []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || contains(ignore, name) || !contains(jpegExtensions, strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		if !recoveredNamePattern.MatchString(name) {
+			return fmt.Errorf("%s is not named ###.jpg (e.g. 000.jpg)", name)
+		}
+		recovered = append(recovered, name)
+	}
+	sort.Strings(recovered)
+
+	if len(recovered) != count {
+		return fmt.Errorf("expected %d JPEGs to be recovered, found %d: %v", count, len(recovered), recovered)
+	}
+	for i, name := range recovered {
+		if expected := fmt.Sprintf("%03d.jpg", i); name != expected {
+			return fmt.Errorf("expected %s, found %s (files must be numbered consecutively from 000.jpg)", expected, name)
+		}
+	}
+	return nil
+}
+
+// describeByteMismatch 描述两个文件第一个不同的位置
+func describeByteMismatch(expected, actual []byte) string {
+	n := len(expected)
+	if len(actual) < n {
+		n = len(actual)
+	}
+	for i := 0; i < n; i++ {
+		if expected[i] != actual[i] {
+			return fmt.Sprintf("first difference at byte %d (block %d)", i, i/helpers.ForensicBlockSize)
+		}
+	}
+	return fmt.Sprintf("expected %d bytes, got %d bytes", len(expected), len(actual))
+}
@@ -2,15 +2,13 @@ package stages
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/bootcs-cn/tester-utils/runner"
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
@@ -43,230 +41,212 @@ func testRunoff(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("runoff compiles")
 
-	// 3. 创建测试程序
-	// 读取学生的 runoff.c，将 main 重命名为 distro_main
-	runoffCode, err := harness.ReadFile("runoff.c")
-	if err != nil {
-		return fmt.Errorf("could not read runoff.c: %v", err)
+	// 3. 运行 vote、tabulate、print_winner、find_min、is_tie 和 eliminate 函数测试
+	if err := runCFunctionChecks(logger, workDir, "runoff.c", runoffChecks(), "-I..", "-lm", "-Wall"); err != nil {
+		return err
 	}
 
-	// 使用正则替换 main 函数
-	mainRegex := regexp.MustCompile(`int\s+main\s*\(`)
-	modifiedCode := mainRegex.ReplaceAllString(string(runoffCode), "int distro_main(")
-
-	// 从学生目录读取测试代码
-	testCodeBytes, err := harness.ReadFile("runoff_test.c")
+	// 4. 端到端随机选举模拟
+	sims, err := runoffSimulations()
 	if err != nil {
-		return fmt.Errorf("runoff_test.c does not exist: %v", err)
+		return err
 	}
-	testCode := string(testCodeBytes)
-	combinedCode := modifiedCode + "\n" + testCode
-	testFilePath := filepath.Join(workDir, "runoff_combined_test.c")
-	if err := os.WriteFile(testFilePath, []byte(combinedCode), 0644); err != nil {
-		return fmt.Errorf("could not write test file: %v", err)
-	}
-
-	// 编译测试程序
-	logger.Infof("Compiling test harness...")
-	cmd = exec.Command("clang", "-o", "runoff_test", "runoff_combined_test.c", "-I..", "-lm", "-Wall")
-	cmd.Dir = workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("test harness does not compile: %s\n%s", err, string(out))
+	if err := runElectionSimulations(logger, workDir, "runoff", sims); err != nil {
+		return err
 	}
-	logger.Successf("test harness compiles")
 
-	// 4. 运行 vote 函数测试
-	voteTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"0", "0", "true", "vote returns true when given name of valid candidate"},
-		{"0", "1", "false", "vote returns false when given name of invalid candidate"},
-		{"0", "2", "2", "vote correctly sets first preference"},
-		{"0", "3", "0", "vote correctly sets third preference"},
-		{"0", "4", "1 0 2", "vote correctly sets all preferences"},
-	}
-
-	for _, tc := range voteTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
+	logger.Successf("All runoff tests passed!")
+	return nil
+}
 
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
+// runoffNames 是函数级检查中候选人的名字
+var runoffNames = []string{"Alice", "Bob", "Charlie", "David"}
 
-		logger.Successf("✓ %s", tc.name)
+// runoffCandidates 设置 voters 名选民和前 len(votes) 名候选人及其票数，所有候选人都未被淘汰
+func runoffCandidates(voters int, votes ...int) helpers.CStep {
+	code := fmt.Sprintf("voter_count = %d; candidate_count = %d;", voters, len(votes))
+	for i, v := range votes {
+		code += fmt.Sprintf(` candidates[%d].name = "%s"; candidates[%d].votes = %d; candidates[%d].eliminated = false;`,
+			i, runoffNames[i], i, v, i)
 	}
+	return helpers.CStmt(code)
+}
 
-	// 5. 运行 tabulate 函数测试
-	tabulateTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"1", "5", "3 3 1 0 ", "tabulate counts votes when all candidates are in election"},
-		{"1", "6", "3 3 1 0 ", "tabulate counts votes when one candidate is eliminated"},
-		{"1", "7", "3 4 0 0 ", "tabulate counts votes when multiple candidates are eliminated"},
-		{"1", "22", "3 4 0 0 ", "tabulate counts votes when multiple rounds have occurred"},
+// runoffEliminate 把候选人标记为已淘汰
+func runoffEliminate(indexes ...int) helpers.CStep {
+	code := ""
+	for _, i := range indexes {
+		code += fmt.Sprintf("candidates[%d].eliminated = true; ", i)
 	}
+	return helpers.CStmt(code)
+}
 
-	for _, tc := range tabulateTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
+// runoffBallots 把选票写入 preferences (每张选票是候选人编号的排名)
+func runoffBallots(ballots [][]int) helpers.CStep {
+	code := ""
+	for v, ballot := range ballots {
+		for r, c := range ballot {
+			code += fmt.Sprintf("preferences[%d][%d] = %d; ", v, r, c)
 		}
-
-		logger.Successf("✓ %s", tc.name)
 	}
+	return helpers.CStmt(code)
+}
 
-	// 6. 运行 print_winner 函数测试
-	logger.Infof("Testing print_winner prints name of candidate with > 50%% votes...")
-	r := runner.Run(workDir, "runoff_test", "2", "8").
-		WithTimeout(5 * time.Second).
-		Execute().
-		Exit(0)
-	if err := r.Error(); err != nil {
-		return fmt.Errorf("test failed for print_winner: %v", err)
-	}
-	stdout := strings.TrimSpace(r.GetStdout())
-	if stdout != "Bob" {
-		return fmt.Errorf("print_winner did not print correct winner: expected 'Bob', got '%s'", stdout)
-	}
-	logger.Successf("✓ print_winner prints name of candidate with > 50%% votes")
-
-	printWinnerTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"2", "9", "Bob\ntrue", "print_winner returns true when someone has > 50% votes"},
-		{"2", "10", "false", "print_winner returns false when no one has > 50% votes"},
-		{"2", "11", "false", "print_winner returns false when exactly 50% votes"},
-	}
-
-	for _, tc := range printWinnerTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
+// runoffTabulateBallots 是 tabulate 检查的选票：全部候选人参选时 Alice、Bob、Charlie、David 的首选票为 3、3、1、0，
+// 最后一张选票淘汰 Charlie 和 David 后要跳过两个排名才能计给 Bob
+var runoffTabulateBallots = [][]int{
+	{0, 1, 2, 3}, {0, 1, 2, 3}, {0, 2, 1, 3},
+	{1, 0, 2, 3}, {1, 2, 0, 3}, {1, 0, 3, 2},
+	{2, 3, 1, 0},
+}
 
-		logger.Successf("✓ %s", tc.name)
+// runoffVotes 检查候选人的票数
+func runoffVotes(votes ...int) []helpers.CStep {
+	steps := make([]helpers.CStep, len(votes))
+	for i, v := range votes {
+		steps[i] = helpers.ExpectInt(fmt.Sprintf("candidates[%d].votes", i), v)
 	}
+	return steps
+}
 
-	// 7. 运行 find_min 函数测试
-	findMinTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"2", "12", "1", "find_min returns minimum votes"},
-		{"2", "13", "7", "find_min returns minimum when all tied"},
-		{"2", "14", "4", "find_min ignores eliminated candidates"},
+// runoffEliminated 检查候选人是否被淘汰
+func runoffEliminated(eliminated ...bool) []helpers.CStep {
+	steps := make([]helpers.CStep, len(eliminated))
+	for i, e := range eliminated {
+		steps[i] = helpers.ExpectBool(fmt.Sprintf("candidates[%d].eliminated", i), e)
 	}
+	return steps
+}
 
-	for _, tc := range findMinTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+// runoffTabulate 设置 tabulate 检查的候选人和选票，淘汰 eliminated 后运行 tabulate
+func runoffTabulate(eliminated ...int) []helpers.CStep {
+	return []helpers.CStep{
+		runoffCandidates(len(runoffTabulateBallots), 0, 0, 0, 0),
+		runoffBallots(runoffTabulateBallots),
+		runoffEliminate(eliminated...),
+		helpers.CStmt("tabulate();"),
 	}
+}
 
-	// 8. 运行 is_tie 函数测试
-	isTieTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"2", "15", "true", "is_tie returns true when all candidates are tied"},
-		{"2", "16", "false", "is_tie returns false when not tied"},
-		{"2", "17", "false", "is_tie returns false when only some candidates are tied"},
-		{"2", "18", "true", "is_tie ignores eliminated candidates when checking tie"},
-	}
-
-	for _, tc := range isTieTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+// runoffChecks 是 runoff 的函数级检查 (对齐 CS50 check50)
+func runoffChecks() []helpers.CFunctionCheck {
+	return []helpers.CFunctionCheck{
+		{Name: "vote returns true when given name of valid candidate", Steps: []helpers.CStep{
+			runoffCandidates(3, 0, 0, 0), helpers.ExpectBool(`vote(0, 0, "Alice")`, true)}},
+		{Name: "vote returns false when given name of invalid candidate", Steps: []helpers.CStep{
+			runoffCandidates(3, 0, 0, 0), helpers.ExpectBool(`vote(0, 0, "David")`, false)}},
+		{Name: "vote correctly sets first preference for first voter", Steps: []helpers.CStep{
+			runoffCandidates(3, 0, 0, 0), helpers.CStmt(`vote(0, 0, "Charlie");`),
+			helpers.ExpectInt("preferences[0][0]", 2)}},
+		{Name: "vote correctly sets third preference for second voter", Steps: []helpers.CStep{
+			runoffCandidates(3, 0, 0, 0), helpers.CStmt(`vote(1, 2, "Alice");`),
+			helpers.ExpectInt("preferences[1][2]", 0)}},
+		{Name: "vote correctly sets all preferences for voter", Steps: []helpers.CStep{
+			runoffCandidates(3, 0, 0, 0), helpers.CStmt(`vote(0, 0, "Bob"); vote(0, 1, "Alice"); vote(0, 2, "Charlie");`),
+			helpers.ExpectIntArray("preferences[0]", []int{1, 0, 2})}},
+
+		{Name: "tabulate counts votes when all candidates remain in election",
+			Steps: cSteps(runoffTabulate(), runoffVotes(3, 3, 1, 0))},
+		{Name: "tabulate counts votes when one candidate is eliminated",
+			Steps: cSteps(runoffTabulate(3), runoffVotes(3, 3, 1, 0))},
+		{Name: "tabulate counts votes when multiple candidates are eliminated",
+			Steps: cSteps(runoffTabulate(2, 3), runoffVotes(3, 4, 0, 0))},
+		{Name: "tabulate handles multiple rounds of preferences", Steps: cSteps(runoffTabulate(), []helpers.CStep{
+			// 与 main 一样，每轮淘汰后把票数清零再重新统计
+			helpers.CStmt("for (int i = 0; i < candidate_count; i++) { candidates[i].votes = 0; }"),
+			runoffEliminate(3), helpers.CStmt("tabulate();"),
+			helpers.CStmt("for (int i = 0; i < candidate_count; i++) { candidates[i].votes = 0; }"),
+			runoffEliminate(2), helpers.CStmt("tabulate();"),
+		}, runoffVotes(3, 4, 0, 0))},
+
+		{Name: "print_winner prints name when someone has a majority", Steps: []helpers.CStep{
+			runoffCandidates(9, 2, 5, 2), helpers.ExpectOutput("print_winner();", "Bob")}},
+		{Name: "print_winner returns true when someone has a majority", Steps: []helpers.CStep{
+			runoffCandidates(9, 2, 5, 2), helpers.ExpectBool("print_winner()", true)}},
+		{Name: "print_winner returns false when nobody has a majority", Steps: []helpers.CStep{
+			runoffCandidates(9, 4, 3, 2), helpers.ExpectOutput("bool won = print_winner();", ""),
+			helpers.ExpectBool("won", false)}},
+		{Name: "print_winner returns false when leader has exactly 50% of vote", Steps: []helpers.CStep{
+			runoffCandidates(8, 4, 3, 1), helpers.ExpectOutput("bool won = print_winner();", ""),
+			helpers.ExpectBool("won", false)}},
+
+		{Name: "find_min returns minimum number of votes for candidate", Steps: []helpers.CStep{
+			runoffCandidates(8, 4, 1, 3), helpers.ExpectInt("find_min()", 1)}},
+		{Name: "find_min returns minimum when all candidates are tied", Steps: []helpers.CStep{
+			runoffCandidates(21, 7, 7, 7), helpers.ExpectInt("find_min()", 7)}},
+		{Name: "find_min ignores eliminated candidates", Steps: []helpers.CStep{
+			runoffCandidates(11, 1, 4, 6), runoffEliminate(0), helpers.ExpectInt("find_min()", 4)}},
+
+		{Name: "is_tie returns true when election is tied", Steps: []helpers.CStep{
+			runoffCandidates(9, 3, 3, 3), helpers.ExpectBool("is_tie(3)", true)}},
+		{Name: "is_tie returns false when election is not tied", Steps: []helpers.CStep{
+			runoffCandidates(9, 5, 3, 1), helpers.ExpectBool("is_tie(1)", false)}},
+		{Name: "is_tie returns false when only some of the candidates are tied", Steps: []helpers.CStep{
+			runoffCandidates(7, 1, 3, 3), helpers.ExpectBool("is_tie(1)", false)}},
+		{Name: "is_tie detects tie after some candidates have been eliminated", Steps: []helpers.CStep{
+			runoffCandidates(9, 1, 4, 4), runoffEliminate(0), helpers.ExpectBool("is_tie(4)", true)}},
+
+		{Name: "eliminate eliminates candidate in last place", Steps: append([]helpers.CStep{
+			runoffCandidates(13, 3, 4, 5, 1), helpers.CStmt("eliminate(1);")},
+			runoffEliminated(false, false, false, true)...)},
+		{Name: "eliminate eliminates multiple candidates in tie for last", Steps: append([]helpers.CStep{
+			runoffCandidates(9, 1, 4, 1, 3), helpers.CStmt("eliminate(1);")},
+			runoffEliminated(true, false, true, false)...)},
+		{Name: "eliminate eliminates candidates after some already eliminated", Steps: append([]helpers.CStep{
+			runoffCandidates(12, 1, 5, 2, 4), runoffEliminate(0), helpers.CStmt("eliminate(2);")},
+			runoffEliminated(true, false, true, false)...)},
 	}
+}
 
-	// 9. 运行 eliminate 函数测试
-	eliminateTests := []struct {
-		setup    string
-		test     string
-		expected string
+// runoffSimulations 生成 runoff 的随机选举 (首轮获胜、需要淘汰、淘汰后平局、全部平局和最大规模)
+func runoffSimulations() ([]electionSimulation, error) {
+	scenarios := []struct {
 		name     string
+		generate func() helpers.Election
+		accept   func(winners []string, rounds int) bool
 	}{
-		{"2", "19", "false false false true ", "eliminate eliminates candidate with minimum votes"},
-		{"2", "20", "true false true false ", "eliminate eliminates multiple candidates tied for last"},
-		{"2", "21", "true false true false ", "eliminate correctly identifies who to eliminate after some already eliminated"},
+		{
+			"runoff simulation decided in the first round",
+			func() helpers.Election { return helpers.RandomRankedElection(3, random.RandomInt(5, 15)) },
+			func(winners []string, rounds int) bool { return len(winners) == 1 && rounds == 1 },
+		},
+		{
+			"runoff simulation requiring eliminations",
+			func() helpers.Election { return helpers.RandomRankedElection(5, random.RandomInt(10, 40)) },
+			func(winners []string, rounds int) bool { return len(winners) == 1 && rounds > 1 },
+		},
+		{
+			"runoff simulation ending in a tie after eliminations",
+			func() helpers.Election { return helpers.RandomRankedElection(4, random.RandomInt(8, 16)) },
+			func(winners []string, rounds int) bool { return len(winners) > 1 && rounds > 1 },
+		},
+		{
+			"runoff simulation where all candidates tie",
+			func() helpers.Election { return helpers.TiedRankedElection(3, random.RandomInt(1, 4)) },
+			func(winners []string, rounds int) bool { return len(winners) == 3 },
+		},
+		{
+			"runoff simulation with maximum candidates and voters",
+			func() helpers.Election {
+				return helpers.RandomRankedElection(helpers.ElectionMaxCandidates, helpers.RunoffMaxVoters)
+			},
+			func(winners []string, rounds int) bool { return true },
+		},
 	}
 
-	for _, tc := range eliminateTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "runoff_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
+	sims := make([]electionSimulation, 0, len(scenarios))
+	for _, sc := range scenarios {
+		accept := sc.accept
+		e, err := generateElection(sc.generate, func(e helpers.Election) bool {
+			return accept(helpers.RunoffWinners(e))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sc.name, err)
 		}
-
-		logger.Successf("✓ %s", tc.name)
+		winners, _ := helpers.RunoffWinners(e)
+		sims = append(sims, electionSimulation{sc.name, e, winners})
 	}
-
-	// 清理测试文件
-	os.Remove(testFilePath)
-	os.Remove(filepath.Join(workDir, "runoff_test"))
-
-	logger.Successf("All runoff tests passed!")
-	return nil
+	return sims, nil
 }
 
 // parseRunoffWinners 从输出中解析获胜者名单
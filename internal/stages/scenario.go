@@ -0,0 +1,364 @@
+package stages
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+	"github.com/bootcs-cn/tester-utils/test_case_harness"
+)
+
+//go:embed scenarios/*.yaml
+var scenarioFiles embed.FS
+
+// flaskApp describes a Flask problem whose checks are a scenario in scenarios/
+type flaskApp struct {
+	// Scenario is the file name in scenarios/, e.g. "finance.yaml"
+	Scenario string
+	// Database is the app's SQLite file, recreated by ResetDatabase before the app starts
+	Database      string
+	ResetDatabase func(path string) error
+	// Modules must be importable by the app's Python before it is started
+	Modules []helpers.PythonRequirement
+}
+
+// scenarioRunner executes scenario steps against a running Flask app
+type scenarioRunner struct {
+	logger   *logger.Logger
+	baseURL  string
+	server   *flaskServer
+	workDir  string
+	dbPath   string
+	quotes   *helpers.QuoteServer
+	sessions map[string]*httpClient
+	vars     helpers.ScenarioVars
+}
+
+// loadScenario reads and parses an embedded scenario
+func loadScenario(name string) (*helpers.HTTPScenario, error) {
+	data, err := scenarioFiles.ReadFile("scenarios/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario %s: %v", name, err)
+	}
+	return helpers.ParseHTTPScenario(data)
+}
+
+// runFlaskScenario starts the app and runs its scenario
+func runFlaskScenario(harness *test_case_harness.TestCaseHarness, app flaskApp) error {
+	scenario, err := loadScenario(app.Scenario)
+	if err != nil {
+		return err
+	}
+	runner, err := startFlaskApp(harness, app, scenario)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(runner.workDir)
+
+	for _, statement := range scenario.Vars {
+		if err := runner.vars.Assign(statement); err != nil {
+			return err
+		}
+	}
+	for _, step := range scenario.Steps {
+		if err := runner.run(step); err != nil {
+			return err
+		}
+	}
+
+	harness.Logger.Successf("All tests passed!")
+	return nil
+}
+
+// startFlaskApp copies the submission to a temp dir, resets its database and
+// starts the app (and a quote server if the scenario needs one);
+// the caller removes runner.workDir when done
+func startFlaskApp(harness *test_case_harness.TestCaseHarness, app flaskApp, scenario *helpers.HTTPScenario) (runner *scenarioRunner, err error) {
+	logger := harness.Logger
+
+	// Convert to absolute path
+	workDir, err := filepath.Abs(harness.SubmissionDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+	logger.Infof("Working directory: %s", workDir)
+
+	// Check app.py exists
+	logger.Infof("Checking app.py exists...")
+	if !harness.FileExists("app.py") {
+		return nil, fmt.Errorf("app.py does not exist")
+	}
+	logger.Successf("app.py exists")
+
+	python, err := resolvePython(harness, app.Modules...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy all files to a temp dir to avoid modifying the original
+	tempDir, err := os.MkdirTemp("", scenario.Name+"_test_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	if err := copyDir(workDir, tempDir); err != nil {
+		return nil, fmt.Errorf("failed to copy files to temp dir: %v", err)
+	}
+	dbPath := filepath.Join(tempDir, app.Database)
+	if app.ResetDatabase != nil {
+		if err := app.ResetDatabase(dbPath); err != nil {
+			return nil, fmt.Errorf("failed to reset database: %v", err)
+		}
+	}
+
+	runner = &scenarioRunner{
+		logger:   logger,
+		workDir:  tempDir,
+		dbPath:   dbPath,
+		sessions: make(map[string]*httpClient),
+		vars:     helpers.ScenarioVars{},
+	}
+
+	// Start the mock quote server with random prices, so prices cannot be hard-coded
+	var env []string
+	if len(scenario.Quotes) > 0 {
+		var quotes []helpers.Quote
+		for _, q := range scenario.Quotes {
+			quotes = append(quotes, helpers.Quote{Symbol: q.Symbol, Name: q.Name, Cents: helpers.RandomQuoteCents()})
+		}
+		runner.quotes, err = helpers.NewQuoteServer(quotes...)
+		if err != nil {
+			return nil, err
+		}
+		harness.RegisterTeardownFunc(runner.quotes.Close)
+		logger.Infof("Quote server listening at %s", runner.quotes.URL)
+		env = append(env, fmt.Sprintf("%s=%s", helpers.QuoteURLEnv, runner.quotes.URL))
+		// Transition release: helpers.py from before BOOTCS_QUOTE_URL only mocks lookup
+		// when BOOTCS_TEST_MODE is set, so keep setting it until those copies are updated
+		env = append(env, "BOOTCS_TEST_MODE=1")
+	}
+
+	// Find an available port
+	port, err := findAvailablePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find available port: %v", err)
+	}
+
+	// Start Flask server
+	logger.Infof("Starting Flask server on port %d...", port)
+	server, err := startFlaskServer(python.Path, tempDir, port, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Flask server: %v", err)
+	}
+	harness.RegisterTeardownFunc(func() { server.stop() })
+	runner.server = server
+	runner.baseURL = server.baseURL
+	logger.Successf("Flask server started")
+	return runner, nil
+}
+
+// sessionName returns the name of a step's session, "default" if it has none
+func sessionName(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// session returns the client for a named session, creating it on first use
+func (r *scenarioRunner) session(name string) (*httpClient, error) {
+	name = sessionName(name)
+	if client, ok := r.sessions[name]; ok {
+		return client, nil
+	}
+	client, err := newHTTPClient(r.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+	r.sessions[name] = client
+	return client, nil
+}
+
+// run executes one step: new prices, variables, the request(s), then the expectations;
+// a failure carries the server's traceback, if the step caused one
+func (r *scenarioRunner) run(step helpers.ScenarioStep) error {
+	r.logger.Infof("Testing %s...", step.Test)
+	mark := r.server.logs.Mark()
+	if err := r.runStep(step); err != nil {
+		err = fmt.Errorf("%v%s", err, r.server.diagnose(mark))
+		if step.Hint != "" {
+			return fmt.Errorf("%v\n%s", err, step.Hint)
+		}
+		return err
+	}
+	r.logger.Successf("%s", step.Pass)
+	return nil
+}
+
+// runStep sends the requests of a step and checks the expectations
+func (r *scenarioRunner) runStep(step helpers.ScenarioStep) error {
+	if step.Clone != "" {
+		source, err := r.session(step.Clone)
+		if err != nil {
+			return err
+		}
+		if r.sessions[sessionName(step.Session)], err = source.clone(); err != nil {
+			return err
+		}
+	}
+	client, err := r.session(step.Session)
+	if err != nil {
+		return err
+	}
+	if step.TamperCookies {
+		client.tamperCookies()
+	}
+
+	if r.quotes != nil {
+		for _, symbol := range step.Quotes {
+			r.quotes.SetPrice(symbol, helpers.RandomQuoteCents(r.quotes.Price(symbol)))
+		}
+	}
+	r.updatePrices()
+	for _, statement := range step.Let {
+		if err := r.vars.Assign(statement); err != nil {
+			return err
+		}
+	}
+
+	lookups := make(map[string]int)
+	for _, symbol := range step.Expect.Lookups {
+		lookups[symbol] = r.quotes.Requests(symbol)
+	}
+
+	before, err := r.inspectDatabase(step.Expect)
+	if err != nil {
+		return err
+	}
+
+	method := step.Method()
+	for _, call := range step.Requests() {
+		var resp *http.Response
+		var body string
+		if method == "POST" {
+			resp, body, err = client.postForm(call.Path, call.Form)
+		} else {
+			path := call.Path
+			if len(call.Form) > 0 {
+				path += "?" + call.Form.Encode()
+			}
+			resp, body, err = client.get(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s: %v", method, call.Path, err)
+		}
+
+		response := &helpers.ScenarioResponse{
+			Method:   method,
+			Path:     call.Path,
+			Status:   resp.StatusCode,
+			Location: resp.Header.Get("Location"),
+			Body:     body,
+		}
+		if err := step.Expect.Check(response, r.vars); err != nil {
+			if len(step.Cases) > 0 {
+				return fmt.Errorf("%v (form: %s)", err, call.Form.Encode())
+			}
+			return err
+		}
+	}
+
+	for _, symbol := range step.Expect.Lookups {
+		if r.quotes.Requests(symbol) == lookups[symbol] {
+			return fmt.Errorf("%s did not request %s from the quote server; lookup should use %s",
+				step.Description(), symbol, helpers.QuoteURLEnv)
+		}
+	}
+	return r.checkDatabase(step, before)
+}
+
+// updatePrices exposes the current quote of every symbol as price.SYMBOL
+func (r *scenarioRunner) updatePrices() {
+	if r.quotes == nil {
+		return
+	}
+	for _, symbol := range r.quotes.Symbols() {
+		r.vars["price."+symbol] = r.quotes.Price(symbol)
+	}
+}
+
+// dbState is what a step records about the database before its request
+type dbState struct {
+	snapshot     map[string]string
+	transactions []helpers.FinanceTransactionsMark
+}
+
+// inspectDatabase records the state that the step's no_writes and transactions checks compare against
+func (r *scenarioRunner) inspectDatabase(expect helpers.ScenarioExpect) (*dbState, error) {
+	if !expect.NoWrites && len(expect.Transactions) == 0 {
+		return nil, nil
+	}
+	db, err := helpers.OpenSQLiteReadOnly(r.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	state := &dbState{}
+	if expect.NoWrites {
+		if state.snapshot, err = helpers.SnapshotDatabase(db); err != nil {
+			return nil, err
+		}
+	}
+	if len(expect.Transactions) > 0 {
+		if state.transactions, err = helpers.MarkFinanceTransactions(db); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// checkDatabase runs the database checks of a step on a read-only connection
+func (r *scenarioRunner) checkDatabase(step helpers.ScenarioStep, before *dbState) error {
+	if len(step.Expect.DB) == 0 && before == nil {
+		return nil
+	}
+	db, err := helpers.OpenSQLiteReadOnly(r.dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	request := step.Description()
+	if step.Expect.NoWrites {
+		after, err := helpers.SnapshotDatabase(db)
+		if err != nil {
+			return err
+		}
+		if changed := helpers.ChangedTables(before.snapshot, after); len(changed) > 0 {
+			return fmt.Errorf("%s was rejected, but it changed the %s table(s) in %s; rejected requests should not write to the database",
+				request, strings.Join(changed, ", "), filepath.Base(r.dbPath))
+		}
+	}
+	if len(step.Expect.Transactions) > 0 {
+		table, err := helpers.CheckNewTransactions(db, before.transactions, step.Expect.Transactions, r.vars)
+		if err != nil {
+			return fmt.Errorf("%s: %s table: %v", request, table, err)
+		}
+	}
+	for _, check := range step.Expect.DB {
+		if err := check.Check(db, r.vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
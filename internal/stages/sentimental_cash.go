@@ -28,6 +28,12 @@ func testSentimentalCash(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("cash.py exists")
 
+	// 找到并检查 Python 解释器 (.venv、BCS_PYTHON 或系统 python3)
+	python, err := pythonCommand(harness)
+	if err != nil {
+		return err
+	}
+
 	// 2. 测试有效输入（对齐 CS50 check50，使用浮点数美元）
 	validTests := []struct {
 		input    string
@@ -45,7 +51,7 @@ func testSentimentalCash(harness *test_case_harness.TestCaseHarness) error {
 	for _, tc := range validTests {
 		logger.Infof("Testing %s...", tc.name)
 
-		r := runner.Run(workDir, "python3", "cash.py").
+		r := runner.Run(workDir, python, "cash.py").
 			WithTimeout(5 * time.Second).
 			Stdin(tc.input).
 			Stdout(tc.expected).
@@ -71,7 +77,7 @@ func testSentimentalCash(harness *test_case_harness.TestCaseHarness) error {
 	for _, tc := range rejectTests {
 		logger.Infof("Testing %s...", tc.name)
 
-		r := runner.Run(workDir, "python3", "cash.py").
+		r := runner.Run(workDir, python, "cash.py").
 			WithTimeout(5 * time.Second).
 			WithPty().
 			Start().
@@ -87,6 +93,16 @@ func testSentimentalCash(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ %s", tc.name)
 	}
 
+	// 4. 直接调用 main() (导入 cash.py 时不运行 __main__ 下的代码)，最后一个用例先输入无效的值再输入有效的值
+	mainCases := []pythonMainCase{
+		{"with input 0.41", "0.41\n", "4"},
+		{"with input 23", "23\n", "92"},
+		{"with inputs -1, foo and \"\" before 4.2", "-1\nfoo\n\n4.2\n", "18"},
+	}
+	if err := runPythonMainChecks(logger, python, workDir, "cash.py", mainCases); err != nil {
+		return err
+	}
+
 	logger.Successf("All tests passed!")
 	return nil
 }
@@ -28,6 +28,12 @@ func testSentimentalCredit(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("credit.py exists")
 
+	// 找到并检查 Python 解释器 (.venv、BCS_PYTHON 或系统 python3)
+	python, err := pythonCommand(harness)
+	if err != nil {
+		return err
+	}
+
 	// 2. 测试用例 (对齐 CS50 check50)
 	tests := []struct {
 		input    string
@@ -56,7 +62,7 @@ func testSentimentalCredit(harness *test_case_harness.TestCaseHarness) error {
 	for _, tc := range tests {
 		logger.Infof("Testing %s...", tc.name)
 
-		r := runner.Run(workDir, "python3", "credit.py").
+		r := runner.Run(workDir, python, "credit.py").
 			WithTimeout(5 * time.Second).
 			Stdin(tc.input).
 			Stdout(tc.expected).
@@ -69,6 +75,15 @@ func testSentimentalCredit(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ %s", tc.name)
 	}
 
+	// 3. 直接调用 main() (导入 credit.py 时不运行 __main__ 下的代码)
+	var mainCases []pythonMainCase
+	for _, tc := range tests {
+		mainCases = append(mainCases, pythonMainCase{tc.name, tc.input, tc.expected})
+	}
+	if err := runPythonMainChecks(logger, python, workDir, "credit.py", mainCases); err != nil {
+		return err
+	}
+
 	logger.Successf("All tests passed!")
 	return nil
 }
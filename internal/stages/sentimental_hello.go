@@ -28,6 +28,12 @@ func testSentimentalHello(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("hello.py exists")
 
+	// 找到并检查 Python 解释器 (.venv、BCS_PYTHON 或系统 python3)
+	python, err := pythonCommand(harness)
+	if err != nil {
+		return err
+	}
+
 	// 2. 测试用例：对齐 CS50 check50 官方测试
 	testCases := []struct {
 		name     string
@@ -41,7 +47,7 @@ func testSentimentalHello(harness *test_case_harness.TestCaseHarness) error {
 	for _, tc := range testCases {
 		logger.Infof("Testing with input %q...", tc.name)
 
-		r := runner.Run(workDir, "python3", "hello.py").
+		r := runner.Run(workDir, python, "hello.py").
 			WithTimeout(5 * time.Second).
 			Stdin(tc.name).
 			Stdout(tc.expected).
@@ -54,6 +60,15 @@ func testSentimentalHello(harness *test_case_harness.TestCaseHarness) error {
 		logger.Successf("✓ Output correct for input %q", tc.name)
 	}
 
+	// 3. 直接调用 main() (导入 hello.py 时不运行 __main__ 下的代码)
+	var mainCases []pythonMainCase
+	for _, tc := range testCases {
+		mainCases = append(mainCases, pythonMainCase{fmt.Sprintf("with input %q", tc.name), tc.name, tc.expected})
+	}
+	if err := runPythonMainChecks(logger, python, workDir, "hello.py", mainCases); err != nil {
+		return err
+	}
+
 	logger.Successf("All tests passed!")
 	return nil
 }
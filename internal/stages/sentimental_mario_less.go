@@ -31,6 +31,12 @@ func testSentimentalMarioLess(harness *test_case_harness.TestCaseHarness) error
 	}
 	logger.Successf("mario.py exists")
 
+	// 找到并检查 Python 解释器 (.venv、BCS_PYTHON 或系统 python3)
+	python, err := pythonCommand(harness)
+	if err != nil {
+		return err
+	}
+
 	// 2. 测试拒绝无效输入 (对齐 CS50 check50)
 	rejectTests := []struct {
 		input string
@@ -45,7 +51,7 @@ func testSentimentalMarioLess(harness *test_case_harness.TestCaseHarness) error
 	for _, tc := range rejectTests {
 		logger.Infof("Testing %s...", tc.name)
 
-		r := runner.Run(workDir, "python3", "mario.py").
+		r := runner.Run(workDir, python, "mario.py").
 			WithTimeout(5 * time.Second).
 			WithPty().
 			Start().
@@ -82,7 +88,7 @@ func testSentimentalMarioLess(harness *test_case_harness.TestCaseHarness) error
 		}
 		expected := strings.TrimSpace(string(expectedBytes))
 
-		r := runner.Run(workDir, "python3", "mario.py").
+		r := runner.Run(workDir, python, "mario.py").
 			WithTimeout(5 * time.Second).
 			Stdin(tc.height).
 			Stdout(expected).
@@ -103,7 +109,7 @@ func testSentimentalMarioLess(harness *test_case_harness.TestCaseHarness) error
 	}
 	expected := strings.TrimSpace(string(expectedBytes))
 
-	r := runner.Run(workDir, "python3", "mario.py").
+	r := runner.Run(workDir, python, "mario.py").
 		WithTimeout(5 * time.Second).
 		WithPty().
 		Start().
@@ -120,6 +126,14 @@ func testSentimentalMarioLess(harness *test_case_harness.TestCaseHarness) error
 
 	logger.Successf("✓ rejects 9 and then accepts 2")
 
+	// 5. 直接调用 main() (导入 mario.py 时不运行 __main__ 下的代码)，先输入无效的高度再输入 2
+	mainCases := []pythonMainCase{
+		{"with inputs -1, 0, foo, \"\" and 9 before 2", "-1\n0\nfoo\n\n9\n2\n", expected},
+	}
+	if err := runPythonMainChecks(logger, python, workDir, "mario.py", mainCases); err != nil {
+		return err
+	}
+
 	logger.Successf("All tests passed!")
 	return nil
 }
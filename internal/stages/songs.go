@@ -1,7 +1,6 @@
 package stages
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -57,73 +56,40 @@ func testSongs(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("answers.txt exists")
 
-	// 3. 打开数据库
-	dbPath := filepath.Join(workDir, "songs.db")
-	db, err := sql.Open("sqlite3", dbPath)
+	// 3. 打开数据库的只读沙箱 (每个查询单独连接，有超时和行数限制) 及 id 打乱的副本
+	sandbox, perturbed, cleanup, err := openSQLSandboxes(logger, workDir, "songs.db")
 	if err != nil {
-		return fmt.Errorf("failed to open songs.db: %v", err)
+		return err
 	}
-	defer db.Close()
+	defer cleanup()
 
 	// 4. 运行各测试
-	// Test 1: 所有歌曲名称 (无序)
-	logger.Infof("Testing 1.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "1.sql", expectedSongs1); err != nil {
-		return fmt.Errorf("1.sql: %v", err)
+	if err := runSQLChecks(logger, workDir, sandbox, perturbed, songsChecks); err != nil {
+		return err
 	}
-	logger.Successf("✓ 1.sql produces correct result")
 
-	// Test 2: 按 tempo 排序的歌曲名称 (有序)
-	logger.Infof("Testing 2.sql produces correct result...")
-	if err := helpers.TestSQLSingleColOrdered(db, workDir, "2.sql", expectedSongs2); err != nil {
-		return fmt.Errorf("2.sql: %v", err)
-	}
-	logger.Successf("✓ 2.sql produces correct result")
+	logger.Successf("All tests passed!")
+	return nil
+}
 
+// songsChecks 是每个 N.sql 的预期结果
+var songsChecks = []helpers.SQLCheck{
+	// Test 1: 所有歌曲名称 (无序)
+	helpers.UnorderedSQLCheck("1.sql", expectedSongs1),
+	// Test 2: 按 tempo 排序的歌曲名称 (有序)
+	helpers.OrderedSQLCheck("2.sql", expectedSongs2),
 	// Test 3: 前 5 首最长歌曲 (有序)
-	logger.Infof("Testing 3.sql produces correct result...")
-	if err := helpers.TestSQLSingleColOrdered(db, workDir, "3.sql", expectedSongs3); err != nil {
-		return fmt.Errorf("3.sql: %v", err)
-	}
-	logger.Successf("✓ 3.sql produces correct result")
-
+	helpers.OrderedSQLCheck("3.sql", expectedSongs3),
 	// Test 4: 高能量歌曲 (无序)
-	logger.Infof("Testing 4.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "4.sql", expectedSongs4); err != nil {
-		return fmt.Errorf("4.sql: %v", err)
-	}
-	logger.Successf("✓ 4.sql produces correct result")
-
+	helpers.UnorderedSQLCheck("4.sql", expectedSongs4),
 	// Test 5: 平均能量 (浮点数)
-	logger.Infof("Testing 5.sql produces correct result...")
-	if err := helpers.TestSQLFloat(db, workDir, "5.sql", 0.65906, 0.01); err != nil {
-		return fmt.Errorf("5.sql: %v", err)
-	}
-	logger.Successf("✓ 5.sql produces correct result")
-
+	helpers.FloatSQLCheck("5.sql", 0.65906, 0.01),
 	// Test 6: Post Malone 的歌曲 (无序)
-	logger.Infof("Testing 6.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "6.sql", expectedSongs6); err != nil {
-		return fmt.Errorf("6.sql: %v", err)
-	}
-	logger.Successf("✓ 6.sql produces correct result")
-
+	helpers.UnorderedSQLCheck("6.sql", expectedSongs6),
 	// Test 7: Post Malone 平均能量 (浮点数)
-	logger.Infof("Testing 7.sql produces correct result...")
-	if err := helpers.TestSQLFloat(db, workDir, "7.sql", 0.599, 0.01); err != nil {
-		return fmt.Errorf("7.sql: %v", err)
-	}
-	logger.Successf("✓ 7.sql produces correct result")
-
+	helpers.FloatSQLCheck("7.sql", 0.599, 0.01),
 	// Test 8: 含 feat. 的歌曲 (无序)
-	logger.Infof("Testing 8.sql produces correct result...")
-	if err := helpers.TestSQLSingleColUnordered(db, workDir, "8.sql", expectedSongs8); err != nil {
-		return fmt.Errorf("8.sql: %v", err)
-	}
-	logger.Successf("✓ 8.sql produces correct result")
-
-	logger.Successf("All tests passed!")
-	return nil
+	helpers.UnorderedSQLCheck("8.sql", expectedSongs8),
 }
 
 // 预期结果数据 (对齐 CS50 check50)
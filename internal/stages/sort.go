@@ -1,15 +1,83 @@
 package stages
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
 
+// sortSizes 是实验使用的两种输入规模 (第二种是第一种的 4 倍)
+var sortSizes = []int{2500, 10000}
+
+// sortRunTimeout 是单次运行 sort 程序的超时时间
+const sortRunTimeout = 10 * time.Second
+
+// sortRepeats 是每个输入上运行的次数，取最短的耗时以减少机器负载的干扰
+const sortRepeats = 3
+
+// sortAttempts 是测量结果与发行版算法不一致时最多测量的次数，仍不一致时跳过该程序的实验结果
+const sortAttempts = 3
+
+// sortExpected 是发行版每个 sort 程序使用的算法 (CS50 check50 的正确答案)
+var sortExpected = []struct {
+	program   string
+	algorithm string
+}{
+	{"sort1", helpers.BubbleSort},
+	{"sort2", helpers.MergeSort},
+	{"sort3", helpers.SelectionSort},
+}
+
+// sortObservationPatterns 是 "How do you know?" 中具体的观察结果：运行时间、输入类型或复杂度
+var sortObservationPatterns = []*regexp.Regexp{
+	// 运行时间，例如 0.5s、2 seconds、120ms、3秒
+	regexp.MustCompile(`(?i)\b\d+(?:\.\d+)?\s*(?:s|secs?|seconds?|ms|milliseconds?)\b|\d+(?:\.\d+)?\s*(?:秒|毫秒)`),
+	// 输入类型，例如 reversed、random、already sorted、sorted50000.txt
+	regexp.MustCompile(`(?i)\b(?:reversed|random(?:ly)?|already sorted|sorted (?:inputs?|lists?|files?|numbers|data|arrays?))\b|\b(?:sorted|reversed|random)\d+(?:\.txt)?\b|已排序|排好序|有序|逆序|倒序|随机`),
+	// 复杂度，例如 O(n²)、n log n、quadratic
+	regexp.MustCompile(`(?i)\b[oΩΘ]\s*\(\s*n|n\s*\^\s*2|n²|\bn\s*log\s*n\b|\bnlogn\b|\b(?:quadratic|linear|logarithmic)\b|复杂度|平方|线性|对数`),
+}
+
+var (
+	sortUsesRegex    = regexp.MustCompile(`(?i)^\s*(sort[123])\s+uses:\s*(.*)$`)
+	sortExplainRegex = regexp.MustCompile(`(?i)^\s*how do you know\??:?\s*(.*)$`)
+)
+
+// sortAnswer 是 answers.txt 中一个 sort 程序的答案
+type sortAnswer struct {
+	algorithm       string
+	algorithmLine   int
+	explanation     string
+	explanationLine int
+}
+
+// sortObservation 是一个 sort 程序的实验结果：small、large 是两种规模下各输入的耗时 (秒)
+type sortObservation struct {
+	algorithm    string
+	small, large map[string]float64
+}
+
+// evidence 描述分类依据，例如 "random 0.004s -> 0.061s"
+func (o sortObservation) evidence() string {
+	var parts []string
+	for _, kind := range helpers.SortInputKinds {
+		parts = append(parts, fmt.Sprintf("%s %.3fs -> %.3fs", kind, o.small[kind], o.large[kind]))
+	}
+	return fmt.Sprintf("from %d to %d numbers its time went %s, which is how %s sort behaves",
+		sortSizes[0], sortSizes[1], strings.Join(parts, ", "), strings.ToLower(o.algorithm))
+}
+
 func sortTestCase() tester_definition.TestCase {
 	return tester_definition.TestCase{
 		Slug:     "sort",
@@ -20,6 +88,7 @@ func sortTestCase() tester_definition.TestCase {
 
 func testSort(harness *test_case_harness.TestCaseHarness) error {
 	logger := harness.Logger
+	workDir := harness.SubmissionDir
 
 	// 1. 检查 answers.txt 文件存在
 	logger.Infof("Checking answers.txt exists...")
@@ -42,27 +111,210 @@ func testSort(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("all questions answered")
 
-	// 4. 检查排序算法识别是否正确
-	logger.Infof("Checking that sorts are classified correctly...")
+	// 4. 实验：提交中有 sort1、sort2、sort3 时在已排序、逆序和随机输入上计时，作为答错时的提示
+	// 评分只看 answers.txt，计时结果不影响是否通过
+	observed, err := runSortExperiments(logger, workDir)
+	if err != nil {
+		return err
+	}
 
-	// CS50 check50 的正确答案
-	expectedPatterns := []struct {
-		pattern string
-		desc    string
-	}{
-		{`sort1 uses:\s*[Bb][Uu][Bb][Bb][Ll][Ee]`, "sort1 should use Bubble sort"},
-		{`sort2 uses:\s*[Mm][Ee][Rr][Gg][Ee]`, "sort2 should use Merge sort"},
-		{`sort3 uses:\s*[Ss][Ee][Ll][Ee][Cc][Tt][Ii][Oo][Nn]`, "sort3 should use Selection sort"},
+	// 5. 逐行检查答案与发行版的算法一致
+	logger.Infof("Checking that sorts are classified correctly...")
+	parsed := parseSortAnswers(answers)
+	var problems []string
+	for _, expected := range sortExpected {
+		answer, ok := parsed[expected.program]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: missing \"%s uses:\" line", expected.program, expected.program))
+		case !strings.HasPrefix(strings.ToLower(answer.algorithm), strings.ToLower(expected.algorithm)):
+			problem := fmt.Sprintf("line %d: %s uses %q is incorrect", answer.algorithmLine, expected.program, answer.algorithm)
+			if observation, ok := observed[expected.program]; ok {
+				problem += "; " + observation.evidence()
+			}
+			problems = append(problems, problem)
+		default:
+			logger.Successf("✓ %s uses %s sort", expected.program, expected.algorithm)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("incorrect assignment of sorts:\n  %s", strings.Join(problems, "\n  "))
 	}
 
-	for _, ep := range expectedPatterns {
-		re := regexp.MustCompile(ep.pattern)
-		if !re.MatchString(answers) {
-			return fmt.Errorf("incorrect assignment of sorts: %s", ep.desc)
+	// 6. 检查 "How do you know?" 的解释引用了具体的观察结果
+	logger.Infof("Checking explanations reference observations...")
+	for _, expected := range sortExpected {
+		answer := parsed[expected.program]
+		if problem := checkSortExplanation(expected.program, answer); problem != "" {
+			problems = append(problems, problem)
+		} else {
+			logger.Successf("✓ %s explanation references observations", expected.program)
 		}
-		logger.Successf("✓ %s", ep.desc)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("explanations need more detail:\n  %s", strings.Join(problems, "\n  "))
 	}
 
 	logger.Successf("All sort tests passed!")
 	return nil
 }
+
+// parseSortAnswers 解析 answers.txt：每个 "sortN uses:" 之后的 "How do you know?:" 为其解释 (可跨多行)
+func parseSortAnswers(content string) map[string]*sortAnswer {
+	answers := make(map[string]*sortAnswer)
+	var current *sortAnswer
+	inExplanation := false
+
+	for i, line := range strings.Split(content, "\n") {
+		if m := sortUsesRegex.FindStringSubmatch(line); m != nil {
+			current = &sortAnswer{algorithm: strings.TrimSpace(m[2]), algorithmLine: i + 1}
+			answers[strings.ToLower(m[1])] = current
+			inExplanation = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := sortExplainRegex.FindStringSubmatch(line); m != nil {
+			current.explanation = strings.TrimSpace(m[1])
+			current.explanationLine = i + 1
+			inExplanation = true
+			continue
+		}
+		if inExplanation && strings.TrimSpace(line) != "" {
+			current.explanation = strings.TrimSpace(current.explanation + " " + strings.TrimSpace(line))
+		}
+	}
+	return answers
+}
+
+// checkSortExplanation 检查解释非空且提到了观察结果，返回问题描述 (没有问题时为空)
+func checkSortExplanation(program string, answer *sortAnswer) string {
+	if answer.explanationLine == 0 {
+		return fmt.Sprintf("%s: missing \"How do you know?:\" after line %d", program, answer.algorithmLine)
+	}
+	if answer.explanation == "" {
+		return fmt.Sprintf("line %d: explanation for %s is empty", answer.explanationLine, program)
+	}
+	for _, pattern := range sortObservationPatterns {
+		if pattern.MatchString(answer.explanation) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("line %d: explanation for %s does not mention a concrete observation: a running time (e.g. 0.5s), "+
+		"an input kind (sorted, reversed or random input) or a complexity (e.g. O(n²))",
+		answer.explanationLine, program)
+}
+
+// runSortExperiments 在两种规模的已排序、逆序和随机输入上计时提交中的每个 sort 程序，按耗时的增长判断其算法
+// 只返回与发行版算法一致的结果；没有该程序、运行失败或多次测量仍不一致 (机器太忙) 时记录下来并跳过
+func runSortExperiments(logger *logger.Logger, workDir string) (map[string]sortObservation, error) {
+	observed := make(map[string]sortObservation)
+	binaries := make(map[string]string)
+	for _, expected := range sortExpected {
+		binary := filepath.Join(workDir, expected.program)
+		if info, err := os.Stat(binary); err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			logger.Infof("%s not found, skipping its timing experiment", expected.program)
+			continue
+		}
+		binaries[expected.program] = binary
+	}
+	if len(binaries) == 0 {
+		return observed, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "sort-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, kind := range helpers.SortInputKinds {
+		for _, size := range sortSizes {
+			name := fmt.Sprintf("%s%d.txt", kind, size)
+			input := helpers.FormatSortInput(helpers.GenerateSortInput(kind, size))
+			if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(input), 0644); err != nil {
+				return nil, fmt.Errorf("could not write %s: %v", name, err)
+			}
+		}
+	}
+
+	for _, expected := range sortExpected {
+		binary, ok := binaries[expected.program]
+		if !ok {
+			continue
+		}
+		logger.Infof("Timing %s on sorted, reversed and random input...", expected.program)
+
+		observation, err := measureSortProgram(logger, binary, tmpDir, expected.algorithm)
+		switch {
+		case err != nil:
+			logger.Infof("could not time %s (%v), skipping its timing experiment", expected.program, err)
+		case observation.algorithm != expected.algorithm:
+			logger.Infof("timings for %s were inconclusive (%s), skipping them", expected.program, observation.evidence())
+		default:
+			logger.Successf("✓ %s behaves like %s sort", expected.program, strings.ToLower(observation.algorithm))
+			observed[expected.program] = observation
+		}
+	}
+	return observed, nil
+}
+
+// measureSortProgram 计时 binary 并分类，与 algorithm 不一致时重新测量，最多 sortAttempts 次
+func measureSortProgram(logger *logger.Logger, binary, inputDir, algorithm string) (sortObservation, error) {
+	var observation sortObservation
+	for attempt := 1; attempt <= sortAttempts; attempt++ {
+		measurements := make([]map[string]float64, len(sortSizes))
+		for i, size := range sortSizes {
+			measurements[i] = make(map[string]float64)
+			for _, kind := range helpers.SortInputKinds {
+				name := fmt.Sprintf("%s%d.txt", kind, size)
+				seconds, err := timeSortProgram(binary, filepath.Join(inputDir, name))
+				if err != nil {
+					return observation, fmt.Errorf("%s: %v", name, err)
+				}
+				measurements[i][kind] = seconds
+				logger.Infof("  %-16s %.3fs", name, seconds)
+			}
+		}
+		observation = sortObservation{
+			algorithm: helpers.ClassifySort(measurements[0], measurements[len(measurements)-1]),
+			small:     measurements[0],
+			large:     measurements[len(measurements)-1],
+		}
+		if observation.algorithm == algorithm || attempt == sortAttempts {
+			break
+		}
+		logger.Infof("%s looked like %s sort, measuring again...", filepath.Base(binary), strings.ToLower(observation.algorithm))
+	}
+	return observation, nil
+}
+
+// timeSortProgram 运行 sort 程序 sortRepeats 次并返回最短耗时 (秒)
+func timeSortProgram(binary, input string) (float64, error) {
+	best := 0.0
+	for i := 0; i < sortRepeats; i++ {
+		start := time.Now()
+		if err := runSortBinary(binary, input); err != nil {
+			return 0, err
+		}
+		if elapsed := time.Since(start).Seconds(); i == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	return best, nil
+}
+
+// runSortBinary 运行发行版的 sort 程序
+func runSortBinary(binary, input string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sortRunTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, input)
+	cmd.Stdout = io.Discard
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %v", sortRunTimeout)
+	}
+	return err
+}
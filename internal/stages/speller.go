@@ -8,10 +8,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
 
+// spellerRandomCorpora 是随机生成的字典/文本组数
+const spellerRandomCorpora = 3
+
 func spellerTestCase() tester_definition.TestCase {
 	return tester_definition.TestCase{
 		Slug:     "speller",
@@ -40,115 +45,64 @@ func testSpeller(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("speller compiles")
 
-	// 使用 CS50 提供的测试目录进行测试
+	// 3. 使用 CS50 提供的测试目录，完整比对输出与参考实现
 	// 每个测试目录包含 dict 和 text 文件
 	testCases := []struct {
 		name     string
-		dir      string   // 测试目录
-		expected []string // 期望的拼错单词
-		notIn    []string // 不应该出现的单词
+		dir      string
+		optional bool
 	}{
-		{
-			name:     "handles basic words properly",
-			dir:      "basic",
-			expected: []string{}, // 所有单词都在字典中
-		},
-		{
-			name:     "handles min length (1-char) words",
-			dir:      "min_length",
-			expected: []string{},
-		},
-		{
-			name:     "handles max length (45-char) words",
-			dir:      "max_length",
-			expected: []string{},
-		},
-		{
-			name:     "spell-checks case-insensitively",
-			dir:      "case",
-			expected: []string{}, // 所有大小写变体都应该匹配
-		},
-		{
-			name:     "handles substrings properly",
-			dir:      "substring",
-			expected: []string{"ca", "cats", "caterpill", "caterpillars"},
-			notIn:    []string{"cat", "caterpillar"},
-		},
+		{name: "handles basic words properly", dir: "basic"},
+		{name: "handles min length (1-char) words", dir: "min_length"},
+		{name: "handles max length (45-char) words", dir: "max_length"},
+		{name: "spell-checks case-insensitively", dir: "case"},
+		{name: "handles substrings properly", dir: "substring"},
+		{name: "handles apostrophes in dictionary and text", dir: filepath.Join("apostrophe", "with")},
+		{name: "handles apostrophes only in text", dir: filepath.Join("apostrophe", "without")},
+		{name: "handles large dictionary", dir: "large", optional: true},
 	}
 
 	for _, tc := range testCases {
-		logger.Infof("Testing %s...", tc.name)
-
 		dictPath := filepath.Join(tc.dir, "dict")
 		textPath := filepath.Join(tc.dir, "text")
 
 		// 检查测试目录是否存在
 		if !harness.FileExists(dictPath) || !harness.FileExists(textPath) {
-			return fmt.Errorf("test directory %s not found (missing dict or text)", tc.dir)
-		}
-
-		// 运行 speller
-		cmd = exec.Command("./speller", dictPath, textPath)
-		cmd.Dir = workDir
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("speller failed on %s: %s\n%s", tc.dir, err, string(out))
-		}
-
-		output := string(out)
-
-		// 提取拼错的单词
-		misspelled := extractMisspelledWords(output)
-
-		// 检查期望的拼错单词
-		for _, word := range tc.expected {
-			if !contains(misspelled, word) {
-				return fmt.Errorf("expected '%s' to be marked as misspelled", word)
-			}
-		}
-
-		// 检查不应该出现的单词
-		for _, word := range tc.notIn {
-			if contains(misspelled, word) {
-				return fmt.Errorf("'%s' should not be marked as misspelled", word)
+			if tc.optional {
+				continue
 			}
+			return fmt.Errorf("test directory %s not found (missing dict or text)", tc.dir)
 		}
 
-		// 如果期望为空，确保没有拼错的单词
-		if len(tc.expected) == 0 && len(misspelled) > 0 {
-			return fmt.Errorf("expected no misspelled words, but got: %v", misspelled)
+		logger.Infof("Testing %s...", tc.name)
+		if err := verifySpellerOutput(workDir, dictPath, textPath); err != nil {
+			return fmt.Errorf("%s: %v", tc.dir, err)
 		}
-
 		logger.Successf("✓ %s", tc.name)
 	}
 
-	// 测试撇号处理 - apostrophe 目录有特殊结构
-	logger.Infof("Testing handles apostrophes properly...")
-
-	// 测试 with apostrophe in dict, with apostrophe in text
-	cmd = exec.Command("./speller", "apostrophe/with/dict", "apostrophe/with/text")
-	cmd.Dir = workDir
-	out, err := cmd.CombinedOutput()
+	// 4. 随机生成的字典和文本
+	tmpDir, err := os.MkdirTemp("", "speller-*")
 	if err != nil {
-		return fmt.Errorf("speller failed on apostrophe/with: %s\n%s", err, string(out))
+		return fmt.Errorf("could not create temp dir: %v", err)
 	}
-	misspelled := extractMisspelledWords(string(out))
-	if len(misspelled) > 0 {
-		return fmt.Errorf("apostrophe test failed: expected no misspelled words, got: %v", misspelled)
-	}
-	logger.Successf("✓ handles apostrophes properly")
-
-	// 测试大字典 (可选，验证性能)
-	logger.Infof("Testing handles large dictionary...")
-	if harness.FileExists("large/dict") && harness.FileExists("large/text") {
-		cmd = exec.Command("./speller", "large/dict", "large/text")
-		cmd.Dir = workDir
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("speller failed on large dictionary: %s\n%s", err, string(out))
+	defer os.RemoveAll(tmpDir)
+
+	for i := 1; i <= spellerRandomCorpora; i++ {
+		logger.Infof("Testing random text %d...", i)
+		dict, text := helpers.GenerateSpellerCorpus(random.RandomInt(50, 500), random.RandomInt(200, 2000))
+		dictPath := filepath.Join(tmpDir, fmt.Sprintf("dict%d", i))
+		textPath := filepath.Join(tmpDir, fmt.Sprintf("text%d", i))
+		if err := os.WriteFile(dictPath, dict, 0644); err != nil {
+			return fmt.Errorf("could not write dictionary: %v", err)
+		}
+		if err := os.WriteFile(textPath, text, 0644); err != nil {
+			return fmt.Errorf("could not write text: %v", err)
 		}
-		// 只检查程序能正常运行完成，不检查具体输出
-		logger.Successf("✓ handles large dictionary")
+		if err := verifySpellerOutput(workDir, dictPath, textPath); err != nil {
+			return fmt.Errorf("random text %d: %v", i, err)
+		}
+		logger.Successf("✓ random text %d", i)
 	}
 
 	// 内存检查 (valgrind) - 如果可用
@@ -157,7 +111,7 @@ func testSpeller(harness *test_case_harness.TestCaseHarness) error {
 		logger.Infof("valgrind not available, skipping memory check")
 	} else {
 		// 使用 basic 目录进行内存检查
-		cmd = exec.Command("valgrind", "--error-exitcode=1", "--leak-check=full",
+		cmd := exec.Command("valgrind", "--error-exitcode=1", "--leak-check=full",
 			"--show-leak-kinds=all", "--errors-for-leak-kinds=all", "-q",
 			"./speller", "basic/dict", "basic/text")
 		cmd.Dir = workDir
@@ -180,25 +134,58 @@ func testSpeller(harness *test_case_harness.TestCaseHarness) error {
 	return nil
 }
 
-// extractMisspelledWords 从 speller 输出中提取拼错的单词
-func extractMisspelledWords(output string) []string {
-	lines := strings.Split(output, "\n")
-	var misspelled []string
-	inMisspelled := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "MISSPELLED WORDS" {
-			inMisspelled = true
-			continue
-		}
-		if strings.HasPrefix(line, "WORDS MISSPELLED:") {
-			break
-		}
-		if inMisspelled && line != "" {
-			misspelled = append(misspelled, line)
+// verifySpellerOutput 运行 speller 并将完整的拼错单词列表和统计数字与参考实现比对
+func verifySpellerOutput(workDir, dictPath, textPath string) error {
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
 		}
+		return filepath.Join(workDir, path)
+	}
+	dictData, err := os.ReadFile(resolve(dictPath))
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", dictPath, err)
+	}
+	textData, err := os.ReadFile(resolve(textPath))
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", textPath, err)
 	}
 
-	return misspelled
+	report, err := runSpeller(workDir, "./speller", dictPath, textPath)
+	if err != nil {
+		return err
+	}
+	expected := helpers.SpellCheck(helpers.LoadSpellerDictionary(dictData), textData)
+
+	var problems []string
+	missing, extra := helpers.DiffSpellerWords(expected.Misspelled, report.Misspelled)
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d misspelled word(s) not reported: %s",
+			len(missing), formatSpellerWords(missing)))
+	}
+	if len(extra) > 0 {
+		problems = append(problems, fmt.Sprintf("%d word(s) wrongly reported as misspelled: %s",
+			len(extra), formatSpellerWords(extra)))
+	}
+	if err := compareSpellerCounts(expected, report); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if report.WordsMisspelled != len(report.Misspelled) {
+		problems = append(problems, fmt.Sprintf("WORDS MISSPELLED is %d but %d word(s) were listed",
+			report.WordsMisspelled, len(report.Misspelled)))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("output does not match reference spell checker:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// formatSpellerWords 格式化单词列表，过长时截断
+func formatSpellerWords(words []string) string {
+	const limit = 10
+	if len(words) <= limit {
+		return strings.Join(words, ", ")
+	}
+	return fmt.Sprintf("%s, ... (%d more)", strings.Join(words[:limit], ", "), len(words)-limit)
 }
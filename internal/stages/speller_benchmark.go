@@ -0,0 +1,240 @@
+package stages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/test_case_harness"
+	"github.com/bootcs-cn/tester-utils/tester_definition"
+)
+
+const (
+	// spellerRunTimeout 是单次运行 speller 的超时时间
+	spellerRunTimeout = 60 * time.Second
+	// spellerPathologicalFactor 是判定实现过慢的倍数 (相对参考实现的耗时)
+	spellerPathologicalFactor = 50.0
+)
+
+// spellerPhases 是 speller 输出中 TIME IN 行的阶段 (按输出顺序)
+var spellerPhases = []string{"load", "check", "size", "unload"}
+
+func spellerBenchmarkTestCase() tester_definition.TestCase {
+	return tester_definition.TestCase{
+		Slug:     "speller-benchmark",
+		Timeout:  600 * time.Second,
+		TestFunc: testSpellerBenchmark,
+	}
+}
+
+// spellerRun 是一次 speller 运行的结果
+type spellerRun struct {
+	text   string
+	report *helpers.SpellerReport
+}
+
+func testSpellerBenchmark(harness *test_case_harness.TestCaseHarness) error {
+	logger := harness.Logger
+	workDir := harness.SubmissionDir
+
+	// 1. 检查文件存在
+	logger.Infof("Checking dictionary.c exists...")
+	if !harness.FileExists("dictionary.c") {
+		return fmt.Errorf("dictionary.c does not exist")
+	}
+	logger.Successf("dictionary.c exists")
+
+	// 2. 编译 speller (与 Makefile 一致)
+	logger.Infof("Compiling speller...")
+	cmd := exec.Command("make", "speller")
+	cmd.Dir = workDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("speller does not compile: %s\n%s", err, string(out))
+	}
+	logger.Successf("speller compiles")
+	defer func() {
+		cleanCmd := exec.Command("make", "clean")
+		cleanCmd.Dir = workDir
+		cleanCmd.Run()
+		os.Remove(filepath.Join(workDir, "speller"))
+	}()
+
+	// 3. 确定语料：优先使用发行版的 dictionaries/large 和 texts/*.txt
+	dictPath, texts, err := findSpellerCorpus(workDir)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Benchmarking with %s and %d text(s)...", dictPath, len(texts))
+
+	dictData, err := os.ReadFile(filepath.Join(workDir, dictPath))
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", dictPath, err)
+	}
+	dict := helpers.LoadSpellerDictionary(dictData)
+
+	// 4. 逐个文本运行 speller，校验统计数字
+	var runs []spellerRun
+	for _, text := range texts {
+		report, err := runSpeller(workDir, "./speller", dictPath, text)
+		if err != nil {
+			return fmt.Errorf("%s: %v", text, err)
+		}
+
+		textData, err := os.ReadFile(filepath.Join(workDir, text))
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", text, err)
+		}
+		expected := helpers.SpellCheck(dict, textData)
+		if err := compareSpellerCounts(expected, report); err != nil {
+			return fmt.Errorf("%s: %v", text, err)
+		}
+
+		runs = append(runs, spellerRun{text: text, report: report})
+		logger.Infof("  %-24s load %.2fs  check %.2fs  size %.2fs  unload %.2fs  total %.2fs",
+			filepath.Base(text), report.Times["load"], report.Times["check"],
+			report.Times["size"], report.Times["unload"], report.Times["TOTAL"])
+	}
+	logger.Successf("✓ word counts match reference spell checker for all texts")
+
+	// 5. 与基准比较
+	studentTotals := make(map[string]float64)
+	for _, run := range runs {
+		for _, phase := range append(spellerPhases, "TOTAL") {
+			studentTotals[phase] += run.report.Times[phase]
+		}
+	}
+
+	baseline, source, err := spellerBaseline(workDir, dictPath, texts)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Baseline (%s): %.2fs total", source, baseline)
+
+	score := 100.0
+	if studentTotals["TOTAL"] > 0 {
+		score = 100.0 * baseline / studentTotals["TOTAL"]
+	}
+	logger.Infof("TIME IN load: %.2fs, check: %.2fs, size: %.2fs, unload: %.2fs",
+		studentTotals["load"], studentTotals["check"], studentTotals["size"], studentTotals["unload"])
+	logger.Successf("SCORE: %.1f (total %.2fs, baseline %.2fs; 100 = as fast as baseline)",
+		score, studentTotals["TOTAL"], baseline)
+
+	// 6. 标记极慢的实现 (例如只用一条链表的哈希表)
+	floor := baseline
+	if floor < 0.05 {
+		floor = 0.05
+	}
+	if studentTotals["TOTAL"] > floor*spellerPathologicalFactor {
+		slowest := slowestSpellerPhase(studentTotals)
+		return fmt.Errorf("speller is pathologically slow: %.2fs total is more than %.0fx the baseline (%.2fs); most time is spent in %s — consider a hash table with more buckets",
+			studentTotals["TOTAL"], spellerPathologicalFactor, baseline, slowest)
+	}
+
+	logger.Successf("Speller benchmark complete!")
+	return nil
+}
+
+// findSpellerCorpus 返回字典路径和文本列表 (相对 workDir)
+func findSpellerCorpus(workDir string) (string, []string, error) {
+	if _, err := os.Stat(filepath.Join(workDir, "dictionaries", "large")); err == nil {
+		texts, _ := filepath.Glob(filepath.Join(workDir, "texts", "*.txt"))
+		if len(texts) > 0 {
+			rel := make([]string, 0, len(texts))
+			for _, t := range texts {
+				r, err := filepath.Rel(workDir, t)
+				if err != nil {
+					return "", nil, err
+				}
+				rel = append(rel, r)
+			}
+			sort.Strings(rel)
+			return filepath.Join("dictionaries", "large"), rel, nil
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(workDir, "large", "dict")); err == nil {
+		return filepath.Join("large", "dict"), []string{filepath.Join("large", "text")}, nil
+	}
+	return "", nil, fmt.Errorf("no benchmark corpus found (expected dictionaries/large with texts/*.txt, or large/dict and large/text)")
+}
+
+// runSpeller 运行 speller 并解析输出
+func runSpeller(workDir, speller, dictPath, textPath string) (*helpers.SpellerReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), spellerRunTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, speller, dictPath, textPath)
+	cmd.Dir = workDir
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("speller timed out after %v", spellerRunTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("speller failed: %v", err)
+	}
+	return helpers.ParseSpellerOutput(string(out))
+}
+
+// compareSpellerCounts 比较 speller 输出的统计数字与参考实现
+func compareSpellerCounts(expected helpers.SpellCheckResult, report *helpers.SpellerReport) error {
+	checks := []struct {
+		name     string
+		expected int
+		actual   int
+	}{
+		{"WORDS MISSPELLED", len(expected.Misspelled), report.WordsMisspelled},
+		{"WORDS IN DICTIONARY", expected.WordsInDictionary, report.WordsInDictionary},
+		{"WORDS IN TEXT", expected.WordsInText, report.WordsInText},
+	}
+	for _, c := range checks {
+		if c.expected != c.actual {
+			return fmt.Errorf("%s: expected %d, got %d", c.name, c.expected, c.actual)
+		}
+	}
+	return nil
+}
+
+// spellerBaseline 计算基准耗时：优先运行 staff 的 speller50，否则使用 Go 参考实现的耗时
+func spellerBaseline(workDir, dictPath string, texts []string) (float64, string, error) {
+	if staff, err := exec.LookPath("speller50"); err == nil {
+		total := 0.0
+		for _, text := range texts {
+			report, err := runSpeller(workDir, staff, dictPath, text)
+			if err != nil {
+				return 0, "", fmt.Errorf("staff speller50 failed on %s: %v", text, err)
+			}
+			total += report.Times["TOTAL"]
+		}
+		return total, "speller50", nil
+	}
+
+	start := time.Now()
+	for _, text := range texts {
+		dictData, err := os.ReadFile(filepath.Join(workDir, dictPath))
+		if err != nil {
+			return 0, "", err
+		}
+		textData, err := os.ReadFile(filepath.Join(workDir, text))
+		if err != nil {
+			return 0, "", err
+		}
+		helpers.SpellCheck(helpers.LoadSpellerDictionary(dictData), textData)
+	}
+	return time.Since(start).Seconds(), "reference spell checker", nil
+}
+
+// slowestSpellerPhase 返回耗时最多的阶段
+func slowestSpellerPhase(totals map[string]float64) string {
+	slowest := spellerPhases[0]
+	for _, phase := range spellerPhases[1:] {
+		if totals[phase] > totals[slowest] {
+			slowest = phase
+		}
+	}
+	return fmt.Sprintf("%s (%.2fs)", slowest, totals[slowest])
+}
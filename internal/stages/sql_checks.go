@@ -0,0 +1,68 @@
+package stages
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/logger"
+)
+
+// distributionDatabases 是课程分发的原始数据库 (构建前由 make databases 下载，可能为空)
+//
+//go:embed databases
+var distributionDatabases embed.FS
+
+// openDistributionDatabase 在原始数据库的只读副本上创建沙箱；
+// tester 没有内置该数据库时，退回到提交目录中数据库的只读副本
+func openDistributionDatabase(logger *logger.Logger, workDir, name string) (*helpers.SQLSandbox, error) {
+	data, err := fs.ReadFile(distributionDatabases, "databases/"+name)
+	if err != nil {
+		logger.Debugf("%s is not built into this tester, using a read-only copy of the submission's", name)
+		return helpers.CopySQLSandbox(filepath.Join(workDir, name))
+	}
+	return helpers.NewSQLSandbox(name, data)
+}
+
+// openSQLSandboxes 创建原始数据库的只读沙箱及其 id 打乱的副本 (用于发现硬编码 id 的查询)，
+// 返回的 cleanup 删除两个副本
+func openSQLSandboxes(logger *logger.Logger, workDir, name string) (*helpers.SQLSandbox, *helpers.SQLSandbox, func(), error) {
+	sandbox, err := openDistributionDatabase(logger, workDir, name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	perturbed, err := sandbox.Perturbed()
+	if err != nil {
+		sandbox.Close()
+		return nil, nil, nil, err
+	}
+	cleanup := func() {
+		perturbed.Close()
+		sandbox.Close()
+	}
+	return sandbox, perturbed, cleanup, nil
+}
+
+// runSQLChecks 依次运行每个 N.sql 的检查 (在原数据库和 id 打乱的副本上)
+// 超时与结果错误分开报告；通过的查询记录耗时和查询计划中的性能问题
+func runSQLChecks(logger *logger.Logger, workDir string, sandbox, perturbed *helpers.SQLSandbox, checks []helpers.SQLCheck) error {
+	for _, check := range checks {
+		logger.Infof("Testing %s produces correct result...", check.Filename)
+		report, err := check.Run(sandbox, perturbed, workDir)
+		var timeout *helpers.SQLTimeoutError
+		switch {
+		case errors.As(err, &timeout):
+			return fmt.Errorf("%s is too slow: %v", check.Filename, err)
+		case err != nil:
+			return fmt.Errorf("%s: %v", check.Filename, err)
+		}
+		logger.Successf("✓ %s produces correct result (%.2fs)", check.Filename, report.Elapsed.Seconds())
+		for _, warning := range report.Warnings {
+			logger.Infof("  warning: %s", warning)
+		}
+	}
+	return nil
+}
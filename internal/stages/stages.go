@@ -36,6 +36,7 @@ func GetDefinition() tester_definition.TesterDefinition {
 			// Week 5: Data Structures
 			inheritanceTestCase(),
 			spellerTestCase(),
+			spellerBenchmarkTestCase(),
 
 			// Week 6: Python
 			sentimentalHelloTestCase(),
@@ -53,6 +54,7 @@ func GetDefinition() tester_definition.TesterDefinition {
 
 			// Week 9: Flask
 			financeTestCase(),
+			financeSecurityTestCase(),
 		},
 	}
 }
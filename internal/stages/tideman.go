@@ -2,14 +2,11 @@ package stages
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
-	"regexp"
-	"strings"
 	"time"
 
-	"github.com/bootcs-cn/tester-utils/runner"
+	"github.com/bootcs-cn/bcs100x-tester/internal/helpers"
+	"github.com/bootcs-cn/tester-utils/random"
 	"github.com/bootcs-cn/tester-utils/test_case_harness"
 	"github.com/bootcs-cn/tester-utils/tester_definition"
 )
@@ -42,198 +39,199 @@ func testTideman(harness *test_case_harness.TestCaseHarness) error {
 	}
 	logger.Successf("tideman compiles")
 
-	// 3. 创建测试程序
-	// 读取学生的 tideman.c，将 main 重命名为 distro_main
-	tidemanCode, err := harness.ReadFile("tideman.c")
-	if err != nil {
-		return fmt.Errorf("could not read tideman.c: %v", err)
+	// 3. 运行 vote、record_preferences、add_pairs、sort_pairs、lock_pairs 和 print_winner 函数测试
+	if err := runCFunctionChecks(logger, workDir, "tideman.c", tidemanChecks(), "-I..", "-lm", "-Wall"); err != nil {
+		return err
 	}
 
-	// 使用正则替换 main 函数
-	mainRegex := regexp.MustCompile(`int\s+main\s*\(`)
-	modifiedCode := mainRegex.ReplaceAllString(string(tidemanCode), "int distro_main(")
-
-	// 从学生目录读取测试代码
-	testCodeBytes, err := harness.ReadFile("tideman_test.c")
+	// 4. 端到端随机选举模拟
+	sims, err := tidemanSimulations()
 	if err != nil {
-		return fmt.Errorf("tideman_test.c does not exist: %v", err)
-	}
-	testCode := string(testCodeBytes)
-	combinedCode := modifiedCode + "\n" + testCode
-	testFilePath := filepath.Join(workDir, "tideman_combined_test.c")
-	if err := os.WriteFile(testFilePath, []byte(combinedCode), 0644); err != nil {
-		return fmt.Errorf("could not write test file: %v", err)
-	}
-
-	// 编译测试程序
-	logger.Infof("Compiling test harness...")
-	cmd = exec.Command("clang", "-o", "tideman_test", "tideman_combined_test.c", "-I..", "-lm", "-Wall")
-	cmd.Dir = workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("test harness does not compile: %s\n%s", err, string(out))
+		return err
 	}
-	logger.Successf("test harness compiles")
-
-	// 4. 运行 vote 函数测试
-	voteTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"0", "0", "true", "vote returns true when given name of candidate"},
-		{"0", "1", "false", "vote returns false when given name of invalid candidate"},
-		{"0", "2", "1", "vote correctly sets rank for first preference"},
-		{"0", "2", "1 2 0", "vote correctly sets rank for all preferences"},
+	if err := runElectionSimulations(logger, workDir, "tideman", sims); err != nil {
+		return err
 	}
 
-	for _, tc := range voteTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "tideman_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
+	logger.Successf("All tideman tests passed!")
+	return nil
+}
 
-		logger.Successf("✓ %s", tc.name)
-	}
+// tidemanNames 是函数级检查中候选人的名字
+var tidemanNames = []string{"Alice", "Bob", "Charlie", "David"}
 
-	// 5. 运行 record_preferences 函数测试
-	recordPrefsTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"0", "3", "0 0 0 1 0 1 1 0 0 ", "record_preferences correctly sets preferences for first voter"},
-		{"0", "4", "0 2 2 4 0 5 3 5 0", "record_preferences correctly sets preferences for all voters"},
+// tidemanCandidates 设置前 n 名候选人
+func tidemanCandidates(n int) helpers.CStep {
+	code := fmt.Sprintf("candidate_count = %d;", n)
+	for i := 0; i < n; i++ {
+		code += fmt.Sprintf(` candidates[%d] = "%s";`, i, tidemanNames[i])
 	}
+	return helpers.CStmt(code)
+}
 
-	for _, tc := range recordPrefsTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "tideman_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
+// tidemanPreferences 设置候选人和 preferences 矩阵 (preferences[i][j] 是偏好 i 胜过 j 的选民数)
+func tidemanPreferences(preferences [][]int) helpers.CStep {
+	code := tidemanCandidates(len(preferences)).Code
+	for i, row := range preferences {
+		for j, n := range row {
+			code += fmt.Sprintf(" preferences[%d][%d] = %d;", i, j, n)
 		}
-
-		logger.Successf("✓ %s", tc.name)
 	}
+	return helpers.CStmt(code)
+}
 
-	// 6. 运行 add_pairs 函数测试
-	addPairsTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"1", "5", "3", "add_pairs generates correct pair count when no ties"},
-		{"2", "5", "2", "add_pairs generates correct pair count when ties exist"},
-		{"1", "6", "true true true ", "add_pairs fills pairs array with winning pairs"},
-		{"1", "7", "0", "add_pairs does not fill pairs array with losing pairs"},
+// tidemanPairs 设置 pairs 数组 (每个 pair 是 {winner, loser})
+func tidemanPairs(pairs [][2]int) helpers.CStep {
+	code := fmt.Sprintf("pair_count = %d;", len(pairs))
+	for i, p := range pairs {
+		code += fmt.Sprintf(" pairs[%d].winner = %d; pairs[%d].loser = %d;", i, p[0], i, p[1])
 	}
+	return helpers.CStmt(code)
+}
 
-	for _, tc := range addPairsTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "tideman_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+// tidemanLocked 设置 locked 图中的边
+func tidemanLocked(edges [][2]int) helpers.CStep {
+	code := ""
+	for _, e := range edges {
+		code += fmt.Sprintf("locked[%d][%d] = true; ", e[0], e[1])
 	}
+	return helpers.CStmt(code)
+}
 
-	// 7. 运行 sort_pairs 函数测试
-	logger.Infof("Testing sort_pairs sorts pairs of candidates by margin of victory...")
-	r := runner.Run(workDir, "tideman_test", "3", "8").
-		WithTimeout(5 * time.Second).
-		Execute().
-		Stdout("0 2 0 1 2 1 ").
-		Exit(0)
-	if err := r.Error(); err != nil {
-		return fmt.Errorf("test failed for sort_pairs: %v", err)
+// tidemanExpectLocked 检查 n 名候选人的 locked 图恰好由 edges 组成
+func tidemanExpectLocked(n int, edges [][2]int) []helpers.CStep {
+	rows := make([][]bool, n)
+	for i := range rows {
+		rows[i] = make([]bool, n)
 	}
-	logger.Successf("✓ sort_pairs sorts pairs of candidates by margin of victory")
-
-	// 8. 运行 lock_pairs 函数测试
-	lockPairsTests := []struct {
-		setup    string
-		test     string
-		expected string
-		name     string
-	}{
-		{"5", "16", "false false false true false true false false false false false false false false false false false false false true false false true false false ", "lock_pairs locks all pairs when no cycles"},
-		{"6", "14", "false true false false false false false false false false true false false false false false false false false false false false false true false false true true false false false false false false false false ", "lock_pairs skips final pair if it creates cycle"},
-		{"5", "15", "false false false false false false false false true false true false false false false false false false false false false true true false false ", "lock_pairs skips middle pair if it creates a cycle"},
+	for _, e := range edges {
+		rows[e[0]][e[1]] = true
 	}
+	steps := make([]helpers.CStep, n)
+	for i, row := range rows {
+		steps[i] = helpers.ExpectBoolArray(fmt.Sprintf("locked[%d]", i), row)
+	}
+	return steps
+}
 
-	for _, tc := range lockPairsTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "tideman_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Stdout(tc.expected).
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+// tidemanPrintPairs 打印每个 pair 的 "winner loser"
+const tidemanPrintPairs = `for (int i = 0; i < pair_count; i++) { printf("%i %i\n", pairs[i].winner, pairs[i].loser); }`
+
+// tidemanPrintLosingPairs 打印 winner 并没有胜过 loser 的 pair
+const tidemanPrintLosingPairs = `for (int i = 0; i < pair_count; i++) { ` +
+	`if (preferences[pairs[i].winner][pairs[i].loser] <= preferences[pairs[i].loser][pairs[i].winner]) ` +
+	`{ printf("%i %i\n", pairs[i].winner, pairs[i].loser); } }`
+
+// tidemanNoTies 是没有平局的 preferences：Alice 胜过 Bob 和 Charlie，Bob 胜过 Charlie
+var tidemanNoTies = [][]int{{0, 3, 3}, {2, 0, 3}, {2, 2, 0}}
+
+// tidemanWithTie 是 Alice 和 Bob 打平的 preferences
+var tidemanWithTie = [][]int{{0, 2, 3}, {2, 0, 4}, {1, 0, 0}}
+
+// tidemanChecks 是 tideman 的函数级检查 (对齐 CS50 check50)
+func tidemanChecks() []helpers.CFunctionCheck {
+	return []helpers.CFunctionCheck{
+		{Name: "vote returns true when given name of candidate", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt("int ranks[3];"), helpers.ExpectBool(`vote(0, "Alice", ranks)`, true)}},
+		{Name: "vote returns false when given name of invalid candidate", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt("int ranks[3];"), helpers.ExpectBool(`vote(0, "David", ranks)`, false)}},
+		{Name: "vote correctly sets rank for first preference", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt(`int ranks[3]; vote(0, "Bob", ranks);`), helpers.ExpectInt("ranks[0]", 1)}},
+		{Name: "vote correctly sets rank for all preferences", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt(`int ranks[3]; vote(0, "Bob", ranks); vote(1, "Charlie", ranks); vote(2, "Alice", ranks);`),
+			helpers.ExpectIntArray("ranks", []int{1, 2, 0})}},
+
+		{Name: "record_preferences correctly sets preferences for first voter", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt("int ranks[] = {1, 2, 0}; record_preferences(ranks);"),
+			helpers.ExpectIntArray("preferences[0]", []int{0, 0, 0}),
+			helpers.ExpectIntArray("preferences[1]", []int{1, 0, 1}),
+			helpers.ExpectIntArray("preferences[2]", []int{1, 0, 0})}},
+		// 五张选票：ABC、ACB、BCA、CAB、BAC
+		{Name: "record_preferences correctly sets preferences for all voters", Steps: []helpers.CStep{
+			tidemanCandidates(3), helpers.CStmt(
+				"int ballots[5][3] = {{0, 1, 2}, {0, 2, 1}, {1, 2, 0}, {2, 0, 1}, {1, 0, 2}}; " +
+					"for (int i = 0; i < 5; i++) { record_preferences(ballots[i]); }"),
+			helpers.ExpectIntArray("preferences[0]", tidemanNoTies[0]),
+			helpers.ExpectIntArray("preferences[1]", tidemanNoTies[1]),
+			helpers.ExpectIntArray("preferences[2]", tidemanNoTies[2])}},
+
+		{Name: "add_pairs generates correct pair count when no ties", Steps: []helpers.CStep{
+			tidemanPreferences(tidemanNoTies), helpers.CStmt("add_pairs();"), helpers.ExpectInt("pair_count", 3)}},
+		{Name: "add_pairs generates correct pair count when ties exist", Steps: []helpers.CStep{
+			tidemanPreferences(tidemanWithTie), helpers.CStmt("add_pairs();"), helpers.ExpectInt("pair_count", 2)}},
+		{Name: "add_pairs fills pairs array with winning pairs", Steps: []helpers.CStep{
+			tidemanPreferences(tidemanNoTies), helpers.CStmt("add_pairs();"),
+			helpers.ExpectOutputLines(tidemanPrintPairs, []string{"0 1", "0 2", "1 2"})}},
+		{Name: "add_pairs does not fill pairs array with losing pairs", Steps: []helpers.CStep{
+			tidemanPreferences(tidemanWithTie), helpers.CStmt("add_pairs();"),
+			helpers.ExpectOutputLines(tidemanPrintLosingPairs, nil)}},
+
+		// 按票数和按票差排序的结果相同：Alice>Charlie 8:1，Alice>Bob 6:3，Charlie>Bob 5:4
+		{Name: "sort_pairs sorts pairs of candidates by margin of victory", Steps: []helpers.CStep{
+			tidemanPreferences([][]int{{0, 6, 8}, {3, 0, 4}, {1, 5, 0}}),
+			tidemanPairs([][2]int{{2, 1}, {0, 1}, {0, 2}}), helpers.CStmt("sort_pairs();"),
+			helpers.ExpectOutput(tidemanPrintPairs, "0 2\n0 1\n2 1")}},
+
+		{Name: "lock_pairs locks all pairs when no cycles", Steps: cSteps([]helpers.CStep{
+			tidemanCandidates(4), tidemanPairs([][2]int{{0, 1}, {0, 2}, {1, 3}, {2, 3}}), helpers.CStmt("lock_pairs();")},
+			tidemanExpectLocked(4, [][2]int{{0, 1}, {0, 2}, {1, 3}, {2, 3}}))},
+		{Name: "lock_pairs skips final pair if it creates cycle", Steps: cSteps([]helpers.CStep{
+			tidemanCandidates(3), tidemanPairs([][2]int{{0, 1}, {1, 2}, {2, 0}}), helpers.CStmt("lock_pairs();")},
+			tidemanExpectLocked(3, [][2]int{{0, 1}, {1, 2}}))},
+		{Name: "lock_pairs skips middle pair if it creates a cycle", Steps: cSteps([]helpers.CStep{
+			tidemanCandidates(4), tidemanPairs([][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 3}}), helpers.CStmt("lock_pairs();")},
+			tidemanExpectLocked(4, [][2]int{{0, 1}, {1, 2}, {2, 3}}))},
+		// David -> Alice 会经过 Alice -> Bob -> Charlie -> David 形成长环
+		{Name: "lock_pairs skips pair that creates a long cycle", Steps: cSteps([]helpers.CStep{
+			tidemanCandidates(4), tidemanPairs([][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 0}}), helpers.CStmt("lock_pairs();")},
+			tidemanExpectLocked(4, [][2]int{{0, 1}, {1, 2}, {2, 3}}))},
+
+		{Name: "print_winner prints winner of election when one candidate wins over all others", Steps: []helpers.CStep{
+			tidemanCandidates(3), tidemanLocked([][2]int{{0, 1}, {0, 2}, {1, 2}}),
+			helpers.ExpectOutput("print_winner();", "Alice")}},
+		{Name: "print_winner prints winner of election when some pairs are tied", Steps: []helpers.CStep{
+			tidemanCandidates(3), tidemanLocked([][2]int{{2, 0}, {2, 1}}),
+			helpers.ExpectOutput("print_winner();", "Charlie")}},
 	}
+}
 
-	// 9. 运行 print_winner 函数测试
-	printWinnerTests := []struct {
-		setup    string
-		test     string
-		expected string
+// tidemanSimulations 生成 tideman 的随机选举 (Condorcet 获胜者、成环和最大规模)
+// 只使用获胜者不取决于同强度 pair 排序的选举
+func tidemanSimulations() ([]electionSimulation, error) {
+	scenarios := []struct {
 		name     string
+		generate func() helpers.Election
+		accept   func(skipped int) bool
 	}{
-		{"4", "12", "Alice", "print_winner prints winner of election when one candidate wins over all others"},
-		{"4", "13", "Charlie", "print_winner prints winner of election when some pairs are tied"},
-	}
-
-	for _, tc := range printWinnerTests {
-		logger.Infof("Testing %s...", tc.name)
-
-		r := runner.Run(workDir, "tideman_test", tc.setup, tc.test).
-			WithTimeout(5 * time.Second).
-			Execute().
-			Exit(0)
-
-		if err := r.Error(); err != nil {
-			return fmt.Errorf("test failed for %s: %v", tc.name, err)
+		{
+			"tideman simulation with a Condorcet winner",
+			func() helpers.Election { return helpers.RandomRankedElection(3, random.RandomInt(5, 15)) },
+			func(skipped int) bool { return skipped == 0 },
+		},
+		{
+			"tideman simulation with a cycle",
+			func() helpers.Election { return helpers.CyclicRankedElection(4) },
+			func(skipped int) bool { return skipped > 0 },
+		},
+		{
+			"tideman simulation with maximum candidates",
+			func() helpers.Election { return helpers.RandomRankedElection(helpers.ElectionMaxCandidates, 99) },
+			func(skipped int) bool { return true },
+		},
+	}
+
+	sims := make([]electionSimulation, 0, len(scenarios))
+	for _, sc := range scenarios {
+		accept := sc.accept
+		e, err := generateElection(sc.generate, func(e helpers.Election) bool {
+			_, skipped, err := helpers.TidemanWinner(e)
+			return err == nil && accept(skipped)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sc.name, err)
 		}
-
-		stdout := strings.TrimSpace(r.GetStdout())
-		if stdout != tc.expected {
-			return fmt.Errorf("test failed for %s: expected '%s', got '%s'", tc.name, tc.expected, stdout)
-		}
-
-		logger.Successf("✓ %s", tc.name)
+		winner, _, _ := helpers.TidemanWinner(e)
+		sims = append(sims, electionSimulation{sc.name, e, []string{winner}})
 	}
-
-	// 清理测试文件
-	os.Remove(testFilePath)
-	os.Remove(filepath.Join(workDir, "tideman_test"))
-
-	logger.Successf("All tideman tests passed!")
-	return nil
+	return sims, nil
 }
@@ -0,0 +1,39 @@
+#!/bin/bash
+# 下载 SQL 题目的课程数据库 (songs.db、movies.db、fiftyville.db)，构建时通过 go:embed 编译进 tester
+# 用法: BOOTCS_DATABASES_URL=<地址> ./scripts/fetch-databases.sh
+#
+# 检查的期望结果是按课程自己的数据库写的，所以只从固定的 bootcs 地址下载，
+# 并用 internal/stages/databases/SHA256SUMS 校验；不下载时 tester 使用提交目录中数据库的只读副本
+
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+DATABASES_DIR="$(dirname "$SCRIPT_DIR")/internal/stages/databases"
+SUMS="$DATABASES_DIR/SHA256SUMS"
+
+if [ -z "$BOOTCS_DATABASES_URL" ]; then
+    echo "BOOTCS_DATABASES_URL is not set; SQL stages will use the submission's databases"
+    exit 0
+fi
+if [ ! -f "$SUMS" ]; then
+    echo "✗ $SUMS is missing; pin the checksums of the course databases before fetching them" >&2
+    exit 1
+fi
+
+TMP_DIR="$(mktemp -d)"
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+for name in songs movies fiftyville; do
+    if ! grep -q " $name.db\$" "$SUMS"; then
+        echo "✗ $name.db has no checksum in $SUMS" >&2
+        exit 1
+    fi
+    echo "Downloading $name.db..."
+    curl -fsSL -o "$TMP_DIR/$name.db" "$BOOTCS_DATABASES_URL/$name.db"
+done
+
+(cd "$TMP_DIR" && sha256sum -c "$SUMS")
+for name in songs movies fiftyville; do
+    cp "$TMP_DIR/$name.db" "$DATABASES_DIR/$name.db"
+    echo "✓ $name.db"
+done
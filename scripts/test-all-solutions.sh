@@ -12,7 +12,7 @@ SOLUTION_DIR="${TESTER_DIR}/../bcs100x-solution"
 cd "$TESTER_DIR"
 go build -o bcs100x-tester .
 
-# Stage 列表（按课程顺序）
+# Stage 列表（按课程顺序）；"stage:目录" 表示使用另一个 stage 的 solution 目录
 STAGES=(
     "hello"
     "mario-less"
@@ -33,6 +33,7 @@ STAGES=(
     "recover"
     "inheritance"
     "speller"
+    "speller-benchmark:speller"
     "sentimental-hello"
     "sentimental-mario-less"
     "sentimental-mario-more"
@@ -44,6 +45,7 @@ STAGES=(
     "movies"
     "fiftyville"
     "finance"
+    "finance-security:finance"
 )
 
 PASSED=0
@@ -56,8 +58,9 @@ echo "  BCS100X Solution Tester"
 echo "=========================================="
 echo ""
 
-for stage in "${STAGES[@]}"; do
-    stage_dir="${SOLUTION_DIR}/${stage}"
+for entry in "${STAGES[@]}"; do
+    stage="${entry%%:*}"
+    stage_dir="${SOLUTION_DIR}/${entry#*:}"
     
     if [ ! -d "$stage_dir" ]; then
         echo "⏭️  [$stage] SKIPPED - directory not found"